@@ -0,0 +1,79 @@
+package main
+
+import (
+	"authentication/pkg/auth"
+	"bufio"
+	"flag"
+	"log"
+	"os"
+)
+
+// breachfilter 从HIBP格式的SHA-1语料（每行 "SHA1:COUNT"）构建紧凑的布隆过滤器文件，
+// 供 password_policy.breach.filter_file 使用。
+//
+//	go run ./cmd/breachfilter -in pwned-passwords-sha1-ordered-by-hash.txt -out config/breached.bf
+func main() {
+	in := flag.String("in", "", "HIBP格式的SHA-1语料文件")
+	out := flag.String("out", "", "输出的过滤器文件")
+	fpRate := flag.Float64("fp", 0.001, "期望误判率")
+	minCount := flag.Int("min-count", 1, "最小出现次数，低于该次数的条目将被忽略")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 第一遍统计条目数量，用于计算过滤器大小
+	expected, err := countLines(*in)
+	if err != nil {
+		log.Fatalf("统计语料条目失败: %v", err)
+	}
+
+	// 第二遍构建过滤器
+	src, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("打开语料文件失败: %v", err)
+	}
+	defer src.Close()
+
+	filter, added, err := auth.BuildBloomFilter(bufio.NewReader(src), expected, *fpRate, *minCount)
+	if err != nil {
+		log.Fatalf("构建过滤器失败: %v", err)
+	}
+
+	// 写出过滤器
+	dst, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("创建过滤器文件失败: %v", err)
+	}
+	writer := bufio.NewWriter(dst)
+	size, err := filter.WriteTo(writer)
+	if err != nil {
+		log.Fatalf("写入过滤器失败: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Fatalf("写入过滤器失败: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		log.Fatalf("写入过滤器失败: %v", err)
+	}
+
+	log.Printf("过滤器构建完成: %d 条记录，%d 字节", added, size)
+}
+
+// countLines 统计文件行数
+func countLines(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var count uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"authentication/internal/config"
+	"authentication/internal/event"
 	"authentication/internal/handler"
+	"authentication/internal/mailer"
 	"authentication/internal/middleware"
+	"authentication/internal/policy"
+	"authentication/internal/ratelimit"
 	"authentication/internal/repository"
 	"authentication/internal/service"
+	"authentication/pkg/auth"
+	"context"
 	"fmt"
 	"log"
 
@@ -22,6 +28,13 @@ func main() {
 	// 设置Gin模式
 	gin.SetMode(cfg.Server.Mode)
 
+	// 初始化密码哈希算法
+	hasherRegistry, err := service.NewHasherRegistry(cfg.PasswordHash)
+	if err != nil {
+		log.Fatalf("初始化密码哈希算法失败: %v", err)
+	}
+	auth.SetDefaultHasherRegistry(hasherRegistry)
+
 	// 初始化数据库连接
 	db, err := repository.InitDB(cfg.DB)
 	if err != nil {
@@ -32,66 +45,286 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	permissionRepo := repository.NewPermissionRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	policyRepo := repository.NewPolicyRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	roleGrantRepo := repository.NewRoleGrantRepository(db)
+	elevationRepo := repository.NewElevationRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	separationRepo := repository.NewSeparationRepository(db)
+	relationRepo := repository.NewRelationRepository(db)
+
+	// 初始化密码策略
+	passwordPolicy, err := service.NewPasswordPolicy(cfg.PasswordPolicy)
+	if err != nil {
+		log.Fatalf("初始化密码策略失败: %v", err)
+	}
+
+	// 初始化邮件发送
+	mail, err := mailer.NewMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("初始化邮件发送失败: %v", err)
+	}
+
+	// 初始化人机验证
+	challengeVerifier, err := service.NewChallengeVerifier(cfg.Challenge, cfg.JWT.Secret)
+	if err != nil {
+		log.Fatalf("初始化人机验证失败: %v", err)
+	}
+
+	// 初始化访问控制策略引擎
+	policyEngine, err := policy.NewEngine()
+	if err != nil {
+		log.Fatalf("初始化策略引擎失败: %v", err)
+	}
+
+	// 初始化事件发布
+	publisher, err := event.NewPublisher(cfg.Events)
+	if err != nil {
+		log.Fatalf("初始化事件发布失败: %v", err)
+	}
+
+	// 路由声明的权限代码，启动时同步到数据库
+	permissionRegistry := auth.NewPermissionRegistry()
 
 	// 初始化服务
-	authService := service.NewAuthService(userRepo, cfg.JWT)
-	userService := service.NewUserService(userRepo)
+	passwordService := service.NewPasswordService(userRepo, passwordHistoryRepo, passwordPolicy, cfg.PasswordPolicy)
+	verificationService := service.NewVerificationService(userRepo, emailVerificationRepo, mail, cfg.EmailVerification)
+	loginProtector := service.NewLoginProtector(cfg.LoginProtection)
+	separationService := service.NewSeparationService(separationRepo)
+	authService := service.NewAuthService(userRepo, organizationRepo, cfg.JWT, passwordService, verificationService, loginProtector, separationService)
+	challengeService := service.NewChallengeService(challengeVerifier, loginProtector, cfg.Challenge)
+	userService := service.NewUserService(userRepo, roleRepo, passwordService, verificationService, loginProtector, separationService)
 	roleService := service.NewRoleService(roleRepo, permissionRepo)
-	permissionService := service.NewPermissionService(permissionRepo)
+	permissionService := service.NewPermissionService(permissionRepo, roleRepo, permissionRegistry)
+	policyService := service.NewPolicyService(policyRepo, policyEngine, cfg.Policy)
+	organizationService := service.NewOrganizationService(organizationRepo, roleRepo, permissionRepo)
+	groupService := service.NewGroupService(groupRepo, roleRepo)
+	auditService := service.NewAuditService(auditLogRepo)
+	roleGrantService := service.NewRoleGrantService(roleGrantRepo, roleRepo, authService, auditService, publisher, cfg.RoleGrants)
+	authzService := service.NewAuthzService(userRepo, authService, policyService, cfg.Authz)
+	elevationService := service.NewElevationService(elevationRepo, roleRepo, roleGrantRepo, roleGrantService, auditService, publisher, cfg.Elevation)
+	relationService, err := service.NewRelationService(relationRepo, cfg.Relations)
+	if err != nil {
+		log.Fatalf("初始化关系命名空间失败: %v", err)
+	}
+
+	// 加载访问控制策略
+	if err := policyService.Reload(); err != nil {
+		log.Fatalf("加载访问控制策略失败: %v", err)
+	}
 
 	// 初始化处理器
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, verificationService, challengeService)
 	userHandler := handler.NewUserHandler(userService)
 	roleHandler := handler.NewRoleHandler(roleService)
 	permissionHandler := handler.NewPermissionHandler(permissionService)
+	policyHandler := handler.NewPolicyHandler(policyService)
+	organizationHandler := handler.NewOrganizationHandler(organizationService)
+	groupHandler := handler.NewGroupHandler(groupService)
+	roleGrantHandler := handler.NewRoleGrantHandler(roleGrantService)
+	elevationHandler := handler.NewElevationHandler(elevationService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	separationHandler := handler.NewSeparationHandler(separationService)
+	authzHandler := handler.NewAuthzHandler(authzService)
+	relationHandler := handler.NewRelationHandler(relationService)
 
 	// 创建路由
 	r := gin.Default()
 
+	// 只采信可信代理转发的客户端IP，登录保护、限流、人机验证和策略都依赖客户端IP
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("设置可信代理失败: %v", err)
+	}
+
+	// 将认证服务注入上下文，供认证中间件使用
+	r.Use(func(c *gin.Context) {
+		c.Set("authService", authService)
+		c.Next()
+	})
+
 	// 注册中间件
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT, permissionRegistry, relationService)
+	policyMiddleware := middleware.NewPolicyMiddleware(policyService)
+	userResource := middleware.UserResourceLoader(userService)
+	userOwner := middleware.UserOwner("id")
+
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimit)
+	if err != nil {
+		log.Fatalf("初始化限流存储失败: %v", err)
+	}
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(cfg.RateLimit, rateLimitStore)
 
 	// API路由
 	api := r.Group("/api")
 	{
 		// 认证路由 - 不需要认证
-		auth := api.Group("/auth")
+		auth := api.Group("/auth", rateLimitMiddleware.Limit("auth"))
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.GET("/challenge", authHandler.GetChallenge)
+			auth.GET("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", authHandler.ResendVerification)
 
 			// 需要认证的路由
 			auth.GET("/profile", authMiddleware.AuthRequired(), authHandler.GetProfile)
+			auth.PUT("/profile", authMiddleware.AuthRequired(), authHandler.UpdateProfile)
+			auth.PATCH("/profile", authMiddleware.AuthRequired(), authHandler.UpdateProfile)
+			auth.PUT("/password", authMiddleware.AuthRequiredAllowExpired(), authHandler.ChangePassword)
+			auth.GET("/organizations", authMiddleware.AuthRequired(), authHandler.ListOrganizations)
+			auth.POST("/switch-organization", authMiddleware.AuthRequired(), authHandler.SwitchOrganization)
 		}
 
 		// 用户管理 - 需要认证
-		users := api.Group("/users", authMiddleware.AuthRequired())
+		users := api.Group("/users", authMiddleware.AuthRequired(), rateLimitMiddleware.Limit("api"))
 		{
 			users.GET("", authMiddleware.HasPermission("user:list"), userHandler.ListUsers)
-			users.GET("/:id", authMiddleware.HasPermission("user:read"), userHandler.GetUser)
-			users.PUT("/:id", authMiddleware.HasPermission("user:update"), userHandler.UpdateUser)
-			users.DELETE("/:id", authMiddleware.HasPermission("user:delete"), userHandler.DeleteUser)
+			users.POST("", authMiddleware.HasPermission("user:create"), userHandler.CreateUser)
+			users.POST("/import", authMiddleware.HasPermission("user:create"), userHandler.ImportUsers)
+			users.GET("/deleted", authMiddleware.HasPermission("user:list"), userHandler.ListDeletedUsers)
+			users.GET("/:id", authMiddleware.HasOwnPermission("user:read", userOwner), policyMiddleware.Authorize("user:read", userResource), userHandler.GetUser)
+			users.PUT("/:id", authMiddleware.HasOwnPermission("user:update", userOwner), policyMiddleware.Authorize("user:update", userResource), userHandler.UpdateUser)
+			users.DELETE("/:id", authMiddleware.HasPermission("user:delete"), policyMiddleware.Authorize("user:delete", userResource), userHandler.DeleteUser)
+			users.POST("/:id/restore", authMiddleware.HasPermission("user:restore"), userHandler.RestoreUser)
+			users.DELETE("/:id/purge", authMiddleware.HasPermission("user:purge"), userHandler.PurgeUser)
+			users.PUT("/:id/password", authMiddleware.HasAnyResourcePermission("user:update"), userHandler.ResetPassword)
+			users.GET("/:id/lockout", authMiddleware.HasOwnPermission("user:read", userOwner), userHandler.GetLockout)
+			users.DELETE("/:id/lockout", authMiddleware.HasPermission("user:unlock"), userHandler.UnlockUser)
+			users.PUT("/:id/roles", authMiddleware.HasPermission("user:assign"), userHandler.SetRoles)
+			users.POST("/:id/roles", authMiddleware.HasPermission("user:assign"), userHandler.AddRoles)
+			users.DELETE("/:id/roles", authMiddleware.HasPermission("user:assign"), userHandler.RemoveRoles)
+			users.GET("/:id/role-grants", authMiddleware.PlatformOnly(), authMiddleware.HasOwnPermission("user:read", userOwner), roleGrantHandler.ListGrants)
+			users.POST("/:id/role-grants", authMiddleware.PlatformOnly(), authMiddleware.HasPermission("user:assign"), roleGrantHandler.GrantRole)
 		}
 
 		// 角色管理 - 需要认证
-		roles := api.Group("/roles", authMiddleware.AuthRequired())
+		roles := api.Group("/roles", authMiddleware.AuthRequired(), rateLimitMiddleware.Limit("api"))
 		{
 			roles.GET("", authMiddleware.HasPermission("role:list"), roleHandler.ListRoles)
 			roles.POST("", authMiddleware.HasPermission("role:create"), roleHandler.CreateRole)
+			roles.GET("/hierarchy", authMiddleware.HasPermission("role:list"), roleHandler.GetHierarchy)
 			roles.GET("/:id", authMiddleware.HasPermission("role:read"), roleHandler.GetRole)
 			roles.PUT("/:id", authMiddleware.HasPermission("role:update"), roleHandler.UpdateRole)
 			roles.DELETE("/:id", authMiddleware.HasPermission("role:delete"), roleHandler.DeleteRole)
 			roles.POST("/:id/permissions", authMiddleware.HasPermission("role:assign"), roleHandler.AssignPermissions)
+			roles.GET("/:id/permissions", authMiddleware.HasPermission("role:read"), roleHandler.GetPermissions)
+			roles.POST("/:id/denied-permissions", authMiddleware.HasPermission("role:assign"), roleHandler.AssignDeniedPermissions)
+			roles.PUT("/:id/parents", authMiddleware.HasPermission("role:update"), roleHandler.SetParents)
 		}
 
 		// 权限管理 - 需要认证
-		permissions := api.Group("/permissions", authMiddleware.AuthRequired())
+		permissions := api.Group("/permissions", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("api"))
 		{
 			permissions.GET("", authMiddleware.HasPermission("permission:list"), permissionHandler.ListPermissions)
+			permissions.POST("", authMiddleware.HasPermission("permission:create"), permissionHandler.CreatePermission)
+			permissions.GET("/orphans", authMiddleware.HasPermission("permission:list"), permissionHandler.ListOrphans)
+			permissions.GET("/:id", authMiddleware.HasPermission("permission:read"), permissionHandler.GetPermission)
+			permissions.PUT("/:id", authMiddleware.HasPermission("permission:update"), permissionHandler.UpdatePermission)
+			permissions.DELETE("/:id", authMiddleware.HasPermission("permission:delete"), permissionHandler.DeletePermission)
+		}
+
+		// 访问控制策略管理 - 需要认证
+		policies := api.Group("/policies", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("api"))
+		{
+			policies.GET("", authMiddleware.HasPermission("policy:list"), policyHandler.ListPolicies)
+			policies.POST("", authMiddleware.HasPermission("policy:create"), policyHandler.CreatePolicy)
+			policies.GET("/:id", authMiddleware.HasPermission("policy:read"), policyHandler.GetPolicy)
+			policies.PUT("/:id", authMiddleware.HasPermission("policy:update"), policyHandler.UpdatePolicy)
+			policies.DELETE("/:id", authMiddleware.HasPermission("policy:delete"), policyHandler.DeletePolicy)
+		}
+
+		// 组织管理 - 需要认证，处于组织中时只能访问当前组织
+		organizations := api.Group("/organizations", authMiddleware.AuthRequired(), rateLimitMiddleware.Limit("api"))
+		{
+			organizations.GET("", authMiddleware.PlatformOnly(), authMiddleware.HasPermission("organization:list"), organizationHandler.ListOrganizations)
+			organizations.POST("", authMiddleware.PlatformOnly(), authMiddleware.HasPermission("organization:create"), organizationHandler.CreateOrganization)
+			organizations.GET("/:id", authMiddleware.SameOrganization("id"), authMiddleware.HasPermission("organization:read"), organizationHandler.GetOrganization)
+			organizations.PUT("/:id", authMiddleware.PlatformOnly(), authMiddleware.HasPermission("organization:update"), organizationHandler.UpdateOrganization)
+			organizations.DELETE("/:id", authMiddleware.PlatformOnly(), authMiddleware.HasPermission("organization:delete"), organizationHandler.DeleteOrganization)
+			organizations.GET("/:id/members", authMiddleware.SameOrganization("id"), authMiddleware.HasPermission("organization:read"), organizationHandler.ListMembers)
+			organizations.PUT("/:id/members", authMiddleware.SameOrganization("id"), authMiddleware.HasPermission("organization:member"), organizationHandler.SetMember)
+			organizations.DELETE("/:id/members/:user_id", authMiddleware.SameOrganization("id"), authMiddleware.HasPermission("organization:member"), organizationHandler.RemoveMember)
 		}
+
+		// 用户分组管理 - 需要认证，分组角色为全局角色，只能在全局范围内管理
+		groups := api.Group("/groups", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("api"))
+		{
+			groups.GET("", authMiddleware.HasPermission("group:list"), groupHandler.ListGroups)
+			groups.POST("", authMiddleware.HasPermission("group:create"), groupHandler.CreateGroup)
+			groups.GET("/:id", authMiddleware.HasPermission("group:read"), groupHandler.GetGroup)
+			groups.PUT("/:id", authMiddleware.HasPermission("group:update"), groupHandler.UpdateGroup)
+			groups.DELETE("/:id", authMiddleware.HasPermission("group:delete"), groupHandler.DeleteGroup)
+			groups.GET("/:id/members", authMiddleware.HasPermission("group:read"), groupHandler.ListMembers)
+			groups.POST("/:id/members", authMiddleware.HasPermission("group:member"), groupHandler.AddMembers)
+			groups.DELETE("/:id/members", authMiddleware.HasPermission("group:member"), groupHandler.RemoveMembers)
+			groups.PUT("/:id/roles", authMiddleware.HasPermission("group:assign"), groupHandler.SetRoles)
+		}
+
+		// 临时提权 - 需要认证，申请的是全局角色，审批只能在全局范围内进行
+		approverPermission := elevationService.ApproverPermission()
+		elevations := api.Group("/elevations", authMiddleware.AuthRequired(), rateLimitMiddleware.Limit("api"))
+		{
+			elevations.POST("", authMiddleware.HasPermission("elevation:request"), elevationHandler.CreateRequest)
+			elevations.GET("/mine", authMiddleware.HasPermission("elevation:request"), elevationHandler.ListMine)
+			elevations.GET("", authMiddleware.PlatformOnly(), authMiddleware.HasPermission(approverPermission), elevationHandler.ListRequests)
+			elevations.POST("/:id/approve", authMiddleware.PlatformOnly(), authMiddleware.HasPermission(approverPermission), elevationHandler.Approve)
+			elevations.POST("/:id/deny", authMiddleware.PlatformOnly(), authMiddleware.HasPermission(approverPermission), elevationHandler.Deny)
+		}
+
+		// 职责分离约束管理 - 需要认证，约束可以包含全局和组织角色，只能在全局范围内管理
+		separations := api.Group("/separation-constraints", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("api"))
+		{
+			separations.GET("", authMiddleware.HasPermission("separation:list"), separationHandler.ListConstraints)
+			separations.POST("", authMiddleware.HasPermission("separation:create"), separationHandler.CreateConstraint)
+			separations.GET("/:id", authMiddleware.HasPermission("separation:read"), separationHandler.GetConstraint)
+			separations.PUT("/:id", authMiddleware.HasPermission("separation:update"), separationHandler.UpdateConstraint)
+			separations.DELETE("/:id", authMiddleware.HasPermission("separation:delete"), separationHandler.DeleteConstraint)
+		}
+
+		// 集中授权决策 - 需要认证，供其他服务查询任意用户的权限，只能在全局范围内调用
+		api.POST("/authz/check", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("authz"), authMiddleware.HasPermission("authz:check"), authzHandler.Check)
+
+		// 基于关系的访问控制 - 需要认证，元组可以涉及任意用户和对象，只能在全局范围内管理
+		relations := api.Group("/relations", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("authz"))
+		{
+			relations.GET("/tuples", authMiddleware.HasPermission("relation:read"), relationHandler.ListTuples)
+			relations.POST("/tuples", authMiddleware.HasPermission("relation:write"), relationHandler.WriteTuples)
+			relations.POST("/check", authMiddleware.HasPermission("relation:check"), relationHandler.Check)
+			relations.POST("/expand", authMiddleware.HasPermission("relation:check"), relationHandler.Expand)
+			relations.POST("/list-objects", authMiddleware.HasPermission("relation:check"), relationHandler.ListObjects)
+		}
+
+		// 对象的所有者无需平台权限即可查看对象的共享情况，为每个定义了 owner 关系的命名空间注册路由
+		for _, namespace := range cfg.Relations.Namespaces {
+			for _, definition := range namespace.Relations {
+				if definition.Name != "owner" {
+					continue
+				}
+				api.GET("/relations/objects/"+namespace.Name+"/:id", authMiddleware.AuthRequired(), rateLimitMiddleware.Limit("authz"),
+					authMiddleware.HasRelation(namespace.Name, "owner", "id"), relationHandler.ExpandObject(namespace.Name))
+			}
+		}
+
+		// 审计日志 - 需要认证
+		api.GET("/audit-logs", authMiddleware.AuthRequired(), authMiddleware.PlatformOnly(), rateLimitMiddleware.Limit("api"), authMiddleware.HasPermission("audit:list"), auditHandler.ListAuditLogs)
+	}
+
+	// 同步路由声明的权限，创建缺少的权限并标记未使用的权限
+	if _, err := permissionService.Sync(); err != nil {
+		log.Fatalf("同步权限失败: %v", err)
 	}
 
+	// 后台清理到期的限时角色
+	go roleGrantService.Run(context.Background())
+
+	// 定期重新加载访问控制策略，同步其他实例的修改
+	go policyService.Run(context.Background())
+
 	// 启动服务器
 	serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
 	log.Printf("服务器启动在 %s", serverAddr)
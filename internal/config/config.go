@@ -13,12 +13,30 @@ type Config struct {
 	Log    LogConfig    `yaml:"log"`
 	DB     DBConfig     `yaml:"db"`
 	JWT    JWTConfig    `yaml:"jwt"`
+
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy"`
+	PasswordHash   PasswordHashConfig   `yaml:"password_hash"`
+
+	Mail              MailConfig              `yaml:"mail"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	LoginProtection   LoginProtectionConfig   `yaml:"login_protection"`
+	RateLimit         RateLimitConfig         `yaml:"rate_limit"`
+	Challenge         ChallengeConfig         `yaml:"challenge"`
+	Policy            PolicyConfig            `yaml:"policy"`
+	RoleGrants        RoleGrantConfig         `yaml:"role_grants"`
+	Events            EventConfig             `yaml:"events"`
+	Elevation         ElevationConfig         `yaml:"elevation"`
+	Authz             AuthzConfig             `yaml:"authz"`
+	Relations         RelationConfig          `yaml:"relations"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Port int    `yaml:"port"`
 	Mode string `yaml:"mode"`
+	// TrustedProxies 可信反向代理的IP或CIDR，只有来自这些地址的请求才采信 X-Forwarded-For，
+	// 为空时使用连接的远端地址作为客户端IP
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 // LogConfig 日志配置
@@ -44,6 +62,243 @@ type JWTConfig struct {
 	RefreshExpire    int    `yaml:"refresh_expire"`     // 刷新令牌过期时间（小时）
 	Issuer           string `yaml:"issuer"`             // 签发者
 	RefreshTokenSize int    `yaml:"refresh_token_size"` // 刷新令牌大小
+	// RevocationCheckTTL 验证访问令牌时缓存用户令牌版本的时间（秒），0表示每次验证都查询数据库，
+	// 其他实例撤销的令牌最迟在此时间后被拒绝
+	RevocationCheckTTL  int `yaml:"revocation_check_ttl"`
+	RevocationCacheSize int `yaml:"revocation_cache_size"` // 最多缓存的令牌版本数，默认为10000
+}
+
+// PasswordPolicyConfig 密码策略配置
+type PasswordPolicyConfig struct {
+	MinLength        int    `yaml:"min_length"`         // 最小长度
+	MaxLength        int    `yaml:"max_length"`         // 最大长度，0表示不限制
+	RequireUpper     bool   `yaml:"require_upper"`      // 必须包含大写字母
+	RequireLower     bool   `yaml:"require_lower"`      // 必须包含小写字母
+	RequireDigit     bool   `yaml:"require_digit"`      // 必须包含数字
+	RequireSymbol    bool   `yaml:"require_symbol"`     // 必须包含特殊字符
+	DisallowUserInfo bool   `yaml:"disallow_user_info"` // 禁止包含用户名或邮箱
+	BannedListFile   string `yaml:"banned_list_file"`   // 禁用密码列表文件，每行一个
+	MinStrengthScore int    `yaml:"min_strength_score"` // 最低强度评分（0-4）
+
+	Breach BreachCheckConfig `yaml:"breach"`
+
+	HistorySize int                  `yaml:"history_size"` // 禁止重复使用的最近密码个数，0表示不限制
+	Expiry      PasswordExpiryConfig `yaml:"expiry"`
+}
+
+// PasswordExpiryConfig 密码过期配置
+type PasswordExpiryConfig struct {
+	DefaultDays int            `yaml:"default_days"` // 默认有效天数，0表示永不过期
+	Roles       map[string]int `yaml:"roles"`        // 按角色设置的有效天数，多个角色时取最短
+}
+
+// BreachCheckConfig 泄露密码检查配置，HashFile与FilterFile二选一
+type BreachCheckConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	HashFile   string `yaml:"hash_file"`   // 已排序的HIBP格式SHA-1文件
+	FilterFile string `yaml:"filter_file"` // 由 cmd/breachfilter 构建的过滤器文件
+	MinCount   int    `yaml:"min_count"`   // 判定为泄露的最小出现次数（仅对HashFile生效）
+}
+
+// PasswordHashConfig 密码哈希配置
+type PasswordHashConfig struct {
+	Algorithm string         `yaml:"algorithm"` // argon2id 或 bcrypt
+	Pepper    string         `yaml:"pepper"`    // 服务端密钥，设置后不可随意更改
+	Bcrypt    BcryptConfig   `yaml:"bcrypt"`
+	Argon2id  Argon2idConfig `yaml:"argon2id"`
+
+	// 轮换pepper（包括首次启用）期间设置 pepper_rotation，使旧哈希在下次登录时校验并升级，
+	// previous_pepper 为轮换前的pepper，为空表示此前未启用；轮换完成后应关闭以免失败的登录计算两次哈希
+	PepperRotation bool   `yaml:"pepper_rotation"`
+	PreviousPepper string `yaml:"previous_pepper"`
+}
+
+// BcryptConfig bcrypt参数
+type BcryptConfig struct {
+	Cost int `yaml:"cost"`
+}
+
+// Argon2idConfig argon2id参数
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory"`      // 内存大小（KiB）
+	Iterations  uint32 `yaml:"iterations"`  // 迭代次数
+	Parallelism uint8  `yaml:"parallelism"` // 并行度
+	SaltLength  uint32 `yaml:"salt_length"` // 盐长度（字节）
+	KeyLength   uint32 `yaml:"key_length"`  // 输出长度（字节）
+}
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver string     `yaml:"driver"` // log 或 smtp
+	From   string     `yaml:"from"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig SMTP服务器配置
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// EmailVerificationConfig 邮箱验证配置
+type EmailVerificationConfig struct {
+	Enabled               bool     `yaml:"enabled"`
+	TokenExpire           int      `yaml:"token_expire"`           // 验证令牌过期时间（小时）
+	ResendInterval        int      `yaml:"resend_interval"`        // 重新发送的最小间隔（秒）
+	VerifyURL             string   `yaml:"verify_url"`             // 验证链接模板，%s 会被替换为令牌
+	UnverifiedLogin       string   `yaml:"unverified_login"`       // 未验证时的登录策略：allow、block 或 restrict
+	RestrictedPermissions []string `yaml:"restricted_permissions"` // restrict 策略下保留的权限
+}
+
+// LoginProtectionConfig 登录防暴力破解配置
+type LoginProtectionConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	FreeAttempts      int  `yaml:"free_attempts"`       // 不受延迟限制的失败次数
+	BaseDelay         int  `yaml:"base_delay"`          // 初始延迟（秒），之后每次失败翻倍
+	MaxDelay          int  `yaml:"max_delay"`           // 最大延迟（秒）
+	MaxAttempts       int  `yaml:"max_attempts"`        // 同一用户名失败多少次后锁定
+	LockoutDuration   int  `yaml:"lockout_duration"`    // 用户名锁定时长（分钟）
+	IPMaxAttempts     int  `yaml:"ip_max_attempts"`     // 同一IP失败多少次后锁定
+	IPLockoutDuration int  `yaml:"ip_lockout_duration"` // IP锁定时长（分钟）
+	Window            int  `yaml:"window"`              // 失败次数统计窗口（分钟），超过后重新计数
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Enabled        bool                       `yaml:"enabled"`
+	Backend        string                     `yaml:"backend"`          // memory 或 redis
+	Redis          RedisConfig                `yaml:"redis"`            // backend 为 redis 时使用
+	ClientIDHeader string                     `yaml:"client_id_header"` // 按客户端限流时读取的请求头
+	Groups         map[string]RateLimitPolicy `yaml:"groups"`           // 按路由组配置的限流策略
+}
+
+// RateLimitPolicy 限流策略
+type RateLimitPolicy struct {
+	Limit  int    `yaml:"limit"`  // 窗口内允许的请求数
+	Window int    `yaml:"window"` // 窗口大小（秒）
+	Key    string `yaml:"key"`    // 限流维度：ip、user 或 client
+}
+
+// RedisConfig Redis配置
+type RedisConfig struct {
+	Addr      string `yaml:"addr"`
+	Password  string `yaml:"password"`
+	DB        int    `yaml:"db"`
+	KeyPrefix string `yaml:"key_prefix"`
+}
+
+// ChallengeConfig 人机验证配置，登录或注册达到风险阈值时要求客户端完成验证
+type ChallengeConfig struct {
+	Enabled               bool          `yaml:"enabled"`
+	Provider              string        `yaml:"provider"`                // pow、hcaptcha 或 turnstile
+	LoginFailureThreshold int           `yaml:"login_failure_threshold"` // 同一IP登录失败多少次后要求验证，0表示总是要求
+	RegisterThreshold     int           `yaml:"register_threshold"`      // 同一IP在窗口内注册多少次后要求验证，0表示总是要求
+	RegisterWindow        int           `yaml:"register_window"`         // 注册次数统计窗口（分钟）
+	Pow                   PowConfig     `yaml:"pow"`
+	Captcha               CaptchaConfig `yaml:"captcha"`
+}
+
+// PowConfig 工作量证明配置
+type PowConfig struct {
+	Difficulty int    `yaml:"difficulty"` // 要求哈希开头为0的位数
+	Expire     int    `yaml:"expire"`     // 挑战有效期（秒）
+	Secret     string `yaml:"secret"`     // 挑战签名密钥，启用时必须配置且不能与JWT密钥相同
+}
+
+// CaptchaConfig 第三方验证码配置（hCaptcha、Turnstile等）
+type CaptchaConfig struct {
+	VerifyURL string `yaml:"verify_url"` // 校验接口地址，为空时使用服务商默认地址
+	SiteKey   string `yaml:"site_key"`   // 前端使用的站点密钥
+	Secret    string `yaml:"secret"`     // 服务端密钥
+	Timeout   int    `yaml:"timeout"`    // 校验请求超时（秒）
+}
+
+// PolicyConfig 基于属性的访问控制配置
+type PolicyConfig struct {
+	Enabled        bool         `yaml:"enabled"`
+	Policies       []PolicyRule `yaml:"policies"`        // 配置文件中的策略，与数据库中的策略一起生效
+	ReloadInterval int          `yaml:"reload_interval"` // 定期从数据库重新加载策略的间隔（秒），多实例部署时使其他实例的修改生效，0表示不定期重新加载
+}
+
+// PolicyRule 访问控制策略
+type PolicyRule struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Effect      string `yaml:"effect"`    // allow 或 deny
+	Action      string `yaml:"action"`    // 适用的操作，支持通配符
+	Condition   string `yaml:"condition"` // CEL表达式
+}
+
+// RoleGrantConfig 限时角色配置
+type RoleGrantConfig struct {
+	SweepInterval int `yaml:"sweep_interval"` // 清理到期授予的间隔（秒），0表示不启动后台清理
+	NotifyBefore  int `yaml:"notify_before"`  // 到期前多久发送即将到期事件（分钟），0表示不发送
+}
+
+// EventConfig 事件发布配置
+type EventConfig struct {
+	Driver     string `yaml:"driver"`      // log 或 webhook
+	WebhookURL string `yaml:"webhook_url"` // webhook 驱动接收事件的地址
+	Timeout    int    `yaml:"timeout"`     // webhook 请求超时（秒）
+}
+
+// ElevationConfig 临时提权配置
+type ElevationConfig struct {
+	ApproverPermission string `yaml:"approver_permission"` // 审批人需要拥有的权限，默认为 elevation:approve
+	MaxDuration        int    `yaml:"max_duration"`        // 可申请的最长时长（分钟），0表示不限制
+}
+
+// AuthzConfig 集中授权决策配置
+type AuthzConfig struct {
+	CacheTTL  int `yaml:"cache_ttl"`  // 决策缓存时间（秒），0表示不缓存
+	CacheSize int `yaml:"cache_size"` // 最多缓存的决策数，默认为10000
+	MaxBatch  int `yaml:"max_batch"`  // 单次批量检查的最大数量，默认为100
+}
+
+// RelationConfig 基于关系的访问控制配置
+type RelationConfig struct {
+	Namespaces  []RelationNamespace `yaml:"namespaces"`   // 命名空间（对象类型）及其关系定义
+	MaxDepth    int                 `yaml:"max_depth"`    // 检查时关系展开的最大深度，默认为25
+	SnapshotTTL int                 `yaml:"snapshot_ttl"` // 低延迟模式下最新版本号的缓存时间（毫秒），0表示每次读取
+	CacheTTL    int                 `yaml:"cache_ttl"`    // 按版本缓存检查结果的时间（秒），0表示不缓存
+	CacheSize   int                 `yaml:"cache_size"`   // 最多缓存的检查结果数，默认为10000
+}
+
+// RelationNamespace 命名空间，对象以 <命名空间>:<ID> 表示
+type RelationNamespace struct {
+	Name      string               `yaml:"name"`
+	Relations []RelationDefinition `yaml:"relations"`
+}
+
+// RelationDefinition 关系定义，未配置 rewrite 时关系只包含直接写入的元组
+type RelationDefinition struct {
+	Name    string          `yaml:"name"`
+	Rewrite *UsersetRewrite `yaml:"rewrite"`
+}
+
+// UsersetRewrite 关系的计算规则，每个节点只能设置一项：
+// this、computed_userset、tuple_to_userset 为叶子，union、intersection、exclusion 组合子规则
+type UsersetRewrite struct {
+	This            bool             `yaml:"this"`             // 直接写入该关系的元组
+	ComputedUserset string           `yaml:"computed_userset"` // 同一对象的另一个关系
+	TupleToUserset  *TupleToUserset  `yaml:"tuple_to_userset"` // 沿 tupleset 关系找到的对象上的关系
+	Union           []UsersetRewrite `yaml:"union"`
+	Intersection    []UsersetRewrite `yaml:"intersection"`
+	Exclusion       *Exclusion       `yaml:"exclusion"`
+}
+
+// TupleToUserset 如文档的 viewer 包含其 parent 文件夹的 viewer
+type TupleToUserset struct {
+	Tupleset        string `yaml:"tupleset"`
+	ComputedUserset string `yaml:"computed_userset"`
+}
+
+// Exclusion 属于 base 但不属于 subtract 的主体
+type Exclusion struct {
+	Base     UsersetRewrite `yaml:"base"`
+	Subtract UsersetRewrite `yaml:"subtract"`
 }
 
 // LoadConfig 从文件加载配置
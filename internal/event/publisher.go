@@ -0,0 +1,92 @@
+package event
+
+import (
+	"authentication/internal/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeRoleGrantExpiring = "role_grant.expiring" // 限时角色即将到期
+	TypeRoleGrantExpired  = "role_grant.expired"  // 限时角色已到期并被移除
+
+	TypeElevationRequested = "elevation.requested" // 提交了提权申请，data 中包含审批人
+	TypeElevationApproved  = "elevation.approved"  // 提权申请已通过
+	TypeElevationDenied    = "elevation.denied"    // 提权申请被拒绝
+)
+
+// Event 系统事件
+type Event struct {
+	Type string                 `json:"type"`
+	Time time.Time              `json:"time"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// New 创建当前时间发生的事件
+func New(eventType string, data map[string]interface{}) Event {
+	return Event{Type: eventType, Time: time.Now(), Data: data}
+}
+
+// Publisher 事件发布接口
+type Publisher interface {
+	Publish(event Event) error
+}
+
+// NewPublisher 根据配置创建事件发布实例
+func NewPublisher(cfg config.EventConfig) (Publisher, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &logPublisher{}, nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook 事件驱动需要配置 webhook_url")
+		}
+		timeout := time.Duration(cfg.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		return &webhookPublisher{url: cfg.WebhookURL, client: &http.Client{Timeout: timeout}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的事件驱动: %s", cfg.Driver)
+	}
+}
+
+// logPublisher 仅将事件写入日志，用于开发环境
+type logPublisher struct{}
+
+// Publish 将事件写入日志
+func (p *logPublisher) Publish(event Event) error {
+	data, _ := json.Marshal(event.Data)
+	log.Printf("事件 type=%s time=%s data=%s", event.Type, event.Time.Format(time.RFC3339), data)
+	return nil
+}
+
+// webhookPublisher 以JSON格式将事件POST到指定地址
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// Publish 发送事件，响应状态码不是2xx时返回错误
+func (p *webhookPublisher) Publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("发送事件失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("发送事件失败: 状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler 创建审计日志处理器实例
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs 获取审计日志，可按操作人、操作和对象过滤
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取过滤条件
+	actorID, _ := strconv.ParseUint(c.Query("actor_id"), 10, 32)
+	targetID, _ := strconv.ParseUint(c.Query("target_id"), 10, 32)
+	filter := service.AuditLogFilter{
+		ActorID:    uint(actorID),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   uint(targetID),
+	}
+
+	// 获取审计日志
+	entries, total, err := h.auditService.List(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计日志失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  entries,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
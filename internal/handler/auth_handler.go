@@ -2,20 +2,29 @@ package handler
 
 import (
 	"authentication/internal/service"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// challengeResponseHeader 客户端提交人机验证结果的请求头
+const challengeResponseHeader = "X-Challenge-Response"
+
 // AuthHandler 认证处理器接口
 type AuthHandler struct {
-	authService service.AuthService
+	authService         service.AuthService
+	verificationService service.VerificationService
+	challengeService    service.ChallengeService
 }
 
 // NewAuthHandler 创建认证处理器实例
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
+func NewAuthHandler(authService service.AuthService, verificationService service.VerificationService, challengeService service.ChallengeService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:         authService,
+		verificationService: verificationService,
+		challengeService:    challengeService,
 	}
 }
 
@@ -27,10 +36,16 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// 达到风险阈值时要求人机验证
+	if !h.checkChallenge(c, service.ChallengeActionRegister) {
+		return
+	}
+
 	if err := h.authService.Register(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	h.challengeService.RecordRegistration(c.ClientIP())
 
 	c.JSON(http.StatusCreated, gin.H{"message": "用户注册成功"})
 }
@@ -43,9 +58,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.ClientIP = c.ClientIP()
+
+	// 达到风险阈值时要求人机验证
+	if !h.checkChallenge(c, service.ChallengeActionLogin) {
+		return
+	}
+
 	tokenPair, err := h.authService.Login(req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		// 失败次数过多时返回429并告知重试时间
+		var blockedErr *service.LoginBlockedError
+		if errors.As(err, &blockedErr) {
+			c.Header("Retry-After", strconv.Itoa(int(blockedErr.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return
+		}
+		// 激活的角色无效或违反职责分离约束时需要重新选择角色
+		if errors.Is(err, service.ErrSeparationViolation) || errors.Is(err, service.ErrRoleNotHeld) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
@@ -62,7 +96,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	tokenPair, err := h.authService.RefreshToken(req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
@@ -87,3 +121,168 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// UpdateProfile 修改当前用户的个人资料，仅允许修改姓名和邮箱
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req service.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.UpdateProfile(userID.(uint), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword 修改当前用户密码
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangePassword(userID.(uint), req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码修改成功"})
+}
+
+// ListOrganizations 获取当前用户加入的组织
+func (h *AuthHandler) ListOrganizations(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	memberships, err := h.authService.ListOrganizations(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取组织列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": memberships, "current": c.GetUint("orgID")})
+}
+
+// SwitchOrganization 切换当前用户所在的组织，返回新的令牌对
+func (h *AuthHandler) SwitchOrganization(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req service.SwitchOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, err := h.authService.SwitchOrganization(userID.(uint), req)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) || errors.Is(err, service.ErrSeparationViolation) || errors.Is(err, service.ErrRoleNotHeld) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenPair)
+}
+
+// VerifyEmail 验证邮箱，支持通过链接（GET ?token=）或JSON请求体提交令牌
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req service.VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.verificationService.Verify(req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱验证成功"})
+}
+
+// ResendVerification 重新发送验证邮件
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req service.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.verificationService.Resend(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送验证邮件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且尚未验证，验证邮件将很快送达"})
+}
+
+// GetChallenge 获取人机验证挑战（工作量证明）或前端验证码参数
+func (h *AuthHandler) GetChallenge(c *gin.Context) {
+	if !h.challengeService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用人机验证"})
+		return
+	}
+
+	challenge, err := h.challengeService.Issue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成人机验证挑战失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// checkChallenge 需要人机验证时校验请求头中的验证结果，未通过时写入响应并返回false
+// 响应中会附带新的挑战，客户端无需再次请求即可重试。
+func (h *AuthHandler) checkChallenge(c *gin.Context, action string) bool {
+	if !h.challengeService.Required(action, c.ClientIP()) {
+		return true
+	}
+
+	err := h.challengeService.Verify(c.Request.Context(), c.GetHeader(challengeResponseHeader), c.ClientIP())
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, service.ErrChallengeRequired) && !errors.Is(err, service.ErrChallengeFailed) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "人机验证服务暂不可用"})
+		return false
+	}
+
+	resp := errorResponse(err)
+	if challenge, issueErr := h.challengeService.Issue(); issueErr == nil && challenge != nil {
+		resp["challenge"] = challenge
+	}
+	c.JSON(http.StatusPreconditionRequired, resp)
+	return false
+}
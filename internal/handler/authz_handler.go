@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthzHandler 集中授权决策处理器
+type AuthzHandler struct {
+	authzService service.AuthzService
+}
+
+// NewAuthzHandler 创建集中授权决策处理器实例
+func NewAuthzHandler(authzService service.AuthzService) *AuthzHandler {
+	return &AuthzHandler{
+		authzService: authzService,
+	}
+}
+
+// checkRequest 批量授权检查请求
+type checkRequest struct {
+	Checks []service.AuthzCheckRequest `json:"checks" binding:"required,min=1,dive"`
+}
+
+// Check 批量检查用户能否对资源执行操作，结果与请求顺序一致
+func (h *AuthzHandler) Check(c *gin.Context) {
+	// 绑定请求数据
+	var req checkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 检查授权
+	decisions, err := h.authzService.CheckBatch(req.Checks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": decisions})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ElevationHandler 临时提权处理器
+type ElevationHandler struct {
+	elevationService service.ElevationService
+}
+
+// NewElevationHandler 创建临时提权处理器实例
+func NewElevationHandler(elevationService service.ElevationService) *ElevationHandler {
+	return &ElevationHandler{
+		elevationService: elevationService,
+	}
+}
+
+// CreateRequest 提交提权申请
+func (h *ElevationHandler) CreateRequest(c *gin.Context) {
+	// 绑定请求数据
+	var req service.CreateElevationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 提交申请
+	request, err := h.elevationService.Request(c.GetUint("userID"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrInvalidDuration):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrElevationPending), errors.Is(err, service.ErrRoleAlreadyHeld):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "提交提权申请失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// ListMine 获取当前用户的提权申请
+func (h *ElevationHandler) ListMine(c *gin.Context) {
+	h.list(c, c.GetUint("userID"))
+}
+
+// ListRequests 获取全部用户的提权申请，可通过 status 过滤
+func (h *ElevationHandler) ListRequests(c *gin.Context) {
+	h.list(c, 0)
+}
+
+// list 分页获取提权申请
+func (h *ElevationHandler) list(c *gin.Context, userID uint) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取申请列表
+	requests, total, err := h.elevationService.List(userID, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取提权申请列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  requests,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// Approve 通过提权申请，审批人必须拥有所申请角色的全部权限
+func (h *ElevationHandler) Approve(c *gin.Context) {
+	h.review(c, h.elevationService.ForOperator(operatorPermissions(c)).Approve)
+}
+
+// Deny 拒绝提权申请
+func (h *ElevationHandler) Deny(c *gin.Context) {
+	h.review(c, h.elevationService.Deny)
+}
+
+// review 解析请求并执行审批，审批人为当前用户
+func (h *ElevationHandler) review(c *gin.Context, review func(id, reviewerID uint, req service.ReviewElevationRequest) (*model.ElevationRequest, error)) {
+	// 获取申请ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的申请ID"})
+		return
+	}
+
+	// 绑定请求数据，审批意见可以为空
+	var req service.ReviewElevationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 审批
+	request, err := review(uint(id), c.GetUint("userID"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "提权申请不存在"})
+		case errors.Is(err, service.ErrSelfApproval), errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, service.ErrElevationNotPending), errors.Is(err, service.ErrRoleAlreadyHeld), errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "审批提权申请失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
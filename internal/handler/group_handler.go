@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GroupHandler 用户分组处理器
+type GroupHandler struct {
+	groupService service.GroupService
+}
+
+// NewGroupHandler 创建用户分组处理器实例
+func NewGroupHandler(groupService service.GroupService) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+	}
+}
+
+// groups 返回以当前用户权限修改分组的分组服务
+func (h *GroupHandler) groups(c *gin.Context) service.GroupService {
+	return h.groupService.ForOperator(operatorPermissions(c))
+}
+
+// ListGroups 获取分组列表
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取分组列表
+	groups, total, err := h.groups(c).List(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分组列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  groups,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// CreateGroup 创建分组
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	// 绑定请求数据
+	var req struct {
+		Name        string `json:"name" binding:"required,max=100"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+		ParentID    *uint  `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建分组
+	group := model.Group{Name: req.Name, Description: req.Description, ParentID: req.ParentID}
+	if err := h.groups(c).Create(&group); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "分组创建成功", "id": group.ID})
+}
+
+// GetGroup 获取分组详情
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 获取分组信息
+	group, err := h.groups(c).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// UpdateGroup 更新分组信息，parent_id 为0时移到顶层
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 获取分组信息
+	group, err := h.groups(c).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+		return
+	}
+
+	// 绑定请求数据
+	var updateData struct {
+		Name        string `json:"name" binding:"omitempty,max=100"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+		ParentID    *uint  `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新分组信息
+	if updateData.Name != "" {
+		group.Name = updateData.Name
+	}
+	if updateData.Description != "" {
+		group.Description = updateData.Description
+	}
+	if updateData.ParentID != nil {
+		group.ParentID = updateData.ParentID
+		if *updateData.ParentID == 0 {
+			group.ParentID = nil
+		}
+	}
+
+	// 保存更新
+	if err := h.groups(c).Update(group); err != nil {
+		if errors.Is(err, service.ErrGroupCycle) || errors.Is(err, service.ErrLastAdmin) || errors.Is(err, service.ErrSeparationViolation) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrPrivilegeEscalation) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分组更新成功"})
+}
+
+// DeleteGroup 删除分组，存在子分组时不能删除
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 删除分组
+	if err := h.groups(c).Delete(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+		case errors.Is(err, service.ErrGroupHasChildren), errors.Is(err, service.ErrLastAdmin):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除分组失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分组删除成功"})
+}
+
+// ListMembers 获取分组的直接成员
+func (h *GroupHandler) ListMembers(c *gin.Context) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 获取成员列表
+	members, err := h.groups(c).ListMembers(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// AddMembers 将用户加入分组
+func (h *GroupHandler) AddMembers(c *gin.Context) {
+	h.updateMembers(c, h.groups(c).AddMembers)
+}
+
+// RemoveMembers 将用户移出分组
+func (h *GroupHandler) RemoveMembers(c *gin.Context) {
+	h.updateMembers(c, h.groups(c).RemoveMembers)
+}
+
+// updateMembers 解析请求并修改分组成员
+func (h *GroupHandler) updateMembers(c *gin.Context, update func(groupID uint, userIDs []uint) error) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.GroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改成员
+	if err := update(uint(id), req.UserIDs); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+		case errors.Is(err, service.ErrUserNotFound):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, service.ErrLastAdmin), errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "修改分组成员失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分组成员已更新"})
+}
+
+// SetRoles 替换分组的角色
+func (h *GroupHandler) SetRoles(c *gin.Context) {
+	// 获取分组ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改角色
+	group, err := h.groups(c).SetRoles(uint(id), req.RoleIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "分组不存在"})
+		case errors.Is(err, service.ErrRoleNotFound):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, service.ErrLastAdmin), errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "分配角色失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OrganizationHandler 组织处理器
+type OrganizationHandler struct {
+	organizationService service.OrganizationService
+}
+
+// NewOrganizationHandler 创建组织处理器实例
+func NewOrganizationHandler(organizationService service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+// ListOrganizations 获取组织列表
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取组织列表
+	orgs, total, err := h.organizationService.List(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取组织列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  orgs,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// CreateOrganization 创建组织
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	// 绑定请求数据
+	var req struct {
+		Name        string `json:"name" binding:"required,max=100"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建组织
+	org := model.Organization{Name: req.Name, Description: req.Description}
+	if err := h.organizationService.Create(&org); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "组织创建成功", "id": org.ID})
+}
+
+// GetOrganization 获取组织详情
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	// 获取组织ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+
+	// 获取组织信息
+	org, err := h.organizationService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// UpdateOrganization 更新组织信息
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	// 获取组织ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+
+	// 获取组织信息
+	org, err := h.organizationService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+		return
+	}
+
+	// 绑定请求数据
+	var updateData struct {
+		Name        string `json:"name" binding:"omitempty,max=100"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新组织信息
+	if updateData.Name != "" {
+		org.Name = updateData.Name
+	}
+	if updateData.Description != "" {
+		org.Description = updateData.Description
+	}
+
+	// 保存更新
+	if err := h.organizationService.Update(org); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "组织更新成功"})
+}
+
+// DeleteOrganization 删除组织，同时删除其成员关系和组织角色
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	// 获取组织ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+
+	// 删除组织
+	if err := h.organizationService.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除组织失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "组织删除成功"})
+}
+
+// ListMembers 获取组织成员
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	// 获取组织ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+
+	// 获取成员列表
+	members, err := h.organizationService.ListMembers(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// SetMember 添加组织成员或替换其组织角色
+func (h *OrganizationHandler) SetMember(c *gin.Context) {
+	// 获取组织ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.SetMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 以当前用户的权限设置成员
+	membership, err := h.organizationService.ForOperator(operatorPermissions(c)).SetMember(uint(id), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "组织或用户不存在"})
+		case errors.Is(err, service.ErrRoleNotFound):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "设置组织成员失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// RemoveMember 将用户移出组织
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	// 获取组织ID和用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的组织ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 移除成员
+	if err := h.organizationService.RemoveMember(uint(id), uint(userID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不是该组织的成员"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除组织成员失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成员已移除"})
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"authentication/internal/model"
 	"authentication/internal/service"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // PermissionHandler 权限处理器
@@ -40,3 +43,127 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 		"size":  pageSize,
 	})
 }
+
+// CreatePermission 创建权限
+func (h *PermissionHandler) CreatePermission(c *gin.Context) {
+	// 绑定请求数据
+	var req struct {
+		Code        string `json:"code" binding:"required,max=50"`
+		Name        string `json:"name" binding:"required,max=50"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建权限
+	permission := model.Permission{Code: req.Code, Name: req.Name, Description: req.Description}
+	if err := h.permissionService.Create(&permission); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "权限创建成功", "id": permission.ID})
+}
+
+// GetPermission 获取权限详情
+func (h *PermissionHandler) GetPermission(c *gin.Context) {
+	// 获取权限ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的权限ID"})
+		return
+	}
+
+	// 获取权限信息
+	permission, err := h.permissionService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "权限不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permission)
+}
+
+// UpdatePermission 更新权限信息
+func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
+	// 获取权限ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的权限ID"})
+		return
+	}
+
+	// 获取权限信息
+	permission, err := h.permissionService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "权限不存在"})
+		return
+	}
+
+	// 绑定请求数据
+	var updateData struct {
+		Code        string `json:"code" binding:"omitempty,max=50"`
+		Name        string `json:"name" binding:"omitempty,max=50"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新权限信息
+	if updateData.Code != "" {
+		permission.Code = updateData.Code
+	}
+	if updateData.Name != "" {
+		permission.Name = updateData.Name
+	}
+	if updateData.Description != "" {
+		permission.Description = updateData.Description
+	}
+
+	// 保存更新
+	if err := h.permissionService.Update(permission); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "权限更新成功"})
+}
+
+// DeletePermission 删除权限，同时移除其与角色的关联
+func (h *PermissionHandler) DeletePermission(c *gin.Context) {
+	// 获取权限ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的权限ID"})
+		return
+	}
+
+	// 删除权限
+	if err := h.permissionService.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "权限不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除权限失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "权限删除成功"})
+}
+
+// ListOrphans 获取未被任何路由使用的权限
+func (h *PermissionHandler) ListOrphans(c *gin.Context) {
+	orphans, err := h.permissionService.Orphans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取权限列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": orphans})
+}
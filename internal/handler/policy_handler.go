@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PolicyHandler 访问控制策略处理器
+type PolicyHandler struct {
+	policyService service.PolicyService
+}
+
+// NewPolicyHandler 创建访问控制策略处理器实例
+func NewPolicyHandler(policyService service.PolicyService) *PolicyHandler {
+	return &PolicyHandler{
+		policyService: policyService,
+	}
+}
+
+// ListPolicies 获取策略列表
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.policyService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取策略列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policies})
+}
+
+// CreatePolicy 创建策略
+func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
+	// 绑定请求数据
+	var req struct {
+		Name        string `json:"name" binding:"required,max=100"`
+		Description string `json:"description" binding:"omitempty,max=255"`
+		Effect      string `json:"effect" binding:"required,oneof=allow deny"`
+		Action      string `json:"action" binding:"required,max=100"`
+		Condition   string `json:"condition" binding:"required"`
+		Enabled     *bool  `json:"enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建策略
+	policy := model.Policy{
+		Name:        req.Name,
+		Description: req.Description,
+		Effect:      req.Effect,
+		Action:      req.Action,
+		Condition:   req.Condition,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if err := h.policyService.Create(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// GetPolicy 获取策略详情
+func (h *PolicyHandler) GetPolicy(c *gin.Context) {
+	// 获取策略ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的策略ID"})
+		return
+	}
+
+	// 获取策略信息
+	policy, err := h.policyService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "策略不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy 更新策略
+func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
+	// 获取策略ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的策略ID"})
+		return
+	}
+
+	// 获取策略信息
+	policy, err := h.policyService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "策略不存在"})
+		return
+	}
+
+	// 绑定请求数据
+	var updateData struct {
+		Name        string  `json:"name" binding:"omitempty,max=100"`
+		Description *string `json:"description" binding:"omitempty,max=255"`
+		Effect      string  `json:"effect" binding:"omitempty,oneof=allow deny"`
+		Action      string  `json:"action" binding:"omitempty,max=100"`
+		Condition   string  `json:"condition" binding:"omitempty"`
+		Enabled     *bool   `json:"enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新策略信息
+	if updateData.Name != "" {
+		policy.Name = updateData.Name
+	}
+	if updateData.Description != nil {
+		policy.Description = *updateData.Description
+	}
+	if updateData.Effect != "" {
+		policy.Effect = updateData.Effect
+	}
+	if updateData.Action != "" {
+		policy.Action = updateData.Action
+	}
+	if updateData.Condition != "" {
+		policy.Condition = updateData.Condition
+	}
+	if updateData.Enabled != nil {
+		policy.Enabled = *updateData.Enabled
+	}
+
+	// 保存更新
+	if err := h.policyService.Update(policy); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy 删除策略
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	// 获取策略ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的策略ID"})
+		return
+	}
+
+	// 删除策略
+	if err := h.policyService.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "策略不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "策略删除成功"})
+}
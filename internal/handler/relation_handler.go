@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelationHandler 基于关系的访问控制处理器
+type RelationHandler struct {
+	relationService service.RelationService
+}
+
+// NewRelationHandler 创建基于关系的访问控制处理器实例
+func NewRelationHandler(relationService service.RelationService) *RelationHandler {
+	return &RelationHandler{
+		relationService: relationService,
+	}
+}
+
+// ListTuples 获取当前有效的关系元组，可按对象、关系和主体过滤
+func (h *RelationHandler) ListTuples(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取过滤条件
+	filter := service.RelationTupleFilter{
+		Namespace:        c.Query("namespace"),
+		ObjectID:         c.Query("object_id"),
+		Relation:         c.Query("relation"),
+		SubjectNamespace: c.Query("subject_namespace"),
+		SubjectID:        c.Query("subject_id"),
+		SubjectRelation:  c.Query("subject_relation"),
+	}
+
+	// 获取关系元组
+	tuples, total, err := h.relationService.List(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取关系元组失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tuples,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// WriteTuples 写入和删除关系元组，返回可用于后续检查的一致性令牌
+func (h *RelationHandler) WriteTuples(c *gin.Context) {
+	// 绑定请求数据
+	var req service.WriteRelationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 写入关系元组
+	token, err := h.relationService.Write(req)
+	if err != nil {
+		relationError(c, err, "写入关系元组失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// Check 检查主体是否拥有对象的关系
+func (h *RelationHandler) Check(c *gin.Context) {
+	// 绑定请求数据
+	var req service.RelationCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 检查关系
+	result, err := h.relationService.Check(req)
+	if err != nil {
+		relationError(c, err, "检查关系失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Expand 展开对象的关系，返回由计算规则组成的树
+func (h *RelationHandler) Expand(c *gin.Context) {
+	// 绑定请求数据
+	var req service.RelationExpandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 展开关系
+	result, err := h.relationService.Expand(req)
+	if err != nil {
+		relationError(c, err, "展开关系失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExpandObject 返回展开命名空间 namespace 中路由参数 id 指定的对象的关系的处理函数，关系由查询参数 relation 指定，
+// 供对象的所有者查看对象的共享情况。请求头 X-Consistency-Token 的用法与 HasRelation 中间件相同。
+func (h *RelationHandler) ExpandObject(namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		relationName := c.Query("relation")
+		if relationName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少关系参数"})
+			return
+		}
+
+		// 展开关系
+		result, err := h.relationService.Expand(service.RelationExpandRequest{
+			Object:      namespace + ":" + c.Param("id"),
+			Relation:    relationName,
+			Consistency: service.Consistency{Token: c.GetHeader("X-Consistency-Token")},
+		})
+		if err != nil {
+			relationError(c, err, "展开关系失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ListObjects 列出主体拥有关系的对象
+func (h *RelationHandler) ListObjects(c *gin.Context) {
+	// 绑定请求数据
+	var req service.ListObjectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 列出对象
+	result, err := h.relationService.ListObjects(req)
+	if err != nil {
+		relationError(c, err, "列出对象失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// relationError 返回关系接口的错误响应，请求数据错误返回400，关系展开过深或排除规则成环返回422，其他错误返回 message
+func relationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTuple),
+		errors.Is(err, service.ErrUnknownRelation),
+		errors.Is(err, service.ErrInvalidConsistencyToken),
+		errors.Is(err, service.ErrEmptyRelationWrite):
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+	case errors.Is(err, service.ErrRelationDepth),
+		errors.Is(err, service.ErrRelationExclusionCycle):
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
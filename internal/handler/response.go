@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCodes 需要返回机器可读错误代码的业务错误
+var errorCodes = map[error]string{
+	service.ErrEmailNotVerified:         "email_not_verified",
+	service.ErrInvalidVerificationToken: "invalid_verification_token",
+	service.ErrChallengeRequired:        "challenge_required",
+	service.ErrChallengeFailed:          "challenge_failed",
+	service.ErrRoleNotFound:             "role_not_found",
+	service.ErrLastAdmin:                "last_admin",
+	service.ErrUserNotDeleted:           "user_not_deleted",
+	service.ErrUserConflict:             "user_conflict",
+	service.ErrSharedAccount:            "shared_account",
+	service.ErrRoleCycle:                "role_cycle",
+	service.ErrPrivilegeEscalation:      "privilege_escalation",
+	service.ErrAdminRoleProtected:       "admin_role_protected",
+	service.ErrPlatformPermission:       "platform_permission",
+	service.ErrInvalidPermissionCode:    "invalid_permission_code",
+	service.ErrInvalidPolicy:            "invalid_policy",
+	service.ErrNotMember:                "not_member",
+	service.ErrGroupNotFound:            "group_not_found",
+	service.ErrGroupCycle:               "group_cycle",
+	service.ErrGroupHasChildren:         "group_has_children",
+	service.ErrUserNotFound:             "user_not_found",
+	service.ErrInvalidGrantWindow:       "invalid_grant_window",
+	service.ErrTokenRevoked:             "token_revoked",
+	service.ErrElevationNotPending:      "elevation_not_pending",
+	service.ErrElevationPending:         "elevation_pending",
+	service.ErrSelfApproval:             "self_approval",
+	service.ErrRoleAlreadyHeld:          "role_already_held",
+	service.ErrInvalidDuration:          "invalid_duration",
+	service.ErrInvalidConstraint:        "invalid_constraint",
+	service.ErrRoleNotHeld:              "role_not_held",
+	service.ErrAuthzBatchTooLarge:       "batch_too_large",
+	service.ErrInvalidAuthzContext:      "invalid_context",
+	service.ErrInvalidTuple:             "invalid_tuple",
+	service.ErrUnknownRelation:          "unknown_relation",
+	service.ErrInvalidConsistencyToken:  "invalid_consistency_token",
+	service.ErrRelationDepth:            "relation_depth_exceeded",
+	service.ErrRelationExclusionCycle:   "relation_exclusion_cycle",
+	service.ErrEmptyRelationWrite:       "empty_write",
+}
+
+// errorResponse 构造错误响应，密码策略错误会附带机器可读的违规代码
+func errorResponse(err error) gin.H {
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return gin.H{
+			"error":      err.Error(),
+			"code":       "password_policy_violation",
+			"violations": policyErr.Violations,
+		}
+	}
+	var separationErr *service.SeparationError
+	if errors.As(err, &separationErr) {
+		return gin.H{
+			"error":      err.Error(),
+			"code":       "separation_violation",
+			"violations": separationErr.Violations,
+		}
+	}
+	var blockedErr *service.LoginBlockedError
+	if errors.As(err, &blockedErr) {
+		code := "login_throttled"
+		if blockedErr.Locked {
+			code = "account_locked"
+		}
+		return gin.H{"error": err.Error(), "code": code}
+	}
+	for target, code := range errorCodes {
+		if errors.Is(err, target) {
+			return gin.H{"error": err.Error(), "code": code}
+		}
+	}
+	return gin.H{"error": err.Error()}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RoleGrantHandler 限时角色处理器
+type RoleGrantHandler struct {
+	roleGrantService service.RoleGrantService
+}
+
+// NewRoleGrantHandler 创建限时角色处理器实例
+func NewRoleGrantHandler(roleGrantService service.RoleGrantService) *RoleGrantHandler {
+	return &RoleGrantHandler{
+		roleGrantService: roleGrantService,
+	}
+}
+
+// ListGrants 获取用户的全部角色授予及其有效期
+func (h *RoleGrantHandler) ListGrants(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 获取授予列表
+	grants, err := h.roleGrantService.ListByUser(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取角色授予失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": grants})
+}
+
+// GrantRole 授予用户限时或计划生效的全局角色
+func (h *RoleGrantHandler) GrantRole(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.GrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 以当前用户的权限授予角色，记录授予人
+	grant, err := h.roleGrantService.ForOperator(operatorPermissions(c)).Grant(uint(id), c.GetUint("userID"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrInvalidGrantWindow):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrLastAdmin), errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "授予角色失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
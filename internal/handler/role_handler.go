@@ -3,10 +3,12 @@ package handler
 import (
 	"authentication/internal/model"
 	"authentication/internal/service"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RoleHandler 角色处理器
@@ -21,6 +23,11 @@ func NewRoleHandler(roleService service.RoleService) *RoleHandler {
 	}
 }
 
+// roles 返回当前请求所在组织范围内、以当前用户权限修改继承关系的角色服务
+func (h *RoleHandler) roles(c *gin.Context) service.RoleService {
+	return h.roleService.ForOrganization(c.GetUint("orgID")).ForOperator(operatorPermissions(c))
+}
+
 // ListRoles 获取角色列表
 func (h *RoleHandler) ListRoles(c *gin.Context) {
 	// 获取分页参数
@@ -28,7 +35,7 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
 	// 获取角色列表
-	roles, total, err := h.roleService.List(page, pageSize)
+	roles, total, err := h.roles(c).List(page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取角色列表失败"})
 		return
@@ -42,17 +49,21 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	})
 }
 
-// CreateRole 创建角色
+// CreateRole 创建角色，权限和上级角色通过各自的接口设置，以便检查操作者能否授予
 func (h *RoleHandler) CreateRole(c *gin.Context) {
 	// 绑定请求数据
-	var role model.Role
-	if err := c.ShouldBindJSON(&role); err != nil {
+	var req struct {
+		Name        string `json:"name" binding:"required,max=50"`
+		Description string `json:"description" binding:"omitempty,max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	role := model.Role{Name: req.Name, Description: req.Description}
 
 	// 创建角色
-	if err := h.roleService.Create(&role); err != nil {
+	if err := h.roles(c).Create(&role); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -70,7 +81,7 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 	}
 
 	// 获取角色信息
-	role, err := h.roleService.GetByID(uint(id))
+	role, err := h.roles(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "角色不存在"})
 		return
@@ -89,7 +100,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	}
 
 	// 获取角色信息
-	role, err := h.roleService.GetByID(uint(id))
+	role, err := h.roles(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "角色不存在"})
 		return
@@ -115,7 +126,11 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	}
 
 	// 保存更新
-	if err := h.roleService.Update(role); err != nil {
+	if err := h.roles(c).Update(role); err != nil {
+		if errors.Is(err, service.ErrAdminRoleProtected) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -133,7 +148,11 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 	}
 
 	// 删除角色
-	if err := h.roleService.Delete(uint(id)); err != nil {
+	if err := h.roles(c).Delete(uint(id)); err != nil {
+		if errors.Is(err, service.ErrAdminRoleProtected) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -161,10 +180,112 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 	}
 
 	// 分配权限
-	if err := h.roleService.AssignPermissions(uint(id), req.PermissionIDs); err != nil {
+	if err := h.roles(c).AssignPermissions(uint(id), req.PermissionIDs); err != nil {
+		if errors.Is(err, service.ErrPlatformPermission) || errors.Is(err, service.ErrPrivilegeEscalation) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "权限分配成功"})
 }
+
+// AssignDeniedPermissions 替换角色显式拒绝的权限
+func (h *RoleHandler) AssignDeniedPermissions(c *gin.Context) {
+	// 获取角色ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req struct {
+		PermissionIDs []uint `json:"permission_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 分配拒绝的权限
+	if err := h.roles(c).AssignDeniedPermissions(uint(id), req.PermissionIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "拒绝权限设置成功"})
+}
+
+// GetHierarchy 获取角色继承关系
+func (h *RoleHandler) GetHierarchy(c *gin.Context) {
+	nodes, err := h.roles(c).Hierarchy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取角色继承关系失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": nodes})
+}
+
+// GetPermissions 获取角色的直接权限和继承权限
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	// 获取角色ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色ID"})
+		return
+	}
+
+	// 获取权限
+	permissions, err := h.roles(c).GetPermissions(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "角色不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// SetParents 设置角色的上级角色
+func (h *RoleHandler) SetParents(c *gin.Context) {
+	// 获取角色ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req struct {
+		ParentIDs []uint `json:"parent_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置上级角色
+	if err := h.roles(c).SetParents(uint(id), req.ParentIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "角色不存在"})
+			return
+		}
+		if errors.Is(err, service.ErrPrivilegeEscalation) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrSeparationViolation) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "上级角色设置成功"})
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SeparationHandler 职责分离约束处理器
+type SeparationHandler struct {
+	separationService service.SeparationService
+}
+
+// NewSeparationHandler 创建职责分离约束处理器实例
+func NewSeparationHandler(separationService service.SeparationService) *SeparationHandler {
+	return &SeparationHandler{
+		separationService: separationService,
+	}
+}
+
+// ListConstraints 获取约束列表
+func (h *SeparationHandler) ListConstraints(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取约束列表
+	constraints, total, err := h.separationService.List(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取职责分离约束列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  constraints,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// CreateConstraint 创建约束
+func (h *SeparationHandler) CreateConstraint(c *gin.Context) {
+	// 绑定请求数据
+	var req service.SeparationConstraintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建约束
+	constraint, err := h.separationService.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, constraint)
+}
+
+// GetConstraint 获取约束详情
+func (h *SeparationHandler) GetConstraint(c *gin.Context) {
+	// 获取约束ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的约束ID"})
+		return
+	}
+
+	// 获取约束信息
+	constraint, err := h.separationService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "职责分离约束不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, constraint)
+}
+
+// UpdateConstraint 更新约束
+func (h *SeparationHandler) UpdateConstraint(c *gin.Context) {
+	// 获取约束ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的约束ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.SeparationConstraintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新约束
+	constraint, err := h.separationService.Update(uint(id), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "职责分离约束不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, constraint)
+}
+
+// DeleteConstraint 删除约束
+func (h *SeparationHandler) DeleteConstraint(c *gin.Context) {
+	// 获取约束ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的约束ID"})
+		return
+	}
+
+	// 删除约束
+	if err := h.separationService.Delete(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "职责分离约束不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除职责分离约束失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "职责分离约束删除成功"})
+}
@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"authentication/internal/model"
 	"authentication/internal/service"
+	"authentication/pkg/auth"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UserHandler 用户处理器
@@ -20,6 +25,19 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 	}
 }
 
+// users 返回当前请求所在组织范围内、以当前用户权限分配角色的用户服务
+func (h *UserHandler) users(c *gin.Context) service.UserService {
+	return h.userService.ForOrganization(c.GetUint("orgID")).ForOperator(operatorPermissions(c))
+}
+
+// operatorPermissions 返回当前用户令牌携带的有效权限
+func operatorPermissions(c *gin.Context) auth.PermissionSet {
+	return auth.PermissionSet{
+		Allowed: c.GetStringSlice("permissions"),
+		Denied:  c.GetStringSlice("deniedPermissions"),
+	}
+}
+
 // ListUsers 获取用户列表
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	// 获取分页参数
@@ -27,7 +45,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
 	// 获取用户列表
-	users, total, err := h.userService.List(page, pageSize)
+	users, total, err := h.users(c).List(page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
 		return
@@ -41,6 +59,33 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	})
 }
 
+// CreateUser 管理员创建用户
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	// 绑定请求数据
+	var req service.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建用户
+	user, err := h.users(c).Create(req)
+	if err != nil {
+		if errors.Is(err, service.ErrSeparationViolation) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrPrivilegeEscalation) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
 // GetUser 获取用户详情
 func (h *UserHandler) GetUser(c *gin.Context) {
 	// 获取用户ID
@@ -51,7 +96,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	}
 
 	// 获取用户信息
-	user, err := h.userService.GetByID(uint(id))
+	user, err := h.users(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
@@ -70,7 +115,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// 获取用户信息
-	user, err := h.userService.GetByID(uint(id))
+	user, err := h.users(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
@@ -78,9 +123,12 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	// 绑定请求数据
 	var updateData struct {
-		Email    string `json:"email" binding:"omitempty,email"`
-		FullName string `json:"full_name" binding:"omitempty"`
-		Active   *bool  `json:"active" binding:"omitempty"`
+		Email      string           `json:"email" binding:"omitempty,email"`
+		FullName   string           `json:"full_name" binding:"omitempty"`
+		Department *string          `json:"department" binding:"omitempty,max=100"`
+		Attributes model.Attributes `json:"attributes"` // 提供时整体替换自定义属性
+		Active     *bool            `json:"active" binding:"omitempty"`
+		Reason     string           `json:"reason" binding:"omitempty,max=255"` // 停用原因
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -88,6 +136,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// 只能修改自己时不允许修改由管理员维护的字段，修改邮箱需通过个人资料接口重新验证
+	if c.GetBool("ownerScope") &&
+		(updateData.Email != "" || updateData.Active != nil || updateData.Department != nil || updateData.Attributes != nil) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能修改自己的姓名，修改邮箱请使用个人资料接口"})
+		return
+	}
+
 	// 更新用户信息
 	if updateData.Email != "" {
 		user.Email = updateData.Email
@@ -95,12 +150,31 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if updateData.FullName != "" {
 		user.FullName = updateData.FullName
 	}
-	if updateData.Active != nil {
-		user.Active = *updateData.Active
+	if updateData.Department != nil {
+		user.Department = *updateData.Department
+	}
+	if updateData.Attributes != nil {
+		user.Attributes = updateData.Attributes
+	}
+	if updateData.Active != nil && *updateData.Active != user.Active {
+		if *updateData.Active {
+			user.Reactivate()
+		} else {
+			operatorID, _ := c.Get("userID")
+			user.Deactivate(operatorID.(uint), updateData.Reason)
+		}
 	}
 
 	// 保存更新
-	if err := h.userService.Update(user); err != nil {
+	if err := h.users(c).Update(user); err != nil {
+		if errors.Is(err, service.ErrLastAdmin) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrSharedAccount) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户失败"})
 		return
 	}
@@ -117,11 +191,252 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// 绑定请求数据，删除原因可选
+	var req struct {
+		Reason string `json:"reason" binding:"omitempty,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 删除用户
-	if err := h.userService.Delete(uint(id)); err != nil {
+	operatorID, _ := c.Get("userID")
+	if err := h.users(c).Delete(uint(id), operatorID.(uint), req.Reason); err != nil {
+		if errors.Is(err, service.ErrLastAdmin) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, service.ErrSharedAccount) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除用户失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "用户已删除"})
 }
+
+// ListDeletedUsers 获取已删除的用户列表
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	// 获取用户列表
+	users, total, err := h.users(c).ListDeleted(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  users,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// RestoreUser 恢复已删除的用户
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 恢复用户
+	if err := h.users(c).Restore(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		case errors.Is(err, service.ErrUserNotDeleted), errors.Is(err, service.ErrUserConflict):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, service.ErrSharedAccount):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复用户失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户已恢复"})
+}
+
+// PurgeUser 彻底删除已软删除的用户，操作不可恢复
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 彻底删除用户
+	if err := h.users(c).Purge(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		case errors.Is(err, service.ErrUserNotDeleted):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, service.ErrSharedAccount):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "彻底删除用户失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户已彻底删除"})
+}
+
+// ResetPassword 管理员重置用户密码
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 重置密码
+	if err := h.users(c).ResetPassword(uint(id), req.Password); err != nil {
+		if errors.Is(err, service.ErrSharedAccount) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功"})
+}
+
+// ImportUsers 批量导入旧系统用户
+func (h *UserHandler) ImportUsers(c *gin.Context) {
+	// 绑定请求数据
+	var req service.ImportUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 导入用户
+	result, err := h.users(c).Import(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入用户失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLockout 获取用户的登录锁定状态
+func (h *UserHandler) GetLockout(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 获取锁定状态
+	status, err := h.users(c).GetLockoutStatus(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// UnlockUser 解除用户的登录锁定
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 解除锁定
+	if err := h.users(c).Unlock(uint(id)); err != nil {
+		if errors.Is(err, service.ErrSharedAccount) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户已解锁"})
+}
+
+// SetRoles 替换用户的角色
+func (h *UserHandler) SetRoles(c *gin.Context) {
+	h.updateRoles(c, h.users(c).SetRoles)
+}
+
+// AddRoles 为用户追加角色
+func (h *UserHandler) AddRoles(c *gin.Context) {
+	h.updateRoles(c, h.users(c).AddRoles)
+}
+
+// RemoveRoles 移除用户的指定角色
+func (h *UserHandler) RemoveRoles(c *gin.Context) {
+	h.updateRoles(c, h.users(c).RemoveRoles)
+}
+
+// updateRoles 解析请求并执行角色修改，成功时返回修改后的用户
+func (h *UserHandler) updateRoles(c *gin.Context, update func(id uint, roleIDs []uint) (*model.User, error)) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var req service.AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改角色
+	user, err := update(uint(id), req.RoleIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		case errors.Is(err, service.ErrRoleNotFound):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, service.ErrPrivilegeEscalation):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, service.ErrLastAdmin), errors.Is(err, service.ErrSeparationViolation):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "分配角色失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
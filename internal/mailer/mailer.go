@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"authentication/internal/config"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer 根据配置创建邮件发送实例
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &logMailer{from: cfg.From}, nil
+	case "smtp":
+		return &smtpMailer{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("不支持的邮件驱动: %s", cfg.Driver)
+	}
+}
+
+// logMailer 仅将邮件内容写入日志，用于开发环境
+type logMailer struct {
+	from string
+}
+
+// Send 将邮件写入日志
+func (m *logMailer) Send(to, subject, body string) error {
+	log.Printf("发送邮件 from=%s to=%s subject=%s\n%s", m.from, to, subject, body)
+	return nil
+}
+
+// smtpMailer 通过SMTP发送邮件
+type smtpMailer struct {
+	cfg config.MailConfig
+}
+
+// Send 通过SMTP发送纯文本邮件
+func (m *smtpMailer) Send(to, subject, body string) error {
+	addr := fmt.Sprintf("%s:%d", m.cfg.SMTP.Host, m.cfg.SMTP.Port)
+
+	var auth smtp.Auth
+	if m.cfg.SMTP.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTP.Username, m.cfg.SMTP.Password, m.cfg.SMTP.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	if err := smtp.SendMail(addr, auth, m.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package middleware
 import (
 	"authentication/internal/config"
 	"authentication/internal/service"
+	"authentication/pkg/auth"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,18 +14,33 @@ import (
 
 // AuthMiddleware 认证中间件
 type AuthMiddleware struct {
-	jwtConfig config.JWTConfig
+	jwtConfig       config.JWTConfig
+	registry        *auth.PermissionRegistry
+	relationService service.RelationService
 }
 
-// NewAuthMiddleware 创建认证中间件实例
-func NewAuthMiddleware(jwtConfig config.JWTConfig) *AuthMiddleware {
+// NewAuthMiddleware 创建认证中间件实例，路由使用的权限代码会登记到 registry，
+// relationService 用于基于关系的路由检查
+func NewAuthMiddleware(jwtConfig config.JWTConfig, registry *auth.PermissionRegistry, relationService service.RelationService) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtConfig: jwtConfig,
+		jwtConfig:       jwtConfig,
+		registry:        registry,
+		relationService: relationService,
 	}
 }
 
 // AuthRequired 需要认证的中间件
 func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
+	return m.authenticate(false)
+}
+
+// AuthRequiredAllowExpired 需要认证但允许密码已过期的中间件，仅用于修改密码接口
+func (m *AuthMiddleware) AuthRequiredAllowExpired() gin.HandlerFunc {
+	return m.authenticate(true)
+}
+
+// authenticate 校验访问令牌并将用户信息存入上下文
+func (m *AuthMiddleware) authenticate(allowExpiredPassword bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取令牌
 		tokenString, err := extractTokenFromHeader(c)
@@ -42,36 +59,177 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// 密码已过期时只允许访问修改密码接口
+		if claims.PasswordExpired && !allowExpiredPassword {
+			c.JSON(http.StatusForbidden, gin.H{"error": "密码已过期，请先修改密码", "code": "password_expired"})
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储在上下文中
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("permissions", claims.Permissions)
+		c.Set("deniedPermissions", claims.DeniedPermissions)
+		c.Set("orgID", claims.OrganizationID)
 
 		c.Next()
 	}
 }
 
-// HasPermission 检查是否有指定权限的中间件
+// HasPermission 检查是否有指定权限的中间件，声明路由时登记权限代码以便启动时同步到数据库
 func (m *AuthMiddleware) HasPermission(permissionCode string) gin.HandlerFunc {
+	if m.registry != nil {
+		m.registry.Register(permissionCode)
+	}
+
+	return func(c *gin.Context) {
+		// 获取用户权限
+		permissions, exists := permissionSet(c)
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "未找到权限信息"})
+			c.Abort()
+			return
+		}
+
+		// 检查是否有指定权限，支持通配符权限，拒绝的权限优先
+		if !permissions.Has(permissionCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasAnyResourcePermission 检查是否可以访问任意资源的中间件，拥有 permissionCode 或 <permissionCode>:any 时通过，
+// 用于只允许管理他人、不开放给 <permissionCode>:self 的路由，如管理员重置密码。
+func (m *AuthMiddleware) HasAnyResourcePermission(permissionCode string) gin.HandlerFunc {
+	anyCode := permissionCode + ":" + auth.ScopeAny
+	if m.registry != nil {
+		m.registry.Register(permissionCode, anyCode)
+	}
+
 	return func(c *gin.Context) {
 		// 获取用户权限
-		permissions, exists := c.Get("permissions")
+		permissions, exists := permissionSet(c)
 		if !exists {
 			c.JSON(http.StatusForbidden, gin.H{"error": "未找到权限信息"})
 			c.Abort()
 			return
 		}
 
-		// 检查是否有指定权限
-		hasPermission := false
-		for _, p := range permissions.([]string) {
-			if p == permissionCode {
-				hasPermission = true
-				break
+		// 拒绝 permissionCode 时 :any 同样被拒绝
+		if permissions.Denies(permissionCode) || !permissions.Has(permissionCode) && !permissions.Has(anyCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasOwnPermission 检查所有权相关权限的中间件，owner 解析路由访问的资源所属的用户ID。
+// 拥有 <permissionCode>:any 或 permissionCode 本身时可访问任意资源，
+// 仅拥有 <permissionCode>:self 时只能访问自己的资源，此时上下文中的 ownerScope 为 true；
+// permissionCode 被拒绝时两种所有权范围都被拒绝。
+func (m *AuthMiddleware) HasOwnPermission(permissionCode string, owner OwnerResolver) gin.HandlerFunc {
+	anyCode := permissionCode + ":" + auth.ScopeAny
+	selfCode := permissionCode + ":" + auth.ScopeSelf
+	if m.registry != nil {
+		m.registry.Register(permissionCode, anyCode, selfCode)
+	}
+
+	return func(c *gin.Context) {
+		// 获取用户权限
+		permissions, exists := permissionSet(c)
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "未找到权限信息"})
+			c.Abort()
+			return
+		}
+
+		// 拒绝的权限优先于 :any 和 :self
+		if permissions.Denies(permissionCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
+			c.Abort()
+			return
+		}
+
+		// 可访问任意资源
+		if permissions.Has(permissionCode) || permissions.Has(anyCode) {
+			c.Set("ownerScope", false)
+			c.Next()
+			return
+		}
+
+		if !permissions.Has(selfCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
+			c.Abort()
+			return
+		}
+
+		// 仅能访问自己的资源
+		ownerID, err := owner(c)
+		if err != nil {
+			if errors.Is(err, errInvalidResourceID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
 			}
+			c.Abort()
+			return
+		}
+		userID, _ := c.Get("userID")
+		if id, ok := userID.(uint); !ok || id != ownerID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能访问自己的资源"})
+			c.Abort()
+			return
+		}
+
+		c.Set("ownerScope", true)
+		c.Next()
+	}
+}
+
+// consistencyTokenHeader 携带一致性令牌的请求头
+const consistencyTokenHeader = "X-Consistency-Token"
+
+// HasRelation 检查当前用户是否拥有路由访问对象的关系的中间件，对象为 <namespace>:<路由参数 param>，
+// 如 HasRelation("doc", "editor", "id")。请求头 X-Consistency-Token 携带写入元组时返回的令牌时，
+// 检查至少基于该次写入之后的快照。
+func (m *AuthMiddleware) HasRelation(namespace, relation, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 获取用户ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "未找到用户信息"})
+			c.Abort()
+			return
 		}
 
-		if !hasPermission {
+		// 检查关系
+		result, err := m.relationService.Check(service.RelationCheckRequest{
+			Object:      namespace + ":" + c.Param(param),
+			Relation:    relation,
+			Subject:     service.UserSubject(userID.(uint)),
+			Consistency: service.Consistency{Token: c.GetHeader(consistencyTokenHeader)},
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidTuple):
+				c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidResourceID.Error()})
+			case errors.Is(err, service.ErrInvalidConsistencyToken):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_consistency_token"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "检查关系失败"})
+			}
+			c.Abort()
+			return
+		}
+		if !result.Allowed {
 			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限执行此操作"})
 			c.Abort()
 			return
@@ -81,6 +239,58 @@ func (m *AuthMiddleware) HasPermission(permissionCode string) gin.HandlerFunc {
 	}
 }
 
+// permissionSet 从上下文中获取令牌携带的允许和拒绝的权限
+func permissionSet(c *gin.Context) (auth.PermissionSet, bool) {
+	permissions, exists := c.Get("permissions")
+	if !exists {
+		return auth.PermissionSet{}, false
+	}
+	allowed, _ := permissions.([]string)
+	denied := c.GetStringSlice("deniedPermissions")
+	return auth.PermissionSet{Allowed: allowed, Denied: denied}, true
+}
+
+// OwnerResolver 解析路由访问的资源所属的用户ID
+type OwnerResolver func(c *gin.Context) (uint, error)
+
+// UserOwner 以路由参数中的用户ID作为资源所有者，用于 /users/:id 这类用户自身即资源的路由
+func UserOwner(param string) OwnerResolver {
+	return func(c *gin.Context) (uint, error) {
+		id, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			return 0, errInvalidResourceID
+		}
+		return uint(id), nil
+	}
+}
+
+// PlatformOnly 只允许在全局范围内访问的中间件，用于权限、策略、组织等跨组织的管理接口
+func (m *AuthMiddleware) PlatformOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetUint("orgID") != 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "该操作只能在全局范围内执行，请先切换到全局范围", "code": "organization_scope"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SameOrganization 处于组织中时只允许访问当前组织的中间件，param 为路由中的组织ID参数
+func (m *AuthMiddleware) SameOrganization(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.GetUint("orgID")
+		if orgID != 0 && c.Param(param) != strconv.FormatUint(uint64(orgID), 10) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能访问当前组织", "code": "organization_scope"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // HasRole 检查是否有指定角色的中间件
 func (m *AuthMiddleware) HasRole(roleName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPermissionRouter 创建以用户1身份访问、携带指定允许和拒绝权限的测试路由
+func newPermissionRouter(allowed, denied []string, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("permissions", allowed)
+		c.Set("deniedPermissions", denied)
+	})
+	handlers = append(handlers, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.DELETE("/users/:id", handlers...)
+	return router
+}
+
+func TestHasOwnPermissionDenyCoversScopes(t *testing.T) {
+	m := &AuthMiddleware{}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		path    string
+		want    int
+	}{
+		{"any scope", []string{"user:delete:any"}, nil, "/users/2", http.StatusOK},
+		{"self scope on own resource", []string{"user:delete:self"}, nil, "/users/1", http.StatusOK},
+		{"self scope on other resource", []string{"user:delete:self"}, nil, "/users/2", http.StatusForbidden},
+		{"deny overrides any scope", []string{"user:delete:any"}, []string{"user:delete"}, "/users/2", http.StatusForbidden},
+		{"deny overrides self scope", []string{"user:delete:self"}, []string{"user:delete"}, "/users/1", http.StatusForbidden},
+		{"auditor wildcard deny", []string{"*:read", "*:read:any", "user:delete:self"}, []string{"*:delete"}, "/users/1", http.StatusForbidden},
+		{"auditor wildcard deny with any scope", []string{"*"}, []string{"*:delete"}, "/users/2", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newPermissionRouter(tt.allowed, tt.denied, m.HasOwnPermission("user:delete", UserOwner("id")))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasAnyResourcePermissionDenyCoversScope(t *testing.T) {
+	m := &AuthMiddleware{}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		want    int
+	}{
+		{"any scope", []string{"user:delete:any"}, nil, http.StatusOK},
+		{"self scope only", []string{"user:delete:self"}, nil, http.StatusForbidden},
+		{"deny overrides any scope", []string{"user:delete:any"}, []string{"user:delete"}, http.StatusForbidden},
+		{"auditor wildcard deny", []string{"*"}, []string{"*:delete"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newPermissionRouter(tt.allowed, tt.denied, m.HasAnyResourcePermission("user:delete"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/2", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// fakeRelationService 记录检查请求并返回固定结果的关系服务
+type fakeRelationService struct {
+	service.RelationService
+	allowed bool
+	err     error
+	req     service.RelationCheckRequest
+}
+
+func (s *fakeRelationService) Check(req service.RelationCheckRequest) (*service.RelationCheckResult, error) {
+	s.req = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &service.RelationCheckResult{Allowed: s.allowed}, nil
+}
+
+func TestHasRelation(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed bool
+		err     error
+		want    int
+	}{
+		{"allowed", true, nil, http.StatusOK},
+		{"not related", false, nil, http.StatusForbidden},
+		{"invalid object", false, service.ErrInvalidTuple, http.StatusBadRequest},
+		{"invalid token", false, service.ErrInvalidConsistencyToken, http.StatusBadRequest},
+		{"check failed", false, errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relations := &fakeRelationService{allowed: tt.allowed, err: tt.err}
+			m := &AuthMiddleware{relationService: relations}
+			router := newPermissionRouter(nil, nil, m.HasRelation("doc", "owner", "id"))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/users/readme", nil)
+			req.Header.Set("X-Consistency-Token", "token")
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+
+			want := service.RelationCheckRequest{
+				Object:      "doc:readme",
+				Relation:    "owner",
+				Subject:     "user:1",
+				Consistency: service.Consistency{Token: "token"},
+			}
+			if relations.req != want {
+				t.Errorf("check request = %+v; want %+v", relations.req, want)
+			}
+		})
+	}
+}
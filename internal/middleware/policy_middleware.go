@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"authentication/internal/policy"
+	"authentication/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidResourceID 路由中的资源ID无效
+var errInvalidResourceID = errors.New("无效的资源ID")
+
+// ResourceLoader 加载路由访问的资源，返回策略中 resource 变量的属性
+type ResourceLoader func(c *gin.Context) (map[string]interface{}, error)
+
+// PolicyMiddleware 基于属性的访问控制中间件
+type PolicyMiddleware struct {
+	policyService service.PolicyService
+}
+
+// NewPolicyMiddleware 创建访问控制中间件实例
+func NewPolicyMiddleware(policyService service.PolicyService) *PolicyMiddleware {
+	return &PolicyMiddleware{policyService: policyService}
+}
+
+// Authorize 加载资源并根据访问控制策略判断是否允许执行操作，需在认证中间件之后使用。
+// 策略只能进一步收紧权限检查，没有适用策略时直接放行。
+func (m *PolicyMiddleware) Authorize(action string, loader ResourceLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 获取用户ID
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "未找到用户信息"})
+			c.Abort()
+			return
+		}
+
+		// 获取用户
+		authService := c.MustGet("authService").(service.AuthService)
+		user, err := authService.GetUserByID(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+			c.Abort()
+			return
+		}
+
+		// 加载资源
+		var resource map[string]interface{}
+		if loader != nil {
+			resource, err = loader(c)
+			if err != nil {
+				switch {
+				case errors.Is(err, errInvalidResourceID):
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				case errors.Is(err, gorm.ErrRecordNotFound):
+					c.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+				default:
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "加载资源失败"})
+				}
+				c.Abort()
+				return
+			}
+		}
+
+		// 策略求值
+		permissions, _ := c.Get("permissions")
+		codes, _ := permissions.([]string)
+		subject := policy.SubjectAttributes(user, codes)
+		subject["organization_id"] = c.GetUint("orgID")
+		decision := m.policyService.Evaluate(policy.Request{
+			Action:   action,
+			Subject:  subject,
+			Resource: resource,
+			Context:  policy.RequestContext(c.ClientIP(), c.Request.Method, c.FullPath(), time.Now()),
+		})
+		if decision.Denied() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":    "访问被策略拒绝",
+				"code":     "policy_denied",
+				"reason":   decision.Reason,
+				"policies": decision.Policies,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// UserResourceLoader 根据路由参数 id 加载用户作为被访问资源，处于组织中时只加载该组织的成员
+func UserResourceLoader(userService service.UserService) ResourceLoader {
+	return func(c *gin.Context) (map[string]interface{}, error) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return nil, errInvalidResourceID
+		}
+
+		user, err := userService.ForOrganization(c.GetUint("orgID")).GetByID(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		return policy.UserResource(user), nil
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Attributes 以JSON格式存储的自定义属性
+type Attributes map[string]interface{}
+
+// Value 实现 driver.Valuer 接口
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (a *Attributes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = Attributes{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无效的属性数据类型")
+	}
+
+	attrs := Attributes{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &attrs); err != nil {
+			return err
+		}
+	}
+	*a = attrs
+	return nil
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLog 审计日志，记录谁在什么时间对什么对象做了什么
+type AuditLog struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	ActorID    *uint      `json:"actor_id,omitempty" gorm:"index"` // 为空时为系统操作
+	Action     string     `json:"action" gorm:"size:100;not null;index"`
+	TargetType string     `json:"target_type" gorm:"size:50;index:idx_audit_logs_target"`
+	TargetID   uint       `json:"target_id" gorm:"index:idx_audit_logs_target"`
+	Detail     Attributes `json:"detail" gorm:"type:text"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+}
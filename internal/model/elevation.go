@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// 提权申请状态
+const (
+	ElevationPending  = "pending"
+	ElevationApproved = "approved"
+	ElevationDenied   = "denied"
+)
+
+// ElevationRequest 临时提权申请
+// 用户申请在一段时间内拥有某个全局角色，审批通过后以限时授予的方式生效。
+type ElevationRequest struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	User          *User      `json:"user,omitempty"`
+	RoleID        uint       `json:"role_id" gorm:"not null"`
+	Role          *Role      `json:"role,omitempty"`
+	Duration      int        `json:"duration" gorm:"not null"` // 申请的时长（分钟）
+	Justification string     `json:"justification" gorm:"type:text;not null"`
+	Status        string     `json:"status" gorm:"size:20;not null;index"`
+	ReviewerID    *uint      `json:"reviewer_id,omitempty"`
+	ReviewComment string     `json:"review_comment,omitempty" gorm:"size:500"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // 审批通过后授予的到期时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
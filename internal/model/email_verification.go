@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailVerification 邮箱验证令牌，只保存令牌的哈希
+type EmailVerification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	Email     string     `json:"email" gorm:"size:100;not null"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
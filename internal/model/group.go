@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// Group 用户分组模型
+// 分组可以嵌套，成员获得所在分组及其全部上级分组的角色。分组角色只能是全局角色。
+type Group struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"size:200"`
+	ParentID    *uint     `json:"parent_id" gorm:"index"`
+	Parent      *Group    `json:"parent,omitempty"`
+	Roles       []Role    `json:"roles" gorm:"many2many:group_roles;"`
+	Members     []User    `json:"members,omitempty" gorm:"many2many:group_members;"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
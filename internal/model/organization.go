@@ -0,0 +1,41 @@
+package model
+
+import (
+	"authentication/pkg/auth"
+	"time"
+)
+
+// OrgAdminRoleName 创建组织时自动创建的组织管理员角色名称
+const OrgAdminRoleName = "org-admin"
+
+// OrgAdminPermissions 组织管理员角色默认拥有的权限，只在所属组织内生效；
+// 也是组织角色能够拥有的权限范围，平台级权限（如 *、authz:check）不能分配给组织角色
+var OrgAdminPermissions = []string{"user:*", "role:*", "organization:read", "organization:member"}
+
+// Organization 组织（租户）模型
+// 用户通过成员关系加入组织，并在每个组织中拥有独立的角色。
+type Organization struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"size:200"`
+	Roles       []Role    `json:"-" gorm:"foreignKey:OrganizationID"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Membership 用户在组织中的成员关系及其在该组织中的角色
+type Membership struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	UserID         uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_memberships_user_org"`
+	OrganizationID uint          `json:"organization_id" gorm:"not null;uniqueIndex:idx_memberships_user_org;index"`
+	User           *User         `json:"user,omitempty"`
+	Organization   *Organization `json:"organization,omitempty"`
+	Roles          []Role        `json:"roles" gorm:"many2many:membership_roles;"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// PermissionSet 返回成员在组织中的有效权限，包括继承的权限
+func (m *Membership) PermissionSet() auth.PermissionSet {
+	return RolePermissionSet(m.Roles)
+}
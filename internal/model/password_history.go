@@ -0,0 +1,20 @@
+package model
+
+import (
+	"authentication/pkg/auth"
+	"time"
+)
+
+// PasswordHistory 用户历史密码，用于防止重复使用近期密码
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Password  string    `json:"-" gorm:"size:255;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches 检查密码是否与该历史密码相同
+func (h *PasswordHistory) Matches(password string) bool {
+	ok, _ := auth.DefaultHasherRegistry().Verify(password, h.Password)
+	return ok
+}
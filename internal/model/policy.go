@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// 策略效果
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// Policy 基于属性的访问控制策略
+// Action 为适用的操作（支持通配符，如 user:*），Condition 为CEL表达式，
+// 可使用 subject、resource、request 三个变量以及 action 字符串。
+type Policy struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"size:255"`
+	Effect      string    `json:"effect" gorm:"size:10;not null"`
+	Action      string    `json:"action" gorm:"size:100;not null"`
+	Condition   string    `json:"condition" gorm:"type:text;not null"`
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// RelationTuple 关系元组 <命名空间>:<对象ID>#<关系>@<主体>，
+// 主体为用户（user:42）或另一对象的关系（group:eng#member，即该组的全部成员）。
+// 元组按版本号保存：CreatedRevision 写入时的版本，DeletedRevision 删除时的版本（0表示未删除），
+// 因此可以在任一历史版本的快照上求值。
+type RelationTuple struct {
+	ID               uint      `json:"-" gorm:"primaryKey"`
+	Namespace        string    `json:"namespace" gorm:"size:64;not null;uniqueIndex:idx_relation_tuples_live,priority:1,where:deleted_revision = 0;index:idx_relation_tuples_object,priority:1"`
+	ObjectID         string    `json:"object_id" gorm:"size:128;not null;uniqueIndex:idx_relation_tuples_live,priority:2;index:idx_relation_tuples_object,priority:2"`
+	Relation         string    `json:"relation" gorm:"size:64;not null;uniqueIndex:idx_relation_tuples_live,priority:3;index:idx_relation_tuples_object,priority:3"`
+	SubjectNamespace string    `json:"subject_namespace" gorm:"size:64;not null;uniqueIndex:idx_relation_tuples_live,priority:4"`
+	SubjectID        string    `json:"subject_id" gorm:"size:128;not null;uniqueIndex:idx_relation_tuples_live,priority:5"`
+	SubjectRelation  string    `json:"subject_relation,omitempty" gorm:"size:64;not null;default:'';uniqueIndex:idx_relation_tuples_live,priority:6"`
+	CreatedRevision  uint64    `json:"created_revision" gorm:"not null"`
+	DeletedRevision  uint64    `json:"-" gorm:"not null;default:0"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Object 返回元组的对象，如 doc:readme
+func (t *RelationTuple) Object() string {
+	return t.Namespace + ":" + t.ObjectID
+}
+
+// Subject 返回元组的主体，如 user:42 或 group:eng#member
+func (t *RelationTuple) Subject() string {
+	if t.SubjectRelation == "" {
+		return t.SubjectNamespace + ":" + t.SubjectID
+	}
+	return t.SubjectNamespace + ":" + t.SubjectID + "#" + t.SubjectRelation
+}
+
+// String 返回元组的文本形式，如 doc:readme#viewer@user:42
+func (t *RelationTuple) String() string {
+	return t.Object() + "#" + t.Relation + "@" + t.Subject()
+}
+
+// RelationRevision 关系元组的全局版本号，每次写入递增，只有一行
+type RelationRevision struct {
+	ID       uint   `gorm:"primaryKey"`
+	Revision uint64 `gorm:"not null"`
+}
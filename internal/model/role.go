@@ -1,26 +1,89 @@
 package model
 
 import (
+	"authentication/pkg/auth"
 	"time"
 )
 
+// AdminRoleName 系统管理员角色名称，系统中至少需要保留一个拥有该角色的用户
+const AdminRoleName = "admin"
+
 // Role 角色模型
+// 角色可以继承多个上级角色，拥有上级角色的全部权限。
+// OrganizationID 为空的是全局角色，直接分配给用户；否则为组织角色，通过成员关系分配，名称在组织内唯一。
+// DeniedPermissions 为显式拒绝的权限，同样被下级角色继承，优先于任何角色授予的权限。
 type Role struct {
-	ID          uint         `json:"id" gorm:"primaryKey"`
-	Name        string       `json:"name" gorm:"size:50;uniqueIndex;not null"`
-	Description string       `json:"description" gorm:"size:200"`
-	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
-	Users       []User       `json:"users,omitempty" gorm:"many2many:user_roles;"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID                uint         `json:"id" gorm:"primaryKey"`
+	Name              string       `json:"name" gorm:"size:50;not null;uniqueIndex:idx_roles_name_global,where:organization_id IS NULL;uniqueIndex:idx_roles_name_org,priority:2"`
+	OrganizationID    *uint        `json:"organization_id,omitempty" gorm:"index;uniqueIndex:idx_roles_name_org,priority:1"`
+	Description       string       `json:"description" gorm:"size:200"`
+	Permissions       []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+	DeniedPermissions []Permission `json:"denied_permissions,omitempty" gorm:"many2many:role_denied_permissions;"`
+	Parents           []Role       `json:"parents,omitempty" gorm:"many2many:role_parents;joinForeignKey:RoleID;joinReferences:ParentID"`
+	Users             []User       `json:"users,omitempty" gorm:"many2many:user_roles;"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
 }
 
-// HasPermission 检查角色是否拥有指定权限
+// HasPermission 检查角色是否拥有指定权限，包括从上级角色继承的权限，支持通配符权限，拒绝的权限优先
 func (r *Role) HasPermission(permissionCode string) bool {
-	for _, permission := range r.Permissions {
-		if permission.Code == permissionCode {
-			return true
+	return RolePermissionSet([]Role{*r}).Has(permissionCode)
+}
+
+// Ancestors 返回所有上级角色（按广度优先顺序去重），需要预先加载 Parents
+func (r *Role) Ancestors() []Role {
+	var ancestors []Role
+	visited := map[uint]bool{r.ID: true}
+	queue := r.Parents
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if visited[role.ID] {
+			continue
+		}
+		visited[role.ID] = true
+		ancestors = append(ancestors, role)
+		queue = append(queue, role.Parents...)
+	}
+	return ancestors
+}
+
+// EffectivePermissions 返回角色自身及继承的全部允许权限，按权限代码去重
+func (r *Role) EffectivePermissions() []Permission {
+	return r.collect(func(role Role) []Permission { return role.Permissions })
+}
+
+// EffectiveDeniedPermissions 返回角色自身及继承的全部拒绝权限，按权限代码去重
+func (r *Role) EffectiveDeniedPermissions() []Permission {
+	return r.collect(func(role Role) []Permission { return role.DeniedPermissions })
+}
+
+// collect 汇总角色自身及上级角色的权限，按权限代码去重
+func (r *Role) collect(permissionsOf func(role Role) []Permission) []Permission {
+	seen := make(map[string]bool)
+	var permissions []Permission
+	for _, role := range append([]Role{*r}, r.Ancestors()...) {
+		for _, permission := range permissionsOf(role) {
+			if !seen[permission.Code] {
+				seen[permission.Code] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// RolePermissionSet 计算一组角色的有效权限，令牌签发和 User.HasPermission 共用：
+// 汇总全部角色（包括继承）允许和拒绝的权限，任一角色拒绝的权限即使被其他角色允许也没有。
+func RolePermissionSet(roles []Role) auth.PermissionSet {
+	var allowed, denied []string
+	for _, role := range roles {
+		for _, permission := range role.EffectivePermissions() {
+			allowed = append(allowed, permission.Code)
+		}
+		for _, permission := range role.EffectiveDeniedPermissions() {
+			denied = append(denied, permission.Code)
 		}
 	}
-	return false
+	return auth.NewPermissionSet(allowed, denied)
 }
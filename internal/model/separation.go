@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// 职责分离约束类型
+const (
+	SeparationStatic  = "static"  // 静态约束：用户不能同时被分配
+	SeparationDynamic = "dynamic" // 动态约束：不能在同一会话（令牌）中同时生效
+)
+
+// SeparationConstraint 职责分离约束
+// 用户拥有（静态）或在会话中激活（动态）约束中超过 MaxRoles 个角色时违反约束，
+// 拥有某个角色时视为同时拥有其全部上级角色。
+type SeparationConstraint struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"size:200"`
+	Type        string    `json:"type" gorm:"size:10;not null"`
+	MaxRoles    int       `json:"max_roles" gorm:"not null;default:1"` // 最多可同时拥有的角色数
+	Roles       []Role    `json:"roles" gorm:"many2many:separation_constraint_roles;"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Conflicts 返回 held 中属于约束的角色，未超过 MaxRoles 时返回 nil
+func (c *SeparationConstraint) Conflicts(held map[uint]bool) []Role {
+	var matched []Role
+	for _, role := range c.Roles {
+		if held[role.ID] {
+			matched = append(matched, role)
+		}
+	}
+	if len(matched) <= c.MaxRoles {
+		return nil
+	}
+	return matched
+}
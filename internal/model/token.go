@@ -1,6 +1,8 @@
 package model
 
 import (
+	"authentication/pkg/auth"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -9,13 +11,30 @@ type TokenClaims struct {
 	UserID      uint     `json:"user_id"`
 	Username    string   `json:"username"`
 	Permissions []string `json:"permissions"`
-	TokenType   string   `json:"token_type"` // "access" 或 "refresh"
+	// DeniedPermissions 显式拒绝的权限，优先于 Permissions
+	DeniedPermissions []string `json:"denied_permissions,omitempty"`
+	// OrganizationID 当前所在的组织，为0时处于全局范围
+	OrganizationID uint   `json:"org_id,omitempty"`
+	TokenType      string `json:"token_type"` // "access" 或 "refresh"
+	// TokenVersion 签发时用户的令牌版本，低于当前版本的令牌已被撤销
+	TokenVersion uint `json:"ver,omitempty"`
+	// ActiveRoleIDs 会话中激活的角色，为空时激活全部角色
+	ActiveRoleIDs []uint `json:"active_roles,omitempty"`
+	// PasswordExpired 密码已过期，令牌仅可用于修改密码
+	PasswordExpired bool `json:"pwd_expired,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// PermissionSet 返回令牌携带的有效权限
+func (c *TokenClaims) PermissionSet() auth.PermissionSet {
+	return auth.PermissionSet{Allowed: c.Permissions, Denied: c.DeniedPermissions}
+}
+
 // TokenPair 包含访问令牌和刷新令牌
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int    `json:"expires_in"` // 访问令牌过期时间（秒）
+	// PasswordExpired 密码已过期，需先调用修改密码接口
+	PasswordExpired bool `json:"password_expired,omitempty"`
 }
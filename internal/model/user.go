@@ -1,49 +1,112 @@
 package model
 
 import (
+	"authentication/pkg/auth"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // User 用户模型
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Username  string    `json:"username" gorm:"size:50;uniqueIndex;not null"`
-	Email     string    `json:"email" gorm:"size:100;uniqueIndex;not null"`
-	Password  string    `json:"-" gorm:"size:100;not null"`
-	FullName  string    `json:"full_name" gorm:"size:100"`
-	Active    bool      `json:"active" gorm:"default:true"`
-	Roles     []Role    `json:"roles" gorm:"many2many:user_roles;"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
-// BeforeSave 保存前的钩子函数，用于加密密码
-func (u *User) BeforeSave(tx *gorm.DB) error {
-	// 如果密码已经被修改，则重新加密
-	if tx.Statement.Changed("Password") {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		u.Password = string(hashedPassword)
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Username string `json:"username" gorm:"size:50;uniqueIndex:idx_users_username_active,where:deleted_at IS NULL;not null"`
+	Email    string `json:"email" gorm:"size:100;uniqueIndex:idx_users_email_active,where:deleted_at IS NULL;not null"`
+	Password string `json:"-" gorm:"size:255;not null"`
+	FullName string `json:"full_name" gorm:"size:100"`
+	Active   bool   `json:"active" gorm:"default:true"`
+	Roles    []Role `json:"roles" gorm:"many2many:user_roles;"`
+	// RoleGrants 限定了有效期的角色授予（包括尚未生效的），Roles 中只包含当前有效的角色
+	RoleGrants []UserRole `json:"role_grants,omitempty" gorm:"-"`
+	// OrganizationRoles 在当前组织中的角色，仅按组织查询时填充
+	OrganizationRoles []Role `json:"organization_roles,omitempty" gorm:"-"`
+	// GroupRoles 通过所在分组（包括上级分组）获得的角色
+	GroupRoles []Role    `json:"group_roles,omitempty" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	PasswordChangedAt *time.Time `json:"password_changed_at"` // 最近一次修改密码的时间
+
+	// TokenVersion 令牌版本，递增后此前签发的令牌全部失效
+	TokenVersion uint `json:"-" gorm:"not null;default:0"`
+
+	EmailVerified bool       `json:"email_verified" gorm:"default:false"`
+	VerifiedAt    *time.Time `json:"verified_at"`
+
+	// 用于访问控制策略的属性
+	Department string     `json:"department" gorm:"size:100"`
+	Attributes Attributes `json:"attributes" gorm:"type:text"`
+
+	// 停用与删除信息，用户名和邮箱仅在未删除的用户中唯一
+	DeactivatedAt      *time.Time     `json:"deactivated_at,omitempty"`
+	DeactivatedBy      *uint          `json:"deactivated_by,omitempty"`
+	DeactivationReason string         `json:"deactivation_reason,omitempty" gorm:"size:255"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Deactivate 记录停用操作人及原因
+func (u *User) Deactivate(by uint, reason string) {
+	now := time.Now()
+	u.Active = false
+	u.DeactivatedAt = &now
+	u.DeactivatedBy = &by
+	u.DeactivationReason = reason
+}
+
+// Reactivate 重新启用用户并清除停用信息
+func (u *User) Reactivate() {
+	u.Active = true
+	u.DeactivatedAt = nil
+	u.DeactivatedBy = nil
+	u.DeactivationReason = ""
+}
+
+// SetPassword 使用当前哈希算法加密并设置密码
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := auth.DefaultHasherRegistry().Hash(password)
+	if err != nil {
+		return err
 	}
+	u.Password = hashedPassword
 	return nil
 }
 
 // CheckPassword 检查密码是否正确
 func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+	ok, _ := u.VerifyPassword(password)
+	return ok
 }
 
-// HasPermission 检查用户是否拥有指定权限
+// VerifyPassword 检查密码是否正确，并返回密码哈希是否需要用当前算法重新计算
+func (u *User) VerifyPassword(password string) (bool, bool) {
+	return auth.DefaultHasherRegistry().Verify(password, u.Password)
+}
+
+// HasPermission 检查用户是否拥有指定权限，包括角色从上级角色继承的权限，支持通配符权限，拒绝的权限优先
 func (u *User) HasPermission(permissionCode string) bool {
-	for _, role := range u.Roles {
-		for _, permission := range role.Permissions {
-			if permission.Code == permissionCode {
+	return u.PermissionSet().Has(permissionCode)
+}
+
+// PermissionSet 返回用户所有角色（包括分组角色）的有效权限
+func (u *User) PermissionSet() auth.PermissionSet {
+	return RolePermissionSet(u.effectiveRoles())
+}
+
+// effectiveRoles 返回直接分配的角色和通过分组获得的角色
+func (u *User) effectiveRoles() []Role {
+	roles := make([]Role, 0, len(u.Roles)+len(u.GroupRoles))
+	roles = append(roles, u.Roles...)
+	return append(roles, u.GroupRoles...)
+}
+
+// HasRole 检查用户是否拥有指定角色（包括分组角色），拥有其下级角色时同样视为拥有
+func (u *User) HasRole(roleName string) bool {
+	for _, role := range u.effectiveRoles() {
+		if role.Name == roleName {
+			return true
+		}
+		for _, ancestor := range role.Ancestors() {
+			if ancestor.Name == roleName {
 				return true
 			}
 		}
@@ -51,12 +114,21 @@ func (u *User) HasPermission(permissionCode string) bool {
 	return false
 }
 
-// HasRole 检查用户是否拥有指定角色
-func (u *User) HasRole(roleName string) bool {
-	for _, role := range u.Roles {
-		if role.Name == roleName {
-			return true
+// RoleNames 返回用户的角色名称，包括分组角色及角色的上级角色，按名称去重
+func (u *User) RoleNames() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(u.Roles))
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
 		}
 	}
-	return false
+	for _, role := range u.effectiveRoles() {
+		add(role.Name)
+		for _, ancestor := range role.Ancestors() {
+			add(ancestor.Name)
+		}
+	}
+	return names
 }
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// UserRole 用户与全局角色的关联（user_roles 表）
+// ValidFrom、ValidUntil 为空时不限制开始或结束时间，只在有效期内计入用户的权限。
+type UserRole struct {
+	UserID     uint       `json:"user_id" gorm:"primaryKey"`
+	RoleID     uint       `json:"role_id" gorm:"primaryKey"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorm:"index"`
+	GrantedBy  *uint      `json:"granted_by,omitempty"`
+	// ExpiryNotifiedAt 已发送即将到期通知的时间，重新授予时清空
+	ExpiryNotifiedAt *time.Time `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ActiveAt 判断授予在指定时间是否有效
+func (g *UserRole) ActiveAt(t time.Time) bool {
+	if g.ValidFrom != nil && t.Before(*g.ValidFrom) {
+		return false
+	}
+	return g.ValidUntil == nil || t.Before(*g.ValidUntil)
+}
+
+// TimeBound 是否为限定了有效期的授予
+func (g *UserRole) TimeBound() bool {
+	return g.ValidFrom != nil || g.ValidUntil != nil
+}
@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"authentication/internal/model"
+	"time"
+)
+
+// SubjectAttributes 构造策略中 subject 变量的属性，permissions 为令牌中的有效权限
+func SubjectAttributes(user *model.User, permissions []string) map[string]interface{} {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return map[string]interface{}{
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"department":  user.Department,
+		"attributes":  attributeMap(user.Attributes),
+		"roles":       user.RoleNames(),
+		"permissions": permissions,
+	}
+}
+
+// UserResource 构造以用户作为被访问资源时 resource 变量的属性
+func UserResource(user *model.User) map[string]interface{} {
+	return map[string]interface{}{
+		"type":           "user",
+		"id":             user.ID,
+		"username":       user.Username,
+		"department":     user.Department,
+		"attributes":     attributeMap(user.Attributes),
+		"roles":          user.RoleNames(),
+		"active":         user.Active,
+		"email_verified": user.EmailVerified,
+	}
+}
+
+// RequestContext 构造策略中 request 变量的属性
+func RequestContext(ip, method, path string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"time":   now,
+		"ip":     ip,
+		"method": method,
+		"path":   path,
+	}
+}
+
+// attributeMap 将自定义属性转换为表达式可直接访问的映射
+func attributeMap(attrs model.Attributes) map[string]interface{} {
+	if attrs == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}(attrs)
+}
@@ -0,0 +1,206 @@
+package policy
+
+import (
+	"authentication/internal/model"
+	"authentication/pkg/auth"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// 决策结果
+const (
+	DecisionAllow         = "allow"
+	DecisionDeny          = "deny"
+	DecisionNotApplicable = "not_applicable" // 没有适用的策略，由RBAC单独决定
+)
+
+// costLimit 单条策略求值的最大开销，防止复杂表达式拖慢请求
+const costLimit = 100000
+
+// ErrInvalidPolicy 策略定义无效
+var ErrInvalidPolicy = errors.New("无效的访问控制策略")
+
+// Request 一次授权请求
+type Request struct {
+	Action   string                 // 操作代码，如 user:update
+	Subject  map[string]interface{} // 主体属性
+	Resource map[string]interface{} // 资源属性
+	Context  map[string]interface{} // 请求上下文，如时间、IP
+}
+
+// Decision 授权决策
+type Decision struct {
+	Effect   string   `json:"effect"`
+	Policies []string `json:"policies,omitempty"` // 决定结果的策略名称
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// Denied 是否被策略拒绝
+func (d Decision) Denied() bool {
+	return d.Effect == DecisionDeny
+}
+
+// Engine 策略引擎接口
+type Engine interface {
+	Load(policies []model.Policy) error
+	Validate(policy model.Policy) error
+	Evaluate(req Request) Decision
+}
+
+// compiledPolicy 编译后的策略
+type compiledPolicy struct {
+	policy  model.Policy
+	program cel.Program
+}
+
+// celEngine 基于CEL表达式的策略引擎
+type celEngine struct {
+	env *cel.Env
+
+	mu       sync.RWMutex
+	policies []compiledPolicy // 按名称排序，保证求值顺序确定
+}
+
+// NewEngine 创建策略引擎
+func NewEngine() (Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("action", cel.StringType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建策略表达式环境失败: %w", err)
+	}
+	return &celEngine{env: env}, nil
+}
+
+// Load 编译并替换全部策略，任一策略无效时保留原有策略
+func (e *celEngine) Load(policies []model.Policy) error {
+	compiled := make([]compiledPolicy, 0, len(policies))
+	for _, p := range policies {
+		if !p.Enabled {
+			continue
+		}
+		program, err := e.compile(p)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, compiledPolicy{policy: p, program: program})
+	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return compiled[i].policy.Name < compiled[j].policy.Name
+	})
+
+	e.mu.Lock()
+	e.policies = compiled
+	e.mu.Unlock()
+	return nil
+}
+
+// Validate 检查策略定义及条件表达式是否有效
+func (e *celEngine) Validate(policy model.Policy) error {
+	_, err := e.compile(policy)
+	return err
+}
+
+// compile 编译策略条件，表达式结果必须为布尔值
+func (e *celEngine) compile(p model.Policy) (cel.Program, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("%w: 策略名称不能为空", ErrInvalidPolicy)
+	}
+	if p.Effect != model.PolicyEffectAllow && p.Effect != model.PolicyEffectDeny {
+		return nil, fmt.Errorf("%w: 策略 %s 的效果必须为 allow 或 deny", ErrInvalidPolicy, p.Name)
+	}
+	if !auth.ValidPermissionCode(p.Action) {
+		return nil, fmt.Errorf("%w: 策略 %s 的操作代码无效", ErrInvalidPolicy, p.Name)
+	}
+
+	ast, issues := e.env.Compile(p.Condition)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%w: 策略 %s 的条件无法编译: %v", ErrInvalidPolicy, p.Name, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("%w: 策略 %s 的条件结果必须为布尔值", ErrInvalidPolicy, p.Name)
+	}
+
+	program, err := e.env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 策略 %s: %v", ErrInvalidPolicy, p.Name, err)
+	}
+	return program, nil
+}
+
+// Evaluate 按 deny 优先的规则合并适用策略的结果：
+// 任一 deny 策略命中即拒绝；否则任一 allow 策略命中即允许；
+// 存在适用的 allow 策略但都未命中时拒绝；没有适用策略时返回不适用。
+// deny 策略求值出错时视为命中，allow 策略求值出错时视为未命中。
+func (e *celEngine) Evaluate(req Request) Decision {
+	e.mu.RLock()
+	policies := e.policies
+	e.mu.RUnlock()
+
+	vars := map[string]interface{}{
+		"subject":  emptyIfNil(req.Subject),
+		"resource": emptyIfNil(req.Resource),
+		"request":  emptyIfNil(req.Context),
+		"action":   req.Action,
+	}
+
+	var denied, allowed []string
+	applicableAllow := false
+	for _, p := range policies {
+		if !auth.MatchPermission(p.policy.Action, req.Action) {
+			continue
+		}
+
+		matched, err := evaluate(p.program, vars)
+		if p.policy.Effect == model.PolicyEffectDeny {
+			if matched || err != nil {
+				denied = append(denied, p.policy.Name)
+			}
+			continue
+		}
+
+		applicableAllow = true
+		if matched && err == nil {
+			allowed = append(allowed, p.policy.Name)
+		}
+	}
+
+	switch {
+	case len(denied) > 0:
+		return Decision{Effect: DecisionDeny, Policies: denied, Reason: "命中拒绝策略"}
+	case len(allowed) > 0:
+		return Decision{Effect: DecisionAllow, Policies: allowed}
+	case applicableAllow:
+		return Decision{Effect: DecisionDeny, Reason: "没有满足条件的允许策略"}
+	default:
+		return Decision{Effect: DecisionNotApplicable}
+	}
+}
+
+// evaluate 执行策略条件，结果不是布尔值时返回错误
+func evaluate(program cel.Program, vars map[string]interface{}) (bool, error) {
+	out, _, err := program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("策略条件结果不是布尔值: %v", out.Value())
+	}
+	return matched, nil
+}
+
+// emptyIfNil 将 nil 属性替换为空映射，使表达式中的字段访问得到明确的错误而不是空指针
+func emptyIfNil(attrs map[string]interface{}) map[string]interface{} {
+	if attrs == nil {
+		return map[string]interface{}{}
+	}
+	return attrs
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"authentication/internal/model"
+	"errors"
+	"testing"
+)
+
+// 测试策略的条件
+const (
+	matches    = "resource.department == subject.department"
+	mismatches = "resource.department != subject.department"
+	fails      = "resource.missing == 1" // 访问不存在的字段，求值出错
+	// expensive 遍历1000个元素的两层循环，超过开销限制
+	expensive = "resource.items.all(x, resource.items.all(y, x + y >= 0))"
+)
+
+func allow(name, action, condition string) model.Policy {
+	return model.Policy{Name: name, Effect: model.PolicyEffectAllow, Action: action, Condition: condition, Enabled: true}
+}
+
+func deny(name, action, condition string) model.Policy {
+	return model.Policy{Name: name, Effect: model.PolicyEffectDeny, Action: action, Condition: condition, Enabled: true}
+}
+
+// newTestEngine 创建加载了指定策略的引擎
+func newTestEngine(t *testing.T, policies ...model.Policy) Engine {
+	t.Helper()
+	engine, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Load(policies); err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// testRequest 同部门用户更新资源的请求，资源带有 items 列表供开销测试使用
+func testRequest() Request {
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	return Request{
+		Action:   "user:update",
+		Subject:  map[string]interface{}{"department": "sales"},
+		Resource: map[string]interface{}{"department": "sales", "items": items},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	disabled := deny("disabled", "user:update", matches)
+	disabled.Enabled = false
+
+	tests := []struct {
+		name     string
+		policies []model.Policy
+		effect   string
+		matched  []string
+	}{
+		{"no policies", nil, DecisionNotApplicable, nil},
+		{"no applicable policy", []model.Policy{deny("other-action", "role:update", matches)}, DecisionNotApplicable, nil},
+		{"disabled policy", []model.Policy{disabled}, DecisionNotApplicable, nil},
+		{"allow matches", []model.Policy{allow("same-department", "user:update", matches)}, DecisionAllow, []string{"same-department"}},
+		{"wildcard action", []model.Policy{allow("same-department", "user:*", matches)}, DecisionAllow, []string{"same-department"}},
+		{"deny beats allow", []model.Policy{allow("same-department", "user:update", matches), deny("freeze", "user:*", matches)}, DecisionDeny, []string{"freeze"}},
+		{"deny not matched", []model.Policy{allow("same-department", "user:update", matches), deny("freeze", "user:update", mismatches)}, DecisionAllow, []string{"same-department"}},
+		{"applicable allow not matched", []model.Policy{allow("other-department", "user:update", mismatches)}, DecisionDeny, nil},
+		{"any allow matches", []model.Policy{allow("a", "user:update", mismatches), allow("b", "user:update", matches)}, DecisionAllow, []string{"b"}},
+		{"deny error counts as matched", []model.Policy{allow("same-department", "user:update", matches), deny("broken", "user:update", fails)}, DecisionDeny, []string{"broken"}},
+		{"allow error counts as not matched", []model.Policy{allow("broken", "user:update", fails)}, DecisionDeny, nil},
+		{"allow error with other allow", []model.Policy{allow("broken", "user:update", fails), allow("same-department", "user:update", matches)}, DecisionAllow, []string{"same-department"}},
+		{"deny over cost limit", []model.Policy{deny("expensive", "user:update", expensive)}, DecisionDeny, []string{"expensive"}},
+		{"allow over cost limit", []model.Policy{allow("expensive", "user:update", expensive)}, DecisionDeny, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := newTestEngine(t, tt.policies...).Evaluate(testRequest())
+			if decision.Effect != tt.effect {
+				t.Fatalf("Evaluate() = %+v; want effect %s", decision, tt.effect)
+			}
+			if len(decision.Policies) != len(tt.matched) {
+				t.Fatalf("Policies = %v; want %v", decision.Policies, tt.matched)
+			}
+			for i := range tt.matched {
+				if decision.Policies[i] != tt.matched[i] {
+					t.Fatalf("Policies = %v; want %v", decision.Policies, tt.matched)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateNilAttributes(t *testing.T) {
+	engine := newTestEngine(t, allow("same-department", "user:update", matches))
+	if decision := engine.Evaluate(Request{Action: "user:update"}); decision.Effect != DecisionDeny {
+		t.Fatalf("Evaluate() = %+v; want %s", decision, DecisionDeny)
+	}
+}
+
+func TestLoadKeepsPoliciesOnFailure(t *testing.T) {
+	engine := newTestEngine(t, deny("freeze", "user:update", matches))
+
+	tests := []struct {
+		name   string
+		policy model.Policy
+	}{
+		{"empty name", allow("", "user:update", matches)},
+		{"invalid effect", model.Policy{Name: "audit", Effect: "audit", Action: "user:update", Condition: matches, Enabled: true}},
+		{"invalid action", allow("bad-action", "user update", matches)},
+		{"syntax error", allow("syntax", "user:update", "resource.department ==")},
+		{"non boolean condition", allow("string", "user:update", "'sales'")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.Load([]model.Policy{allow("same-department", "user:update", matches), tt.policy})
+			if !errors.Is(err, ErrInvalidPolicy) {
+				t.Fatalf("Load() error = %v; want %v", err, ErrInvalidPolicy)
+			}
+			if decision := engine.Evaluate(testRequest()); decision.Effect != DecisionDeny || len(decision.Policies) != 1 || decision.Policies[0] != "freeze" {
+				t.Fatalf("Evaluate() after failed Load = %+v; want the previous deny policy", decision)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket 令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+// memoryStore 基于令牌桶算法的进程内限流存储，仅适用于单实例部署
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore 创建进程内限流存储
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow 从令牌桶中取出一个令牌，桶容量为limit，每个窗口补满一次
+func (s *memoryStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	rate := float64(limit) / window.Seconds() // 每秒补充的令牌数
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), last: now, window: window}
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+	}
+
+	result := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	// 令牌桶补满所需的时间
+	result.Reset = time.Duration((float64(limit) - b.tokens) / rate * float64(time.Second))
+	return result, nil
+}
+
+// sweep 清理已经补满的令牌桶，防止内存无限增长
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.window {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"authentication/internal/config"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript 基于有序集合的滑动窗口限流脚本
+// KEYS[1] 限流键；ARGV: 当前时间(毫秒)、窗口(毫秒)、限额、请求唯一标识
+// 返回: {是否放行, 窗口内请求数, 最早请求的时间(毫秒), 最晚请求的时间(毫秒)}
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local oldestScore = now
+if oldest[2] then
+	oldestScore = tonumber(oldest[2])
+end
+local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+local newestScore = now
+if newest[2] then
+	newestScore = tonumber(newest[2])
+end
+return {allowed, count, oldestScore, newestScore}
+`)
+
+// redisStore 基于Redis滑动窗口的限流存储，适用于多实例部署
+type redisStore struct {
+	client *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisStore 创建Redis限流存储
+func NewRedisStore(cfg config.RedisConfig) (Store, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("连接Redis失败: %w", err)
+	}
+
+	return NewRedisStoreWithClient(client, cfg.KeyPrefix), nil
+}
+
+// NewRedisStoreWithClient 使用已有的Redis客户端创建限流存储
+func NewRedisStoreWithClient(client *redis.Client, prefix string) Store {
+	if prefix == "" {
+		prefix = "ratelimit:"
+	}
+	return &redisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+// Allow 在滑动窗口内记录一次请求
+func (s *redisStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := s.now()
+	nowMs := now.UnixMilli()
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+
+	values, err := slidingWindowScript.Run(ctx, s.client, []string{s.prefix + key},
+		nowMs, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("执行限流脚本失败: %w", err)
+	}
+
+	allowed, count, oldest, newest := values[0] == 1, int(values[1]), values[2], values[3]
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	// 窗口内最晚的请求过期后配额完全恢复，最早的请求过期后即可释放一个名额
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Duration(newest+window.Milliseconds()-nowMs) * time.Millisecond,
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration(oldest+window.Milliseconds()-nowMs) * time.Millisecond
+	}
+	return result, nil
+}
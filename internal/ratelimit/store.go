@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"authentication/internal/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Result 一次限流判断的结果
+type Result struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 窗口内允许的请求数
+	Remaining  int           // 剩余可用请求数
+	Reset      time.Duration // 不再有新请求时配额完全恢复（Remaining 回到 Limit）所需的时间，两种存储含义相同
+	RetryAfter time.Duration // 被拒绝时距离下一次可用的时间
+}
+
+// Store 限流存储接口
+type Store interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// NewStore 根据配置创建限流存储
+func NewStore(cfg config.RateLimitConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStore(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("不支持的限流存储: %s", cfg.Backend)
+	}
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeClock 测试使用的可控时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestStores 创建使用同一时钟的进程内存储和基于 miniredis 的Redis存储
+func newTestStores(t *testing.T, clock *fakeClock) map[string]Store {
+	t.Helper()
+
+	memory := NewMemoryStore().(*memoryStore)
+	memory.now = clock.Now
+	memory.lastSweep = clock.now
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	rds := NewRedisStoreWithClient(client, "").(*redisStore)
+	rds.now = clock.Now
+
+	return map[string]Store{"memory": memory, "redis": rds}
+}
+
+// ceilSeconds 按响应头 RateLimit-Reset 的精度向上取整到秒
+func ceilSeconds(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(d.Seconds())) * time.Second
+}
+
+func TestStoreAllowUpToLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	for name, store := range newTestStores(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 1; i <= 3; i++ {
+				result, err := store.Allow(ctx, "limit", 3, time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Allowed || result.Limit != 3 || result.Remaining != 3-i {
+					t.Fatalf("request %d: %+v", i, result)
+				}
+			}
+
+			result, err := store.Allow(ctx, "limit", 3, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+				t.Fatalf("over limit: %+v", result)
+			}
+
+			// 其他键不受影响
+			if result, _ := store.Allow(ctx, "other", 3, time.Minute); !result.Allowed {
+				t.Fatalf("other key: %+v", result)
+			}
+		})
+	}
+}
+
+// consume 每隔一秒发送一个请求，返回最后一个请求的结果和发送时间
+func consume(t *testing.T, store Store, clock *fakeClock, key string, n int) (Result, time.Time) {
+	t.Helper()
+	var result Result
+	var at time.Time
+	for i := 0; i < n; i++ {
+		var err error
+		at = clock.now
+		if result, err = store.Allow(context.Background(), key, 5, 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	return result, at
+}
+
+// TestStoreResetMeaning 两种存储的 Reset 都表示配额完全恢复所需的时间：
+// 按响应头的精度等待 Reset 后，下一个请求看到完整的配额；提前一秒则尚未完全恢复。
+func TestStoreResetMeaning(t *testing.T) {
+	for _, used := range []int{1, 3, 5} {
+		clock := &fakeClock{now: time.Unix(1700000000, 0)}
+		for name, store := range newTestStores(t, clock) {
+			t.Run(name, func(t *testing.T) {
+				last, at := consume(t, store, clock, "early", used)
+				if last.Reset <= 0 || last.Reset > 10*time.Second {
+					t.Fatalf("Reset = %v; want within window", last.Reset)
+				}
+				clock.now = at.Add(ceilSeconds(last.Reset) - time.Second)
+				result, err := store.Allow(context.Background(), "early", 5, 10*time.Second)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Remaining == 4 {
+					t.Fatalf("used %d: quota fully restored before Reset: %+v", used, result)
+				}
+
+				last, at = consume(t, store, clock, "full", used)
+				clock.now = at.Add(ceilSeconds(last.Reset))
+				result, err = store.Allow(context.Background(), "full", 5, 10*time.Second)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Allowed || result.Remaining != 4 {
+					t.Fatalf("used %d: after Reset %v: %+v; want full quota", used, last.Reset, result)
+				}
+			})
+		}
+	}
+}
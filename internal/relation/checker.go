@@ -0,0 +1,333 @@
+package relation
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"errors"
+	"fmt"
+)
+
+// defaultMaxDepth 默认的关系展开最大深度
+const defaultMaxDepth = 25
+
+// DefaultListObjectsLimit 列出对象时默认的每页数量
+const DefaultListObjectsLimit = 100
+
+// listObjectsScanFactor 列出对象时每页最多检查的候选对象数与每页数量的比值
+const listObjectsScanFactor = 10
+
+var (
+	// ErrMaxDepth 关系展开超过最大深度，通常是元组之间形成了很长的链或环
+	ErrMaxDepth = errors.New("关系展开超过最大深度")
+	// ErrExclusionCycle 排除规则的被减项经过了正在求值的关系，环上的不成立结果取反后没有确定的含义
+	ErrExclusionCycle = errors.New("关系的排除规则形成了环")
+)
+
+// 展开树的节点类型
+const (
+	NodeThis         = "this"         // 直接写入的主体
+	NodeUnion        = "union"        // 子节点的并集
+	NodeIntersection = "intersection" // 子节点的交集
+	NodeExclusion    = "exclusion"    // 第一个子节点减去第二个子节点
+)
+
+// TupleReader 按版本号读取元组快照
+type TupleReader interface {
+	Read(namespace, objectID, relation string, revision uint64) ([]model.RelationTuple, error)
+	ListObjectIDs(namespace string, revision uint64, after string, limit int) ([]string, error)
+}
+
+// ExpandNode 关系展开树的节点，叶子节点的主体可能仍是需要进一步展开的对象集合
+type ExpandNode struct {
+	Operation string        `json:"operation"`
+	Object    string        `json:"object,omitempty"`
+	Relation  string        `json:"relation,omitempty"`
+	Subjects  []string      `json:"subjects,omitempty"`
+	Children  []*ExpandNode `json:"children,omitempty"`
+}
+
+// Checker 在指定版本的快照上对关系求值
+type Checker interface {
+	Check(revision uint64, object Object, relation string, subject Subject) (bool, error)
+	Expand(revision uint64, object Object, relation string) (*ExpandNode, error)
+	ListObjects(revision uint64, namespace, relation string, subject Subject, cursor string, limit int) ([]string, string, error)
+}
+
+// checker 按命名空间的计算规则递归求值
+type checker struct {
+	schema   *Schema
+	reader   TupleReader
+	maxDepth int
+}
+
+// NewChecker 创建关系求值器，maxDepth 不大于0时使用默认深度
+func NewChecker(schema *Schema, reader TupleReader, maxDepth int) Checker {
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	return &checker{schema: schema, reader: reader, maxDepth: maxDepth}
+}
+
+// evaluation 一次求值的状态，记录已求值的结果，正在求值的关系再次出现时视为不成立以避免环。
+// 遇到环时得到的不成立结果依赖于尚未完成的求值，不会被记录。
+type evaluation struct {
+	*checker
+	revision uint64
+	results  map[string]bool
+	pending  map[string]bool
+	cycles   int
+}
+
+// newEvaluation 创建一次求值
+func (c *checker) newEvaluation(revision uint64) *evaluation {
+	return &evaluation{checker: c, revision: revision, results: make(map[string]bool), pending: make(map[string]bool)}
+}
+
+// Check 判断主体是否拥有对象的关系
+func (c *checker) Check(revision uint64, object Object, relation string, subject Subject) (bool, error) {
+	return c.newEvaluation(revision).check(object, relation, subject, 0)
+}
+
+// ListObjects 按对象ID顺序列出命名空间中主体拥有该关系的对象ID，从 cursor 之后开始，最多返回 limit 个，limit 不大于0时使用默认数量。
+// 对象的关系最终都来自写在该对象上的元组，因此只需检查快照中出现在元组里的对象。
+// 每页最多检查 limit 的固定倍数个候选对象，找满 limit 个或达到检查上限时返回最后检查的对象ID作为下一页的游标，
+// 候选对象检查完毕时游标为空。
+func (c *checker) ListObjects(revision uint64, namespace, relation string, subject Subject, cursor string, limit int) ([]string, string, error) {
+	if _, err := c.schema.rewrite(namespace, relation); err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 {
+		limit = DefaultListObjectsLimit
+	}
+
+	e := c.newEvaluation(revision)
+	objects := []string{}
+	for scanned := 0; scanned < limit*listObjectsScanFactor; {
+		ids, err := c.reader.ListObjectIDs(namespace, revision, cursor, limit)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, id := range ids {
+			ok, err := e.check(Object{Namespace: namespace, ID: id}, relation, subject, 0)
+			if err != nil {
+				return nil, "", err
+			}
+			cursor = id
+			scanned++
+			if ok {
+				objects = append(objects, id)
+				if len(objects) == limit {
+					return objects, cursor, nil
+				}
+			}
+		}
+		if len(ids) < limit {
+			return objects, "", nil
+		}
+	}
+	return objects, cursor, nil
+}
+
+// check 按关系的计算规则求值，结果在本次求值内缓存
+func (e *evaluation) check(object Object, relation string, subject Subject, depth int) (bool, error) {
+	if depth > e.maxDepth {
+		return false, ErrMaxDepth
+	}
+	rewrite, err := e.schema.rewrite(object.Namespace, relation)
+	if err != nil {
+		return false, err
+	}
+
+	key := object.String() + "#" + relation
+	if result, ok := e.results[key]; ok {
+		return result, nil
+	}
+	if e.pending[key] {
+		e.cycles++
+		return false, nil
+	}
+	e.pending[key] = true
+	cycles := e.cycles
+	result, err := e.checkRewrite(object, relation, rewrite, subject, depth)
+	delete(e.pending, key)
+	if err != nil {
+		return false, err
+	}
+	if result || e.cycles == cycles {
+		e.results[key] = result
+	}
+	return result, nil
+}
+
+// checkRewrite 对计算规则的一个节点求值
+func (e *evaluation) checkRewrite(object Object, relation string, rewrite *config.UsersetRewrite, subject Subject, depth int) (bool, error) {
+	switch {
+	case rewrite.This:
+		tuples, err := e.reader.Read(object.Namespace, object.ID, relation, e.revision)
+		if err != nil {
+			return false, err
+		}
+		for _, tuple := range tuples {
+			if tuple.Subject() == subject.String() {
+				return true, nil
+			}
+		}
+		// 主体为对象集合时递归检查
+		for _, tuple := range tuples {
+			if tuple.SubjectRelation == "" {
+				continue
+			}
+			userset := Object{Namespace: tuple.SubjectNamespace, ID: tuple.SubjectID}
+			ok, err := e.check(userset, tuple.SubjectRelation, subject, depth+1)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case rewrite.ComputedUserset != "":
+		return e.check(object, rewrite.ComputedUserset, subject, depth+1)
+
+	case rewrite.TupleToUserset != nil:
+		tuples, err := e.reader.Read(object.Namespace, object.ID, rewrite.TupleToUserset.Tupleset, e.revision)
+		if err != nil {
+			return false, err
+		}
+		for _, tuple := range tuples {
+			// 指向的对象没有定义该关系时跳过
+			if !e.schema.HasRelation(tuple.SubjectNamespace, rewrite.TupleToUserset.ComputedUserset) {
+				continue
+			}
+			target := Object{Namespace: tuple.SubjectNamespace, ID: tuple.SubjectID}
+			ok, err := e.check(target, rewrite.TupleToUserset.ComputedUserset, subject, depth+1)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case len(rewrite.Union) > 0:
+		for i := range rewrite.Union {
+			ok, err := e.checkRewrite(object, relation, &rewrite.Union[i], subject, depth)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case len(rewrite.Intersection) > 0:
+		for i := range rewrite.Intersection {
+			ok, err := e.checkRewrite(object, relation, &rewrite.Intersection[i], subject, depth)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case rewrite.Exclusion != nil:
+		ok, err := e.checkRewrite(object, relation, &rewrite.Exclusion.Base, subject, depth)
+		if err != nil || !ok {
+			return false, err
+		}
+		cycles := e.cycles
+		excluded, err := e.checkRewrite(object, relation, &rewrite.Exclusion.Subtract, subject, depth)
+		if err != nil {
+			return false, err
+		}
+		// 被减项因环而不成立时无法确定是否应排除，拒绝而不是授予关系
+		if !excluded && e.cycles != cycles {
+			return false, fmt.Errorf("%w: %s#%s", ErrExclusionCycle, object.Namespace, relation)
+		}
+		return !excluded, nil
+	}
+	return false, fmt.Errorf("%w: %s#%s 的计算规则为空", ErrInvalidSchema, object.Namespace, relation)
+}
+
+// Expand 展开对象的关系，返回由计算规则组成的树，叶子节点为直接写入的主体
+func (c *checker) Expand(revision uint64, object Object, relation string) (*ExpandNode, error) {
+	return c.newEvaluation(revision).expand(object, relation, 0)
+}
+
+// expand 展开对象的关系
+func (e *evaluation) expand(object Object, relation string, depth int) (*ExpandNode, error) {
+	if depth > e.maxDepth {
+		return nil, ErrMaxDepth
+	}
+	rewrite, err := e.schema.rewrite(object.Namespace, relation)
+	if err != nil {
+		return nil, err
+	}
+	node, err := e.expandRewrite(object, relation, rewrite, depth)
+	if err != nil {
+		return nil, err
+	}
+	node.Object = object.String()
+	node.Relation = relation
+	return node, nil
+}
+
+// expandRewrite 展开计算规则的一个节点
+func (e *evaluation) expandRewrite(object Object, relation string, rewrite *config.UsersetRewrite, depth int) (*ExpandNode, error) {
+	switch {
+	case rewrite.This:
+		tuples, err := e.reader.Read(object.Namespace, object.ID, relation, e.revision)
+		if err != nil {
+			return nil, err
+		}
+		subjects := make([]string, len(tuples))
+		for i, tuple := range tuples {
+			subjects[i] = tuple.Subject()
+		}
+		return &ExpandNode{Operation: NodeThis, Object: object.String(), Relation: relation, Subjects: subjects}, nil
+
+	case rewrite.ComputedUserset != "":
+		// 包装为单个子节点，使展开的关系保留自己的名称
+		child, err := e.expand(object, rewrite.ComputedUserset, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return &ExpandNode{Operation: NodeUnion, Children: []*ExpandNode{child}}, nil
+
+	case rewrite.TupleToUserset != nil:
+		tuples, err := e.reader.Read(object.Namespace, object.ID, rewrite.TupleToUserset.Tupleset, e.revision)
+		if err != nil {
+			return nil, err
+		}
+		node := &ExpandNode{Operation: NodeUnion, Children: []*ExpandNode{}}
+		for _, tuple := range tuples {
+			if !e.schema.HasRelation(tuple.SubjectNamespace, rewrite.TupleToUserset.ComputedUserset) {
+				continue
+			}
+			target := Object{Namespace: tuple.SubjectNamespace, ID: tuple.SubjectID}
+			child, err := e.expand(target, rewrite.TupleToUserset.ComputedUserset, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		}
+		return node, nil
+
+	case len(rewrite.Union) > 0:
+		return e.expandChildren(NodeUnion, object, relation, rewrite.Union, depth)
+
+	case len(rewrite.Intersection) > 0:
+		return e.expandChildren(NodeIntersection, object, relation, rewrite.Intersection, depth)
+
+	case rewrite.Exclusion != nil:
+		return e.expandChildren(NodeExclusion, object, relation, []config.UsersetRewrite{rewrite.Exclusion.Base, rewrite.Exclusion.Subtract}, depth)
+	}
+	return nil, fmt.Errorf("%w: %s#%s 的计算规则为空", ErrInvalidSchema, object.Namespace, relation)
+}
+
+// expandChildren 展开组合规则的全部子规则
+func (e *evaluation) expandChildren(operation string, object Object, relation string, rewrites []config.UsersetRewrite, depth int) (*ExpandNode, error) {
+	node := &ExpandNode{Operation: operation, Children: make([]*ExpandNode, 0, len(rewrites))}
+	for i := range rewrites {
+		child, err := e.expandRewrite(object, relation, &rewrites[i], depth)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
@@ -0,0 +1,178 @@
+package relation
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memoryReader 测试使用的内存元组快照，只包含一个版本
+type memoryReader struct {
+	tuples []model.RelationTuple
+	pages  int // ListObjectIDs 的调用次数
+}
+
+// write 写入文本形式的元组，如 doc:a#viewer@user:1
+func (r *memoryReader) write(t *testing.T, texts ...string) {
+	t.Helper()
+	for _, text := range texts {
+		object, rest, _ := strings.Cut(text, "#")
+		relation, subject, _ := strings.Cut(rest, "@")
+		o, err := ParseObject(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := ParseSubject(subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.tuples = append(r.tuples, model.RelationTuple{
+			Namespace: o.Namespace, ObjectID: o.ID, Relation: relation,
+			SubjectNamespace: s.Namespace, SubjectID: s.ID, SubjectRelation: s.Relation,
+		})
+	}
+}
+
+func (r *memoryReader) Read(namespace, objectID, relation string, revision uint64) ([]model.RelationTuple, error) {
+	var tuples []model.RelationTuple
+	for _, tuple := range r.tuples {
+		if tuple.Namespace == namespace && tuple.ObjectID == objectID && tuple.Relation == relation {
+			tuples = append(tuples, tuple)
+		}
+	}
+	return tuples, nil
+}
+
+func (r *memoryReader) ListObjectIDs(namespace string, revision uint64, after string, limit int) ([]string, error) {
+	r.pages++
+	seen := map[string]bool{}
+	var ids []string
+	for _, tuple := range r.tuples {
+		if tuple.Namespace == namespace && tuple.ObjectID > after && !seen[tuple.ObjectID] {
+			seen[tuple.ObjectID] = true
+			ids = append(ids, tuple.ObjectID)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// newTestChecker 创建 doc 命名空间的求值器，viewer 为直接写入的查看者减去 parent 上的 blocked
+func newTestChecker(t *testing.T, reader TupleReader) Checker {
+	t.Helper()
+	schema, err := NewSchema(config.RelationConfig{Namespaces: []config.RelationNamespace{{
+		Name: "doc",
+		Relations: []config.RelationDefinition{
+			{Name: "parent"},
+			{Name: "blocked", Rewrite: &config.UsersetRewrite{Union: []config.UsersetRewrite{
+				{This: true},
+				{TupleToUserset: &config.TupleToUserset{Tupleset: "parent", ComputedUserset: "viewer"}},
+			}}},
+			{Name: "viewer", Rewrite: &config.UsersetRewrite{Exclusion: &config.Exclusion{
+				Base:     config.UsersetRewrite{This: true},
+				Subtract: config.UsersetRewrite{ComputedUserset: "blocked"},
+			}}},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewChecker(schema, reader, 0)
+}
+
+func TestCheckExclusionCycle(t *testing.T) {
+	reader := &memoryReader{}
+	reader.write(t,
+		"doc:a#viewer@user:1",
+		"doc:b#viewer@user:1", "doc:b#blocked@user:1",
+		"doc:c#viewer@user:1", "doc:c#parent@doc:a",
+		// doc:d 的 blocked 经过 parent 回到 doc:d#viewer
+		"doc:d#viewer@user:1", "doc:d#parent@doc:d",
+	)
+	checker := newTestChecker(t, reader)
+	user := Subject{Namespace: UserNamespace, ID: "1"}
+
+	tests := []struct {
+		id      string
+		want    bool
+		wantErr error
+	}{
+		{"a", true, nil},
+		{"b", false, nil},
+		{"c", false, nil},
+		{"d", false, ErrExclusionCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			ok, err := checker.Check(1, Object{Namespace: "doc", ID: tt.id}, "viewer", user)
+			if ok != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() = %v, %v; want %v, %v", ok, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListObjectsPages(t *testing.T) {
+	reader := &memoryReader{}
+	for i := 0; i < 25; i++ {
+		reader.write(t, fmt.Sprintf("doc:%02d#viewer@user:1", i))
+		if i%2 == 1 {
+			reader.write(t, fmt.Sprintf("doc:%02d#blocked@user:1", i))
+		}
+	}
+	checker := newTestChecker(t, reader)
+	user := Subject{Namespace: UserNamespace, ID: "1"}
+
+	var all []string
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("cursor did not advance")
+		}
+		objects, next, err := checker.ListObjects(1, "doc", "viewer", user, cursor, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) > 5 {
+			t.Fatalf("page %d has %d objects", page, len(objects))
+		}
+		all = append(all, objects...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(all) != 13 {
+		t.Fatalf("listed %d objects; want 13: %v", len(all), all)
+	}
+	for i, id := range all {
+		if want := fmt.Sprintf("%02d", 2*i); id != want {
+			t.Fatalf("objects[%d] = %s; want %s", i, id, want)
+		}
+	}
+}
+
+func TestListObjectsBoundsScan(t *testing.T) {
+	reader := &memoryReader{}
+	for i := 0; i < 100; i++ {
+		reader.write(t, fmt.Sprintf("doc:%03d#blocked@user:1", i))
+	}
+	checker := newTestChecker(t, reader)
+
+	objects, next, err := checker.ListObjects(1, "doc", "viewer", Subject{Namespace: UserNamespace, ID: "1"}, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每页数量为2时最多检查20个候选对象
+	if len(objects) != 0 || next != "019" || reader.pages != listObjectsScanFactor {
+		t.Fatalf("ListObjects() = %v, %q after %d reads; want no objects, cursor 019 after %d reads", objects, next, reader.pages, listObjectsScanFactor)
+	}
+}
@@ -0,0 +1,234 @@
+package relation
+
+import (
+	"authentication/internal/config"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UserNamespace 主体中表示系统用户的命名空间，如 user:42，无需在配置中定义
+const UserNamespace = "user"
+
+var (
+	// ErrInvalidSchema 命名空间配置无效
+	ErrInvalidSchema = errors.New("无效的关系命名空间配置")
+	// ErrInvalidTuple 对象、关系或主体无效
+	ErrInvalidTuple = errors.New("无效的关系元组")
+	// ErrUnknownRelation 命名空间中未定义该关系
+	ErrUnknownRelation = errors.New("未定义的关系")
+)
+
+// Object 对象，文本形式为 <命名空间>:<ID>，如 doc:readme
+type Object struct {
+	Namespace string
+	ID        string
+}
+
+// String 返回对象的文本形式
+func (o Object) String() string {
+	return o.Namespace + ":" + o.ID
+}
+
+// Subject 主体，文本形式为 user:42 或表示一组主体的 group:eng#member
+type Subject struct {
+	Namespace string
+	ID        string
+	Relation  string // 不为空时表示对象在该关系上的全部主体
+}
+
+// String 返回主体的文本形式
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.ID
+	}
+	return s.Namespace + ":" + s.ID + "#" + s.Relation
+}
+
+// Object 返回主体所在的对象
+func (s Subject) Object() Object {
+	return Object{Namespace: s.Namespace, ID: s.ID}
+}
+
+// ParseObject 解析 <命名空间>:<ID> 形式的对象
+func ParseObject(text string) (Object, error) {
+	namespace, id, ok := strings.Cut(text, ":")
+	if !ok || !validName(namespace) || !validID(id) {
+		return Object{}, fmt.Errorf("%w: 对象 %q 应为 <命名空间>:<ID>", ErrInvalidTuple, text)
+	}
+	return Object{Namespace: namespace, ID: id}, nil
+}
+
+// ParseSubject 解析 <命名空间>:<ID> 或 <命名空间>:<ID>#<关系> 形式的主体
+func ParseSubject(text string) (Subject, error) {
+	objectText, relation, hasRelation := strings.Cut(text, "#")
+	object, err := ParseObject(objectText)
+	if err != nil || hasRelation && !validName(relation) {
+		return Subject{}, fmt.Errorf("%w: 主体 %q 应为 <命名空间>:<ID> 或 <命名空间>:<ID>#<关系>", ErrInvalidTuple, text)
+	}
+	return Subject{Namespace: object.Namespace, ID: object.ID, Relation: relation}, nil
+}
+
+// validName 命名空间和关系名称只能包含小写字母、数字和 "_"
+func validName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validID 对象ID不能包含分隔符 ":"、"#"、"@" 和空白
+func validID(id string) bool {
+	return id != "" && len(id) <= 128 && !strings.ContainsAny(id, ":#@ \t\r\n")
+}
+
+// Schema 编译后的命名空间配置
+type Schema struct {
+	namespaces map[string]map[string]*config.UsersetRewrite
+}
+
+// NewSchema 校验并编译命名空间配置
+func NewSchema(cfg config.RelationConfig) (*Schema, error) {
+	schema := &Schema{namespaces: make(map[string]map[string]*config.UsersetRewrite, len(cfg.Namespaces))}
+	for _, ns := range cfg.Namespaces {
+		if !validName(ns.Name) {
+			return nil, fmt.Errorf("%w: 命名空间名称 %q 无效", ErrInvalidSchema, ns.Name)
+		}
+		if _, ok := schema.namespaces[ns.Name]; ok {
+			return nil, fmt.Errorf("%w: 命名空间 %s 重复定义", ErrInvalidSchema, ns.Name)
+		}
+		relations := make(map[string]*config.UsersetRewrite, len(ns.Relations))
+		for _, rel := range ns.Relations {
+			if !validName(rel.Name) {
+				return nil, fmt.Errorf("%w: 命名空间 %s 的关系名称 %q 无效", ErrInvalidSchema, ns.Name, rel.Name)
+			}
+			if _, ok := relations[rel.Name]; ok {
+				return nil, fmt.Errorf("%w: 关系 %s#%s 重复定义", ErrInvalidSchema, ns.Name, rel.Name)
+			}
+			relations[rel.Name] = rel.Rewrite
+		}
+		schema.namespaces[ns.Name] = relations
+	}
+
+	// 校验计算规则引用的关系
+	for name, relations := range schema.namespaces {
+		for relation, rewrite := range relations {
+			if rewrite == nil {
+				continue
+			}
+			if err := schema.validateRewrite(relations, rewrite); err != nil {
+				return nil, fmt.Errorf("%w: 关系 %s#%s: %v", ErrInvalidSchema, name, relation, err)
+			}
+		}
+	}
+	return schema, nil
+}
+
+// validateRewrite 检查计算规则的每个节点只设置一项，引用的关系在同一命名空间中有定义
+func (s *Schema) validateRewrite(relations map[string]*config.UsersetRewrite, rewrite *config.UsersetRewrite) error {
+	set := 0
+	for _, ok := range []bool{
+		rewrite.This,
+		rewrite.ComputedUserset != "",
+		rewrite.TupleToUserset != nil,
+		len(rewrite.Union) > 0,
+		len(rewrite.Intersection) > 0,
+		rewrite.Exclusion != nil,
+	} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("每个规则节点只能设置 this、computed_userset、tuple_to_userset、union、intersection、exclusion 中的一项")
+	}
+
+	switch {
+	case rewrite.ComputedUserset != "":
+		if _, ok := relations[rewrite.ComputedUserset]; !ok {
+			return fmt.Errorf("computed_userset 引用了未定义的关系 %s", rewrite.ComputedUserset)
+		}
+	case rewrite.TupleToUserset != nil:
+		// computed_userset 位于 tupleset 指向的对象上，其命名空间在写入元组时才能确定
+		if _, ok := relations[rewrite.TupleToUserset.Tupleset]; !ok {
+			return fmt.Errorf("tuple_to_userset 引用了未定义的关系 %s", rewrite.TupleToUserset.Tupleset)
+		}
+		if !validName(rewrite.TupleToUserset.ComputedUserset) {
+			return errors.New("tuple_to_userset 缺少 computed_userset")
+		}
+	case rewrite.Exclusion != nil:
+		for _, child := range []*config.UsersetRewrite{&rewrite.Exclusion.Base, &rewrite.Exclusion.Subtract} {
+			if err := s.validateRewrite(relations, child); err != nil {
+				return err
+			}
+		}
+	default:
+		for i := range rewrite.Union {
+			if err := s.validateRewrite(relations, &rewrite.Union[i]); err != nil {
+				return err
+			}
+		}
+		for i := range rewrite.Intersection {
+			if err := s.validateRewrite(relations, &rewrite.Intersection[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HasRelation 判断命名空间中是否定义了该关系
+func (s *Schema) HasRelation(namespace, relation string) bool {
+	_, ok := s.namespaces[namespace][relation]
+	return ok
+}
+
+// HasNamespace 判断是否定义了该命名空间
+func (s *Schema) HasNamespace(namespace string) bool {
+	_, ok := s.namespaces[namespace]
+	return ok
+}
+
+// rewrite 返回关系的计算规则，未配置规则的关系只包含直接写入的元组
+func (s *Schema) rewrite(namespace, relation string) (*config.UsersetRewrite, error) {
+	relations, ok := s.namespaces[namespace]
+	if !ok {
+		return nil, fmt.Errorf("%w: 命名空间 %s 未定义", ErrUnknownRelation, namespace)
+	}
+	rewrite, ok := relations[relation]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, namespace, relation)
+	}
+	if rewrite == nil {
+		return &config.UsersetRewrite{This: true}, nil
+	}
+	return rewrite, nil
+}
+
+// ValidateRelation 检查对象的命名空间和关系已定义
+func (s *Schema) ValidateRelation(object Object, relation string) error {
+	_, err := s.rewrite(object.Namespace, relation)
+	return err
+}
+
+// ValidateSubject 检查主体：用户主体不能带关系，其他主体的命名空间（及关系）需已定义
+func (s *Schema) ValidateSubject(subject Subject) error {
+	if subject.Namespace == UserNamespace && !s.HasNamespace(UserNamespace) {
+		if subject.Relation != "" {
+			return fmt.Errorf("%w: 用户主体不能指定关系", ErrInvalidTuple)
+		}
+		return nil
+	}
+	if !s.HasNamespace(subject.Namespace) {
+		return fmt.Errorf("%w: 命名空间 %s 未定义", ErrUnknownRelation, subject.Namespace)
+	}
+	if subject.Relation != "" && !s.HasRelation(subject.Namespace, subject.Relation) {
+		return fmt.Errorf("%w: %s#%s", ErrUnknownRelation, subject.Namespace, subject.Relation)
+	}
+	return nil
+}
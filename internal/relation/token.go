@@ -0,0 +1,33 @@
+package relation
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// tokenPrefix 一致性令牌的格式版本
+const tokenPrefix = "r1."
+
+// ErrInvalidToken 一致性令牌无效
+var ErrInvalidToken = errors.New("无效的一致性令牌")
+
+// EncodeToken 将版本号编码为不透明的一致性令牌，写入元组后返回给调用方，
+// 之后的检查携带该令牌即可保证看到这次写入
+func EncodeToken(revision uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + strconv.FormatUint(revision, 10)))
+}
+
+// DecodeToken 解析一致性令牌中的版本号
+func DecodeToken(token string) (uint64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(data), tokenPrefix) {
+		return 0, ErrInvalidToken
+	}
+	revision, err := strconv.ParseUint(strings.TrimPrefix(string(data), tokenPrefix), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return revision, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter 审计日志查询条件，零值字段不参与过滤
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+}
+
+// AuditLogRepository 审计日志存储库接口，日志只能追加不能修改
+type AuditLogRepository interface {
+	Create(entry *model.AuditLog) error
+	List(filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error)
+}
+
+// auditLogRepository 审计日志存储库实现
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建审计日志存储库实例
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create 追加审计日志
+func (r *auditLogRepository) Create(entry *model.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// List 按条件获取审计日志，按时间倒序
+func (r *auditLogRepository) List(filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	var entries []model.AuditLog
+	var total int64
+
+	query := r.db.Model(&model.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 // InitDB 初始化数据库连接
@@ -20,16 +22,57 @@ func InitDB(cfg config.DBConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("连接数据库失败: %w", err)
 	}
 
+	// 移除已被替换的旧唯一索引
+	if err := dropLegacyIndexes(db); err != nil {
+		return nil, fmt.Errorf("迁移索引失败: %w", err)
+	}
+
+	// 用户角色关联使用自定义连接表，记录有效期和授予人
+	if err := db.SetupJoinTable(&model.User{}, "Roles", &model.UserRole{}); err != nil {
+		return nil, fmt.Errorf("设置用户角色关联失败: %w", err)
+	}
+	if err := db.SetupJoinTable(&model.Role{}, "Users", &model.UserRole{}); err != nil {
+		return nil, fmt.Errorf("设置用户角色关联失败: %w", err)
+	}
+
+	// 新增邮箱验证字段前已存在的用户视为邮箱已验证，避免升级后按未验证用户限制权限
+	backfillVerified := db.Migrator().HasTable(&model.User{}) && !db.Migrator().HasColumn(&model.User{}, "EmailVerified")
+
 	// 自动迁移模型
 	err = db.AutoMigrate(
 		&model.User{},
 		&model.Role{},
 		&model.Permission{},
+		&model.PasswordHistory{},
+		&model.EmailVerification{},
+		&model.Policy{},
+		&model.Organization{},
+		&model.Membership{},
+		&model.Group{},
+		&model.ElevationRequest{},
+		&model.AuditLog{},
+		&model.SeparationConstraint{},
+		&model.RelationTuple{},
+		&model.RelationRevision{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("迁移数据库模型失败: %w", err)
 	}
 
+	if backfillVerified {
+		err := db.Unscoped().Model(&model.User{}).Where("email_verified = ?", false).
+			Updates(map[string]interface{}{"email_verified": true, "verified_at": gorm.Expr("created_at")}).Error
+		if err != nil {
+			return nil, fmt.Errorf("迁移邮箱验证状态失败: %w", err)
+		}
+	}
+
+	// 关系元组的全局版本号行，写入元组时锁定该行
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RelationRevision{ID: relationRevisionID}).Error
+	if err != nil {
+		return nil, fmt.Errorf("初始化关系元组版本号失败: %w", err)
+	}
+
 	// 初始化基础数据
 	if err := initBaseData(db); err != nil {
 		return nil, fmt.Errorf("初始化基础数据失败: %w", err)
@@ -38,6 +81,28 @@ func InitDB(cfg config.DBConfig) (*gorm.DB, error) {
 	return db, nil
 }
 
+// dropLegacyIndexes 删除已被替换的唯一索引：
+// 用户名、邮箱改为仅在未删除用户中唯一，角色名称改为在全局或组织内唯一
+func dropLegacyIndexes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	legacy := []struct {
+		model interface{}
+		name  string
+	}{
+		{&model.User{}, "idx_users_username"},
+		{&model.User{}, "idx_users_email"},
+		{&model.Role{}, "idx_roles_name"},
+	}
+	for _, index := range legacy {
+		if migrator.HasIndex(index.model, index.name) {
+			if err := migrator.DropIndex(index.model, index.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // initBaseData 初始化基础数据
 func initBaseData(db *gorm.DB) error {
 	// 检查是否已有权限数据
@@ -55,6 +120,10 @@ func initBaseData(db *gorm.DB) error {
 		{Code: "user:create", Name: "创建用户", Description: "创建新用户"},
 		{Code: "user:update", Name: "更新用户", Description: "更新用户信息"},
 		{Code: "user:delete", Name: "删除用户", Description: "删除用户"},
+		{Code: "user:unlock", Name: "解锁用户", Description: "解除用户的登录锁定"},
+		{Code: "user:assign", Name: "分配角色", Description: "为用户分配角色"},
+		{Code: "user:restore", Name: "恢复用户", Description: "恢复已删除的用户"},
+		{Code: "user:purge", Name: "彻底删除用户", Description: "彻底删除已删除的用户及其数据"},
 
 		{Code: "role:list", Name: "角色列表", Description: "查看角色列表"},
 		{Code: "role:read", Name: "查看角色", Description: "查看角色详情"},
@@ -64,11 +133,47 @@ func initBaseData(db *gorm.DB) error {
 		{Code: "role:assign", Name: "分配权限", Description: "为角色分配权限"},
 
 		{Code: "permission:list", Name: "权限列表", Description: "查看权限列表"},
+		{Code: "permission:read", Name: "查看权限", Description: "查看权限详情"},
+		{Code: "permission:create", Name: "创建权限", Description: "创建新权限"},
+		{Code: "permission:update", Name: "更新权限", Description: "更新权限信息"},
+		{Code: "permission:delete", Name: "删除权限", Description: "删除权限"},
+		{Code: "policy:list", Name: "策略列表", Description: "查看访问控制策略列表"},
+		{Code: "policy:read", Name: "查看策略", Description: "查看访问控制策略详情"},
+		{Code: "policy:create", Name: "创建策略", Description: "创建访问控制策略"},
+		{Code: "policy:update", Name: "更新策略", Description: "更新访问控制策略"},
+		{Code: "policy:delete", Name: "删除策略", Description: "删除访问控制策略"},
+		{Code: "user:read:self", Name: "查看本人", Description: "查看自己的用户详情"},
+		{Code: "user:update:self", Name: "更新本人", Description: "更新自己的用户信息"},
+		{Code: "organization:list", Name: "组织列表", Description: "查看组织列表"},
+		{Code: "organization:read", Name: "查看组织", Description: "查看组织详情及成员"},
+		{Code: "organization:create", Name: "创建组织", Description: "创建新组织"},
+		{Code: "organization:update", Name: "更新组织", Description: "更新组织信息"},
+		{Code: "organization:delete", Name: "删除组织", Description: "删除组织及其成员关系和角色"},
+		{Code: "organization:member", Name: "管理成员", Description: "管理组织成员及其组织角色"},
+		{Code: "group:list", Name: "分组列表", Description: "查看用户分组列表"},
+		{Code: "group:read", Name: "查看分组", Description: "查看用户分组详情及成员"},
+		{Code: "group:create", Name: "创建分组", Description: "创建用户分组"},
+		{Code: "group:update", Name: "更新分组", Description: "更新用户分组信息及上级分组"},
+		{Code: "group:delete", Name: "删除分组", Description: "删除用户分组"},
+		{Code: "group:member", Name: "管理分组成员", Description: "添加或移除分组成员"},
+		{Code: "group:assign", Name: "分配分组角色", Description: "为用户分组分配角色"},
+		{Code: "elevation:request", Name: "申请提权", Description: "申请在限定时间内拥有某个角色"},
+		{Code: "elevation:approve", Name: "审批提权", Description: "查看并审批他人的提权申请"},
+		{Code: "audit:list", Name: "审计日志", Description: "查看审计日志"},
+		{Code: "separation:list", Name: "职责分离约束列表", Description: "查看职责分离约束列表"},
+		{Code: "separation:read", Name: "查看职责分离约束", Description: "查看职责分离约束详情"},
+		{Code: "separation:create", Name: "创建职责分离约束", Description: "创建职责分离约束"},
+		{Code: "separation:update", Name: "更新职责分离约束", Description: "更新职责分离约束"},
+		{Code: "separation:delete", Name: "删除职责分离约束", Description: "删除职责分离约束"},
+		{Code: "authz:check", Name: "授权检查", Description: "查询任意用户能否对资源执行操作，供其他服务调用"},
+		{Code: "relation:read", Name: "查看关系元组", Description: "查看关系元组"},
+		{Code: "relation:write", Name: "写入关系元组", Description: "写入和删除关系元组"},
+		{Code: "relation:check", Name: "关系检查", Description: "检查、展开关系及列出主体可访问的对象"},
 	}
 
 	// 创建基础角色
 	adminRole := model.Role{
-		Name:        "admin",
+		Name:        model.AdminRoleName,
 		Description: "系统管理员",
 		Permissions: permissions,
 	}
@@ -76,31 +181,34 @@ func initBaseData(db *gorm.DB) error {
 	userRole := model.Role{
 		Name:        "user",
 		Description: "普通用户",
-		Permissions: []model.Permission{
-			permissions[0], // user:list
-			permissions[1], // user:read
-		},
 	}
 
 	// 创建管理员用户
+	now := time.Now()
 	adminUser := model.User{
-		Username: "admin",
-		Email:    "admin@example.com",
-		Password: "password", // 会在BeforeSave钩子中自动加密
-		FullName: "系统管理员",
-		Active:   true,
-		Roles:    []model.Role{adminRole},
+		Username:      "admin",
+		Email:         "admin@example.com",
+		FullName:      "系统管理员",
+		Active:        true,
+		EmailVerified: true,
+		VerifiedAt:    &now,
+	}
+	if err := adminUser.SetPassword("password"); err != nil {
+		return err
 	}
 
 	// 使用事务保证数据一致性
 	return db.Transaction(func(tx *gorm.DB) error {
 		// 创建权限
-		for _, perm := range permissions {
-			if err := tx.Create(&perm).Error; err != nil {
+		for i := range permissions {
+			if err := tx.Create(&permissions[i]).Error; err != nil {
 				return err
 			}
 		}
 
+		// 普通用户角色按权限代码选取权限，增删基础权限时不受顺序影响
+		userRole.Permissions = permissionsByCode(permissions, "user:read:self", "user:update:self", "elevation:request")
+
 		// 创建角色
 		if err := tx.Create(&adminRole).Error; err != nil {
 			return err
@@ -110,7 +218,8 @@ func initBaseData(db *gorm.DB) error {
 			return err
 		}
 
-		// 创建管理员用户
+		// 创建管理员用户，角色需在创建后取得ID再关联
+		adminUser.Roles = []model.Role{adminRole}
 		if err := tx.Create(&adminUser).Error; err != nil {
 			return err
 		}
@@ -118,3 +227,17 @@ func initBaseData(db *gorm.DB) error {
 		return nil
 	})
 }
+
+// permissionsByCode 按权限代码从基础权限中选取权限，代码不存在时忽略
+func permissionsByCode(permissions []model.Permission, codes ...string) []model.Permission {
+	selected := make([]model.Permission, 0, len(codes))
+	for _, code := range codes {
+		for _, permission := range permissions {
+			if permission.Code == code {
+				selected = append(selected, permission)
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"errors"
+	"gorm.io/gorm"
+)
+
+// ErrElevationNotPending 提权申请已被处理
+var ErrElevationNotPending = errors.New("提权申请已被处理")
+
+// ElevationRepository 提权申请存储库接口
+type ElevationRepository interface {
+	Create(request *model.ElevationRequest) error
+	GetByID(id uint) (*model.ElevationRequest, error)
+	List(userID uint, status string, page, pageSize int) ([]model.ElevationRequest, int64, error)
+	HasPending(userID, roleID uint) (bool, error)
+	Review(request *model.ElevationRequest) error
+	Reopen(id uint) error
+}
+
+// elevationRepository 提权申请存储库实现
+type elevationRepository struct {
+	db *gorm.DB
+}
+
+// NewElevationRepository 创建提权申请存储库实例
+func NewElevationRepository(db *gorm.DB) ElevationRepository {
+	return &elevationRepository{db: db}
+}
+
+// Create 创建提权申请
+func (r *elevationRepository) Create(request *model.ElevationRequest) error {
+	return r.db.Omit("User", "Role").Create(request).Error
+}
+
+// GetByID 根据ID获取提权申请及申请人和角色
+func (r *elevationRepository) GetByID(id uint) (*model.ElevationRequest, error) {
+	var request model.ElevationRequest
+	err := r.db.Preload("User").Preload("Role").First(&request, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// List 获取提权申请列表，userID 为0时不限申请人，status 为空时不限状态
+func (r *elevationRepository) List(userID uint, status string, page, pageSize int) ([]model.ElevationRequest, int64, error) {
+	var requests []model.ElevationRequest
+	var total int64
+
+	query := r.db.Model(&model.ElevationRequest{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Preload("User").Preload("Role").Order("id DESC").Offset(offset).Limit(pageSize).Find(&requests).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return requests, total, nil
+}
+
+// HasPending 用户是否已有该角色的待审批申请
+func (r *elevationRepository) HasPending(userID, roleID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.ElevationRequest{}).
+		Where("user_id = ? AND role_id = ? AND status = ?", userID, roleID, model.ElevationPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Review 记录审批结果，只有待审批的申请可以审批，并发审批时只有一个成功
+func (r *elevationRepository) Review(request *model.ElevationRequest) error {
+	result := r.db.Model(&model.ElevationRequest{}).
+		Where("id = ? AND status = ?", request.ID, model.ElevationPending).
+		Updates(map[string]interface{}{
+			"status":         request.Status,
+			"reviewer_id":    request.ReviewerID,
+			"review_comment": request.ReviewComment,
+			"reviewed_at":    request.ReviewedAt,
+			"expires_at":     request.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrElevationNotPending
+	}
+	return nil
+}
+
+// Reopen 将已通过的申请恢复为待审批，用于授予失败时回滚审批结果
+func (r *elevationRepository) Reopen(id uint) error {
+	return r.db.Model(&model.ElevationRequest{}).
+		Where("id = ? AND status = ?", id, model.ElevationApproved).
+		Updates(map[string]interface{}{
+			"status":         model.ElevationPending,
+			"reviewer_id":    nil,
+			"review_comment": "",
+			"reviewed_at":    nil,
+			"expires_at":     nil,
+		}).Error
+}
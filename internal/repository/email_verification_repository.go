@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	// ErrVerificationUsed 验证令牌已被使用
+	ErrVerificationUsed = errors.New("验证令牌已被使用")
+	// ErrVerificationEmailChanged 令牌签发后用户邮箱已被修改
+	ErrVerificationEmailChanged = errors.New("用户邮箱已被修改")
+)
+
+// EmailVerificationRepository 邮箱验证令牌存储库接口
+type EmailVerificationRepository interface {
+	Create(verification *model.EmailVerification) error
+	GetByTokenHash(tokenHash string) (*model.EmailVerification, error)
+	GetLatestByUser(userID uint) (*model.EmailVerification, error)
+	Consume(verification *model.EmailVerification) error
+	InvalidateByUser(userID uint) error
+}
+
+// emailVerificationRepository 邮箱验证令牌存储库实现
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationRepository 创建邮箱验证令牌存储库实例
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
+
+// Create 创建验证令牌
+func (r *emailVerificationRepository) Create(verification *model.EmailVerification) error {
+	return r.db.Create(verification).Error
+}
+
+// GetByTokenHash 根据令牌哈希获取验证记录
+func (r *emailVerificationRepository) GetByTokenHash(tokenHash string) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+	err := r.db.Where("token_hash = ?", tokenHash).First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// GetLatestByUser 获取用户最近一次的验证记录
+func (r *emailVerificationRepository) GetLatestByUser(userID uint) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Consume 在同一事务中标记令牌已使用并将用户邮箱标记为已验证。
+// 令牌已被使用时返回 ErrVerificationUsed，保证并发请求中只有一个成功；
+// 用户邮箱已不是令牌签发时的邮箱时返回 ErrVerificationEmailChanged，令牌保持未使用。
+func (r *emailVerificationRepository) Consume(verification *model.EmailVerification) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.EmailVerification{}).
+			Where("id = ? AND used_at IS NULL", verification.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVerificationUsed
+		}
+
+		result = tx.Model(&model.User{}).
+			Where("id = ? AND email = ?", verification.UserID, verification.Email).
+			Updates(map[string]interface{}{"email_verified": true, "verified_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVerificationEmailChanged
+		}
+		return nil
+	})
+}
+
+// InvalidateByUser 使用户所有未使用的令牌失效
+func (r *emailVerificationRepository) InvalidateByUser(userID uint) error {
+	return r.db.Model(&model.EmailVerification{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("expires_at", time.Now()).Error
+}
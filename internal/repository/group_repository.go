@@ -0,0 +1,469 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrGroupNotFound 分组不存在
+	ErrGroupNotFound = errors.New("分组不存在")
+	// ErrGroupCycle 分组嵌套关系形成环
+	ErrGroupCycle = errors.New("分组嵌套关系不能形成环")
+	// ErrGroupHasChildren 分组下还有子分组
+	ErrGroupHasChildren = errors.New("分组下还有子分组，不能删除")
+	// ErrUserNotFound 要添加的用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+)
+
+// GroupRepository 用户分组存储库接口
+type GroupRepository interface {
+	Create(group *model.Group) error
+	GetByID(id uint) (*model.Group, error)
+	GetByName(name string) (*model.Group, error)
+	Update(group *model.Group) error
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.Group, int64, error)
+	ListMembers(groupID uint) ([]model.User, error)
+	AddMembers(groupID uint, userIDs []uint) error
+	RemoveMembers(groupID uint, userIDs []uint) error
+	SetRoles(groupID uint, roleIDs []uint) error
+	EffectiveRoles(groupID uint) ([]model.Role, error)
+}
+
+// groupRepository 用户分组存储库实现
+type groupRepository struct {
+	db *gorm.DB
+}
+
+// NewGroupRepository 创建用户分组存储库实例
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+// Create 创建分组，上级分组必须存在
+func (r *groupRepository) Create(group *model.Group) error {
+	if group.ParentID != nil {
+		if err := r.db.First(&model.Group{}, *group.ParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGroupNotFound
+			}
+			return err
+		}
+	}
+	return r.db.Omit(clause.Associations).Create(group).Error
+}
+
+// GetByID 根据ID获取分组及其上级分组和角色
+func (r *groupRepository) GetByID(id uint) (*model.Group, error) {
+	var group model.Group
+	err := r.db.Preload("Parent").Preload("Roles").First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByName 根据名称获取分组
+func (r *groupRepository) GetByName(name string) (*model.Group, error) {
+	var group model.Group
+	err := r.db.Where("name = ?", name).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Update 更新分组，修改上级分组时形成环返回 ErrGroupCycle，成员因此失去最后一个管理员角色时返回 ErrLastAdmin，
+// 违反静态职责分离约束时返回 *SeparationError
+func (r *groupRepository) Update(group *model.Group) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			return saveGroup(tx, group)
+		})
+	})
+}
+
+// saveGroup 在事务中检查嵌套关系并保存分组，上级分组改变时检查分组及其下级分组的成员是否违反静态职责分离约束
+func saveGroup(tx *gorm.DB, group *model.Group) error {
+	// 锁定全部分组，串行化嵌套关系的修改
+	var groups []model.Group
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "parent_id").Find(&groups).Error; err != nil {
+		return err
+	}
+	parents := make(map[uint]*uint, len(groups))
+	for _, g := range groups {
+		parents[g.ID] = g.ParentID
+	}
+
+	// 检查上级分组是否存在，以及沿上级分组能否回到自身
+	if group.ParentID != nil {
+		if _, ok := parents[*group.ParentID]; !ok {
+			return ErrGroupNotFound
+		}
+		for id := group.ParentID; id != nil; id = parents[*id] {
+			if *id == group.ID {
+				return ErrGroupCycle
+			}
+		}
+	}
+
+	group.Parent = nil
+	if err := tx.Omit(clause.Associations).Save(group).Error; err != nil {
+		return err
+	}
+
+	previous := parents[group.ID]
+	if previous == nil && group.ParentID == nil || previous != nil && group.ParentID != nil && *previous == *group.ParentID {
+		return nil
+	}
+	return checkGroupMembers(tx, group.ID)
+}
+
+// checkGroupMembers 检查分组及其下级分组的成员是否违反静态职责分离约束
+func checkGroupMembers(tx *gorm.DB, groupID uint) error {
+	groupIDs, err := groupDescendants(tx, []uint{groupID})
+	if err != nil {
+		return err
+	}
+	var userIDs []uint
+	if err := tx.Table("group_members").Where("group_id IN ?", groupIDs).Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	return checkAssignments(tx, userIDs)
+}
+
+// Delete 删除分组及其成员和角色关联，存在子分组时返回 ErrGroupHasChildren，成员因此失去最后一个管理员角色时返回 ErrLastAdmin
+func (r *groupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			group := model.Group{}
+			if err := tx.First(&group, id).Error; err != nil {
+				return err
+			}
+
+			// 检查子分组
+			var children int64
+			if err := tx.Model(&model.Group{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+				return err
+			}
+			if children > 0 {
+				return ErrGroupHasChildren
+			}
+
+			// 清除关联
+			for _, name := range []string{"Roles", "Members"} {
+				if err := tx.Model(&group).Association(name).Clear(); err != nil {
+					return err
+				}
+			}
+
+			return tx.Delete(&group).Error
+		})
+	})
+}
+
+// List 获取分组列表
+func (r *groupRepository) List(page, pageSize int) ([]model.Group, int64, error) {
+	var groups []model.Group
+	var total int64
+
+	// 计算总数
+	if err := r.db.Model(&model.Group{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := r.db.Preload("Roles").Order("id").Offset(offset).Limit(pageSize).Find(&groups).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return groups, total, nil
+}
+
+// ListMembers 获取分组的直接成员
+func (r *groupRepository) ListMembers(groupID uint) ([]model.User, error) {
+	group := model.Group{}
+	if err := r.db.First(&group, groupID).Error; err != nil {
+		return nil, err
+	}
+
+	var users []model.User
+	err := r.db.Model(&group).Order("id").Association("Members").Find(&users)
+	return users, err
+}
+
+// AddMembers 将用户加入分组，已是成员的用户会被忽略，用户因此违反静态职责分离约束时返回 *SeparationError
+func (r *groupRepository) AddMembers(groupID uint, userIDs []uint) error {
+	return r.updateMembers(groupID, userIDs, true, func(association *gorm.Association, users []model.User) error {
+		return association.Append(users)
+	})
+}
+
+// RemoveMembers 将用户移出分组
+func (r *groupRepository) RemoveMembers(groupID uint, userIDs []uint) error {
+	return r.updateMembers(groupID, userIDs, false, func(association *gorm.Association, users []model.User) error {
+		return association.Delete(users)
+	})
+}
+
+// updateMembers 在事务中校验分组与用户是否存在并修改成员关联，不允许移除通过分组获得管理员角色的最后一个管理员，
+// check 为 true 时检查用户修改后的角色是否违反静态职责分离约束
+func (r *groupRepository) updateMembers(groupID uint, userIDs []uint, check bool, apply func(association *gorm.Association, users []model.User) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			// 获取分组
+			group := model.Group{}
+			if err := tx.First(&group, groupID).Error; err != nil {
+				return err
+			}
+			if len(userIDs) == 0 {
+				return nil
+			}
+
+			// 获取用户
+			var users []model.User
+			if err := tx.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				return err
+			}
+			found := make(map[uint]bool, len(users))
+			for _, user := range users {
+				found[user.ID] = true
+			}
+			for _, id := range userIDs {
+				if !found[id] {
+					return fmt.Errorf("%w: ID %d", ErrUserNotFound, id)
+				}
+			}
+
+			if err := apply(tx.Model(&group).Association("Members"), users); err != nil {
+				return err
+			}
+
+			if !check {
+				return nil
+			}
+			return checkAssignments(tx, userIDs)
+		})
+	})
+}
+
+// SetRoles 将分组的角色替换为指定的全局角色，成员因此失去最后一个管理员角色时返回 ErrLastAdmin，
+// 分组及其下级分组的成员因此违反静态职责分离约束时返回 *SeparationError
+func (r *groupRepository) SetRoles(groupID uint, roleIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			// 获取分组
+			group := model.Group{}
+			if err := tx.First(&group, groupID).Error; err != nil {
+				return err
+			}
+
+			// 获取角色
+			roles, err := findRoles(tx, 0, roleIDs)
+			if err != nil {
+				return err
+			}
+
+			if len(roles) == 0 {
+				return tx.Model(&group).Association("Roles").Clear()
+			}
+			if err := tx.Model(&group).Association("Roles").Replace(roles); err != nil {
+				return err
+			}
+			return checkGroupMembers(tx, group.ID)
+		})
+	})
+}
+
+// groupMember 分组成员关联
+type groupMember struct {
+	GroupID uint
+	UserID  uint
+}
+
+// groupRole 分组角色关联
+type groupRole struct {
+	GroupID uint
+	RoleID  uint
+}
+
+// loadGroupRoles 为用户填充通过所在分组及其上级分组获得的角色，角色包含权限和上级角色
+func loadGroupRoles(db *gorm.DB, users []model.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	// 用户直接所在的分组
+	var members []groupMember
+	if err := db.Table("group_members").Where("user_id IN ?", userIDs).Find(&members).Error; err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	// 展开上级分组，visited 用于防止数据中存在环时无限循环
+	memberGroupIDs := make([]uint, len(members))
+	for i, member := range members {
+		memberGroupIDs[i] = member.GroupID
+	}
+	parents, err := groupParents(db, memberGroupIDs)
+	if err != nil {
+		return err
+	}
+	userGroups := make(map[uint]map[uint]bool)
+	for _, member := range members {
+		visited := userGroups[member.UserID]
+		if visited == nil {
+			visited = make(map[uint]bool)
+			userGroups[member.UserID] = visited
+		}
+		for id := &member.GroupID; id != nil && !visited[*id]; id = parents[*id] {
+			visited[*id] = true
+		}
+	}
+
+	// 分组的角色
+	groupIDs := make([]uint, 0, len(parents))
+	for id := range parents {
+		groupIDs = append(groupIDs, id)
+	}
+	grants, byID, err := findGroupRoles(db, groupIDs)
+	if err != nil {
+		return err
+	}
+
+	// 按用户汇总角色
+	for i := range users {
+		seen := make(map[uint]bool)
+		for _, grant := range grants {
+			role, ok := byID[grant.RoleID]
+			if !ok || seen[role.ID] || !userGroups[users[i].ID][grant.GroupID] {
+				continue
+			}
+			seen[role.ID] = true
+			users[i].GroupRoles = append(users[i].GroupRoles, role)
+		}
+	}
+	return nil
+}
+
+// groupParents 获取指定分组及其全部上级分组的上级分组ID，只查询沿上级关系可达的分组
+func groupParents(db *gorm.DB, groupIDs []uint) (map[uint]*uint, error) {
+	parents := make(map[uint]*uint)
+	frontier := groupIDs
+	for len(frontier) > 0 {
+		var groups []model.Group
+		if err := db.Select("id", "parent_id").Where("id IN ?", frontier).Find(&groups).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, group := range groups {
+			parents[group.ID] = group.ParentID
+		}
+		for _, group := range groups {
+			if group.ParentID == nil {
+				continue
+			}
+			if _, seen := parents[*group.ParentID]; !seen {
+				frontier = append(frontier, *group.ParentID)
+			}
+		}
+	}
+	return parents, nil
+}
+
+// groupDescendants 获取指定分组及其全部下级分组的ID
+func groupDescendants(db *gorm.DB, groupIDs []uint) ([]uint, error) {
+	seen := make(map[uint]bool)
+	var result []uint
+	frontier := groupIDs
+	for len(frontier) > 0 {
+		var next []uint
+		for _, id := range frontier {
+			if !seen[id] {
+				seen[id] = true
+				result = append(result, id)
+				next = append(next, id)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		frontier = nil
+		if err := db.Model(&model.Group{}).Where("parent_id IN ?", next).Pluck("id", &frontier).Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// EffectiveRoles 获取成员通过该分组获得的角色，即分组及其全部上级分组的角色，角色包含权限和上级角色
+func (r *groupRepository) EffectiveRoles(groupID uint) ([]model.Role, error) {
+	if err := r.db.First(&model.Group{}, groupID).Error; err != nil {
+		return nil, err
+	}
+
+	parents, err := groupParents(r.db, []uint{groupID})
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make([]uint, 0, len(parents))
+	for id := range parents {
+		groupIDs = append(groupIDs, id)
+	}
+	grants, byID, err := findGroupRoles(r.db, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []model.Role{}
+	seen := make(map[uint]bool)
+	for _, grant := range grants {
+		if role, ok := byID[grant.RoleID]; ok && !seen[role.ID] {
+			seen[role.ID] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// findGroupRoles 获取分组的角色关联及涉及的全局角色，角色包含权限和上级角色
+func findGroupRoles(db *gorm.DB, groupIDs []uint) ([]groupRole, map[uint]model.Role, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil, nil
+	}
+	var grants []groupRole
+	if err := db.Table("group_roles").Where("group_id IN ?", groupIDs).Find(&grants).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(grants) == 0 {
+		return nil, nil, nil
+	}
+	roleIDs := make([]uint, 0, len(grants))
+	for _, grant := range grants {
+		roleIDs = append(roleIDs, grant.RoleID)
+	}
+	var roles []model.Role
+	if err := scopeRoles(db, 0).Preload("Permissions").Preload("DeniedPermissions").Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := loadRoleParents(db, roles); err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint]model.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+	return grants, byID, nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository 组织存储库接口
+type OrganizationRepository interface {
+	Create(org *model.Organization) error
+	GetByID(id uint) (*model.Organization, error)
+	GetByName(name string) (*model.Organization, error)
+	Update(org *model.Organization) error
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.Organization, int64, error)
+	GetMembership(userID, orgID uint) (*model.Membership, error)
+	ListMemberships(userID uint) ([]model.Membership, error)
+	ListMembers(orgID uint) ([]model.Membership, error)
+	SetMember(orgID, userID uint, roleIDs []uint) error
+	RemoveMember(orgID, userID uint) error
+}
+
+// organizationRepository 组织存储库实现
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository 创建组织存储库实例
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create 创建组织，同时创建 org.Roles 中的组织角色
+func (r *organizationRepository) Create(org *model.Organization) error {
+	return r.db.Create(org).Error
+}
+
+// GetByID 根据ID获取组织
+func (r *organizationRepository) GetByID(id uint) (*model.Organization, error) {
+	var org model.Organization
+	err := r.db.First(&org, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetByName 根据名称获取组织
+func (r *organizationRepository) GetByName(name string) (*model.Organization, error) {
+	var org model.Organization
+	err := r.db.Where("name = ?", name).First(&org).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Update 更新组织
+func (r *organizationRepository) Update(org *model.Organization) error {
+	return r.db.Omit("Roles").Save(org).Error
+}
+
+// Delete 删除组织及其成员关系和组织角色
+func (r *organizationRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		org := model.Organization{}
+		if err := tx.First(&org, id).Error; err != nil {
+			return err
+		}
+
+		// 删除成员关系
+		if err := deleteMemberships(tx, tx.Model(&model.Membership{}).Select("id").Where("organization_id = ?", id)); err != nil {
+			return err
+		}
+
+		// 删除组织角色及其关联
+		roleIDs := tx.Model(&model.Role{}).Select("id").Where("organization_id = ?", id)
+		if err := tx.Table("role_permissions").Where("role_id IN (?)", roleIDs).Delete(&rolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("role_parents").Where("role_id IN (?) OR parent_id IN (?)", roleIDs, roleIDs).Delete(&roleParent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&model.Role{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&org).Error
+	})
+}
+
+// List 获取组织列表
+func (r *organizationRepository) List(page, pageSize int) ([]model.Organization, int64, error) {
+	var orgs []model.Organization
+	var total int64
+
+	// 计算总数
+	if err := r.db.Model(&model.Organization{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := r.db.Order("id").Offset(offset).Limit(pageSize).Find(&orgs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orgs, total, nil
+}
+
+// GetMembership 获取用户在组织中的成员关系及其角色、权限和上级角色
+func (r *organizationRepository) GetMembership(userID, orgID uint) (*model.Membership, error) {
+	var membership model.Membership
+	err := r.db.Preload("Roles.Permissions").Preload("Roles.DeniedPermissions").
+		Where("user_id = ? AND organization_id = ?", userID, orgID).
+		First(&membership).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRoleParents(r.db, membership.Roles); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// ListMemberships 获取用户加入的全部组织，按组织ID排序
+func (r *organizationRepository) ListMemberships(userID uint) ([]model.Membership, error) {
+	var memberships []model.Membership
+	err := r.db.Preload("Organization").Preload("Roles").
+		Where("user_id = ?", userID).
+		Order("organization_id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// ListMembers 获取组织的全部成员
+func (r *organizationRepository) ListMembers(orgID uint) ([]model.Membership, error) {
+	var memberships []model.Membership
+	err := r.db.Preload("User").Preload("Roles").
+		Joins("JOIN users ON users.id = memberships.user_id AND users.deleted_at IS NULL").
+		Where("memberships.organization_id = ?", orgID).
+		Order("memberships.user_id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// SetMember 将用户加入组织并设置其组织角色，已是成员时替换其角色，替换后违反静态职责分离约束时返回 *SeparationError
+func (r *organizationRepository) SetMember(orgID, userID uint, roleIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockAssignments(tx); err != nil {
+			return err
+		}
+
+		// 检查组织和用户是否存在
+		if err := tx.First(&model.Organization{}, orgID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&model.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		// 角色只能从该组织中选择
+		roles, err := findRoles(tx, orgID, roleIDs)
+		if err != nil {
+			return err
+		}
+
+		membership := model.Membership{}
+		err = tx.Where(model.Membership{UserID: userID, OrganizationID: orgID}).FirstOrCreate(&membership).Error
+		if err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return tx.Model(&membership).Association("Roles").Clear()
+		}
+		if err := tx.Model(&membership).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		return checkAssignments(tx, []uint{userID})
+	})
+}
+
+// RemoveMember 将用户移出组织
+func (r *organizationRepository) RemoveMember(orgID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		membership := model.Membership{}
+		if err := tx.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&membership).Error; err != nil {
+			return err
+		}
+		return deleteMemberships(tx, []uint{membership.ID})
+	})
+}
+
+// rolePermission 角色权限关联
+type rolePermission struct {
+	RoleID       uint
+	PermissionID uint
+}
+
+// deleteMemberships 删除成员关系及其组织角色关联，ids 可以是ID列表或子查询
+func deleteMemberships(tx *gorm.DB, ids interface{}) error {
+	if err := tx.Table("membership_roles").Where("membership_id IN (?)", ids).Delete(&membershipRole{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN (?)", ids).Delete(&model.Membership{}).Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"gorm.io/gorm"
+)
+
+// PasswordHistoryRepository 历史密码存储库接口
+type PasswordHistoryRepository interface {
+	Create(history *model.PasswordHistory) error
+	ListRecent(userID uint, limit int) ([]model.PasswordHistory, error)
+	Prune(userID uint, keep int) error
+}
+
+// passwordHistoryRepository 历史密码存储库实现
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository 创建历史密码存储库实例
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+// Create 记录历史密码
+func (r *passwordHistoryRepository) Create(history *model.PasswordHistory) error {
+	return r.db.Create(history).Error
+}
+
+// ListRecent 获取用户最近的历史密码
+func (r *passwordHistoryRepository) ListRecent(userID uint, limit int) ([]model.PasswordHistory, error) {
+	var histories []model.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+// Prune 只保留用户最近的keep条历史密码
+func (r *passwordHistoryRepository) Prune(userID uint, keep int) error {
+	// 查询需要保留的记录
+	var keepIDs []uint
+	err := r.db.Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(keep).
+		Pluck("id", &keepIDs).Error
+	if err != nil {
+		return err
+	}
+
+	// 删除其余记录
+	query := r.db.Where("user_id = ?", userID)
+	if len(keepIDs) > 0 {
+		query = query.Where("id NOT IN ?", keepIDs)
+	}
+	return query.Delete(&model.PasswordHistory{}).Error
+}
@@ -13,6 +13,7 @@ type PermissionRepository interface {
 	Update(permission *model.Permission) error
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.Permission, int64, error)
+	ListAll() ([]model.Permission, error)
 }
 
 // permissionRepository 权限存储库实现
@@ -55,9 +56,24 @@ func (r *permissionRepository) Update(permission *model.Permission) error {
 	return r.db.Save(permission).Error
 }
 
-// Delete 删除权限
+// Delete 删除权限及其角色关联（包括拒绝该权限的角色）
 func (r *permissionRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Permission{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		permission := model.Permission{}
+		if err := tx.First(&permission, id).Error; err != nil {
+			return err
+		}
+
+		// 清除角色关联
+		if err := tx.Model(&permission).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Table("role_denied_permissions").Where("permission_id = ?", id).Delete(&roleDeniedPermission{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&permission).Error
+	})
 }
 
 // List 获取权限列表
@@ -79,3 +95,10 @@ func (r *permissionRepository) List(page, pageSize int) ([]model.Permission, int
 
 	return permissions, total, nil
 }
+
+// ListAll 获取全部权限
+func (r *permissionRepository) ListAll() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("code").Find(&permissions).Error
+	return permissions, err
+}
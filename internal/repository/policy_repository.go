@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"gorm.io/gorm"
+)
+
+// PolicyRepository 访问控制策略存储库接口
+type PolicyRepository interface {
+	Create(policy *model.Policy) error
+	GetByID(id uint) (*model.Policy, error)
+	GetByName(name string) (*model.Policy, error)
+	Update(policy *model.Policy) error
+	Delete(id uint) error
+	ListAll() ([]model.Policy, error)
+}
+
+// policyRepository 访问控制策略存储库实现
+type policyRepository struct {
+	db *gorm.DB
+}
+
+// NewPolicyRepository 创建访问控制策略存储库实例
+func NewPolicyRepository(db *gorm.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+// Create 创建策略
+func (r *policyRepository) Create(policy *model.Policy) error {
+	return r.db.Create(policy).Error
+}
+
+// GetByID 根据ID获取策略
+func (r *policyRepository) GetByID(id uint) (*model.Policy, error) {
+	var policy model.Policy
+	err := r.db.First(&policy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetByName 根据名称获取策略
+func (r *policyRepository) GetByName(name string) (*model.Policy, error) {
+	var policy model.Policy
+	err := r.db.Where("name = ?", name).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Update 更新策略
+func (r *policyRepository) Update(policy *model.Policy) error {
+	return r.db.Save(policy).Error
+}
+
+// Delete 删除策略
+func (r *policyRepository) Delete(id uint) error {
+	result := r.db.Delete(&model.Policy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListAll 获取全部策略，按名称排序
+func (r *policyRepository) ListAll() ([]model.Policy, error) {
+	var policies []model.Policy
+	err := r.db.Order("name").Find(&policies).Error
+	return policies, err
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/relation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+)
+
+// relationRevisionID 全局版本号所在的行，由 InitDB 创建
+const relationRevisionID = 1
+
+// RelationTupleFilter 关系元组查询条件，零值字段不参与过滤
+type RelationTupleFilter struct {
+	Namespace        string
+	ObjectID         string
+	Relation         string
+	SubjectNamespace string
+	SubjectID        string
+	SubjectRelation  string
+}
+
+// RelationRepository 关系元组存储库接口
+type RelationRepository interface {
+	Write(writes, deletes []model.RelationTuple) (uint64, error)
+	CurrentRevision() (uint64, error)
+	Read(namespace, objectID, relation string, revision uint64) ([]model.RelationTuple, error)
+	ListObjectIDs(namespace string, revision uint64, after string, limit int) ([]string, error)
+	List(filter RelationTupleFilter, page, pageSize int) ([]model.RelationTuple, int64, error)
+}
+
+// relationRepository 关系元组存储库实现
+type relationRepository struct {
+	db *gorm.DB
+}
+
+// NewRelationRepository 创建关系元组存储库实例
+func NewRelationRepository(db *gorm.DB) RelationRepository {
+	return &relationRepository{db: db}
+}
+
+// Write 在一个事务中写入和删除元组，返回新的版本号。
+// 已存在的元组不会重复写入，不存在的元组忽略删除；写入按版本号行锁串行化，保证快照不会再变化。
+func (r *relationRepository) Write(writes, deletes []model.RelationTuple) (uint64, error) {
+	var revision uint64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if revision, err = nextRevision(tx); err != nil {
+			return err
+		}
+
+		// 标记删除
+		for _, tuple := range deletes {
+			err := liveTuple(tx, tuple).Model(&model.RelationTuple{}).Update("deleted_revision", revision).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// 写入不存在的元组
+		for _, tuple := range writes {
+			var count int64
+			if err := liveTuple(tx, tuple).Model(&model.RelationTuple{}).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			tuple.ID = 0
+			tuple.CreatedRevision = revision
+			tuple.DeletedRevision = 0
+			if err := tx.Create(&tuple).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// CurrentRevision 获取最新的版本号，尚未写入过元组时为0
+func (r *relationRepository) CurrentRevision() (uint64, error) {
+	var current model.RelationRevision
+	err := r.db.Where("id = ?", relationRevisionID).Limit(1).Find(&current).Error
+	if err != nil {
+		return 0, err
+	}
+	return current.Revision, nil
+}
+
+// Read 获取版本 revision 的快照中对象某个关系的全部元组
+func (r *relationRepository) Read(namespace, objectID, relation string, revision uint64) ([]model.RelationTuple, error) {
+	var tuples []model.RelationTuple
+	err := atRevision(r.db, revision).
+		Where("namespace = ? AND object_id = ? AND relation = ?", namespace, objectID, relation).
+		Order("id").Find(&tuples).Error
+	if err != nil {
+		return nil, err
+	}
+	return tuples, nil
+}
+
+// ListObjectIDs 按顺序获取版本 revision 的快照中命名空间内出现在元组里、排在 after 之后的至多 limit 个对象ID
+func (r *relationRepository) ListObjectIDs(namespace string, revision uint64, after string, limit int) ([]string, error) {
+	var ids []string
+	err := atRevision(r.db.Model(&model.RelationTuple{}), revision).
+		Where("namespace = ? AND object_id > ?", namespace, after).
+		Distinct("object_id").Order("object_id").Limit(limit).Pluck("object_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// List 按条件获取当前有效的元组
+func (r *relationRepository) List(filter RelationTupleFilter, page, pageSize int) ([]model.RelationTuple, int64, error) {
+	var tuples []model.RelationTuple
+	var total int64
+
+	query := r.db.Model(&model.RelationTuple{}).Where("deleted_revision = 0")
+	for column, value := range map[string]string{
+		"namespace":         filter.Namespace,
+		"object_id":         filter.ObjectID,
+		"relation":          filter.Relation,
+		"subject_namespace": filter.SubjectNamespace,
+		"subject_id":        filter.SubjectID,
+		"subject_relation":  filter.SubjectRelation,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Order("id").Offset(offset).Limit(pageSize).Find(&tuples).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return tuples, total, nil
+}
+
+// nextRevision 锁定并递增全局版本号，修改元组的事务按该行锁串行化
+func nextRevision(tx *gorm.DB) (uint64, error) {
+	var current model.RelationRevision
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, relationRevisionID).Error; err != nil {
+		return 0, err
+	}
+	revision := current.Revision + 1
+	if err := tx.Model(&current).Update("revision", revision).Error; err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// deleteUserTuples 在事务中删除以用户为主体或对象的全部当前有效元组，没有这样的元组时不产生新版本
+func deleteUserTuples(tx *gorm.DB, userID uint) error {
+	id := strconv.FormatUint(uint64(userID), 10)
+	userTuples := func() *gorm.DB {
+		return tx.Model(&model.RelationTuple{}).
+			Where("deleted_revision = 0").
+			Where("(subject_namespace = ? AND subject_id = ?) OR (namespace = ? AND object_id = ?)",
+				relation.UserNamespace, id, relation.UserNamespace, id)
+	}
+
+	var count int64
+	if err := userTuples().Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	revision, err := nextRevision(tx)
+	if err != nil {
+		return err
+	}
+	return userTuples().Update("deleted_revision", revision).Error
+}
+
+// atRevision 将查询限定为版本 revision 的快照中有效的元组
+func atRevision(db *gorm.DB, revision uint64) *gorm.DB {
+	return db.Where("created_revision <= ? AND (deleted_revision = 0 OR deleted_revision > ?)", revision, revision)
+}
+
+// liveTuple 查找当前有效的同一元组
+func liveTuple(db *gorm.DB, tuple model.RelationTuple) *gorm.DB {
+	return db.Where("namespace = ? AND object_id = ? AND relation = ? AND subject_namespace = ? AND subject_id = ? AND subject_relation = ? AND deleted_revision = 0",
+		tuple.Namespace, tuple.ObjectID, tuple.Relation, tuple.SubjectNamespace, tuple.SubjectID, tuple.SubjectRelation)
+}
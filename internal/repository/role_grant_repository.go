@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RoleGrantRepository 用户角色授予存储库接口，管理带有效期的全局角色
+type RoleGrantRepository interface {
+	Grant(grant *model.UserRole) error
+	ListByUser(userID uint) ([]model.UserRole, error)
+	ListExpiring(now, before time.Time) ([]model.UserRole, error)
+	MarkNotified(grants []model.UserRole, at time.Time) error
+	DeleteExpired(now time.Time) ([]model.UserRole, error)
+	ListUserIDsByRoles(roleIDs []uint, now time.Time) ([]uint, error)
+}
+
+// roleGrantRepository 用户角色授予存储库实现
+type roleGrantRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleGrantRepository 创建用户角色授予存储库实例
+func NewRoleGrantRepository(db *gorm.DB) RoleGrantRepository {
+	return &roleGrantRepository{db: db}
+}
+
+// Grant 授予用户全局角色，已拥有该角色时覆盖原有的有效期和授予人，授予后违反静态职责分离约束时返回 *SeparationError
+// 永久有效的管理员授予被替换为限时授予时同样受最后一个管理员的保护。
+func (r *roleGrantRepository) Grant(grant *model.UserRole) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			// 检查用户和角色是否存在
+			if err := tx.First(&model.User{}, grant.UserID).Error; err != nil {
+				return err
+			}
+			if _, err := findRoles(tx, 0, []uint{grant.RoleID}); err != nil {
+				return err
+			}
+
+			grant.ExpiryNotifiedAt = nil
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"valid_from", "valid_until", "granted_by", "expiry_notified_at"}),
+			}).Create(grant).Error
+			if err != nil {
+				return err
+			}
+			return checkAssignments(tx, []uint{grant.UserID})
+		})
+	})
+}
+
+// ListByUser 获取用户的全部角色授予，包括尚未生效的
+func (r *roleGrantRepository) ListByUser(userID uint) ([]model.UserRole, error) {
+	grants := []model.UserRole{}
+	err := r.db.Where("user_id = ?", userID).Order("role_id").Find(&grants).Error
+	return grants, err
+}
+
+// ListExpiring 获取在 before 之前到期、尚未到期且未发送过通知的授予
+func (r *roleGrantRepository) ListExpiring(now, before time.Time) ([]model.UserRole, error) {
+	var grants []model.UserRole
+	err := r.db.
+		Where("valid_until > ? AND valid_until <= ? AND expiry_notified_at IS NULL", now, before).
+		Order("valid_until").
+		Find(&grants).Error
+	return grants, err
+}
+
+// MarkNotified 记录已发送即将到期通知
+func (r *roleGrantRepository) MarkNotified(grants []model.UserRole, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, grant := range grants {
+			err := tx.Model(&model.UserRole{}).
+				Where("user_id = ? AND role_id = ?", grant.UserID, grant.RoleID).
+				UpdateColumn("expiry_notified_at", at).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteExpired 删除已到期的授予并返回被删除的记录
+func (r *roleGrantRepository) DeleteExpired(now time.Time) ([]model.UserRole, error) {
+	var grants []model.UserRole
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定到期的记录，避免与重新授予并发时误删新的有效期
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("valid_until <= ?", now).Find(&grants).Error; err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			return nil
+		}
+		return tx.Where("valid_until <= ?", now).Delete(&model.UserRole{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ListUserIDsByRoles 获取当前有效地直接拥有任一指定角色且未删除的用户ID
+func (r *roleGrantRepository) ListUserIDsByRoles(roleIDs []uint, now time.Time) ([]uint, error) {
+	userIDs := []uint{}
+	if len(roleIDs) == 0 {
+		return userIDs, nil
+	}
+
+	err := r.db.Table("user_roles").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.role_id IN ? AND users.deleted_at IS NULL AND users.active = ?", roleIDs, true).
+		Where("(user_roles.valid_from IS NULL OR user_roles.valid_from <= ?) AND (user_roles.valid_until IS NULL OR user_roles.valid_until > ?)", now, now).
+		Distinct().
+		Order("user_roles.user_id").
+		Pluck("user_roles.user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// loadRoleGrants 为用户填充限时授予，并从 Roles 中去掉当前不在有效期内的角色
+func loadRoleGrants(db *gorm.DB, users []model.User, now time.Time) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	var grants []model.UserRole
+	err := db.Where("user_id IN ? AND (valid_from IS NOT NULL OR valid_until IS NOT NULL)", userIDs).
+		Order("role_id").
+		Find(&grants).Error
+	if err != nil {
+		return err
+	}
+
+	type key struct{ userID, roleID uint }
+	inactive := make(map[key]bool)
+	byUser := make(map[uint][]model.UserRole)
+	for _, grant := range grants {
+		byUser[grant.UserID] = append(byUser[grant.UserID], grant)
+		if !grant.ActiveAt(now) {
+			inactive[key{grant.UserID, grant.RoleID}] = true
+		}
+	}
+
+	for i := range users {
+		users[i].RoleGrants = byUser[users[i].ID]
+		if len(inactive) == 0 {
+			continue
+		}
+		roles := users[i].Roles[:0]
+		for _, role := range users[i].Roles {
+			if !inactive[key{users[i].ID, role.ID}] {
+				roles = append(roles, role)
+			}
+		}
+		users[i].Roles = roles
+	}
+	return nil
+}
@@ -2,7 +2,16 @@ package repository
 
 import (
 	"authentication/internal/model"
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRoleCycle 角色继承关系形成环
+	ErrRoleCycle = errors.New("角色继承关系不能形成环")
+	// ErrAdminRoleProtected 系统管理员角色不能删除或重命名
+	ErrAdminRoleProtected = errors.New("不能删除或重命名系统管理员角色")
 )
 
 // RoleRepository 角色存储库接口
@@ -13,12 +22,19 @@ type RoleRepository interface {
 	Update(role *model.Role) error
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.Role, int64, error)
+	ListAll() ([]model.Role, error)
 	AssignPermissions(roleID uint, permissionIDs []uint) error
+	AssignDeniedPermissions(roleID uint, permissionIDs []uint) error
+	AddPermissions(roleID uint, permissionIDs []uint) error
+	SetParents(roleID uint, parentIDs []uint) error
+	ForOrganization(orgID uint) RoleRepository
 }
 
 // roleRepository 角色存储库实现
+// orgID 为0时只访问全局角色，否则只访问该组织的角色。
 type roleRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	orgID uint
 }
 
 // NewRoleRepository 创建角色存储库实例
@@ -26,29 +42,52 @@ func NewRoleRepository(db *gorm.DB) RoleRepository {
 	return &roleRepository{db: db}
 }
 
-// Create 创建角色
+// ForOrganization 返回只访问指定组织角色的存储库，orgID 为0时访问全局角色
+func (r *roleRepository) ForOrganization(orgID uint) RoleRepository {
+	return &roleRepository{db: r.db, orgID: orgID}
+}
+
+// scope 将查询限定在当前组织或全局角色范围内
+func (r *roleRepository) scope(db *gorm.DB) *gorm.DB {
+	return scopeRoles(db, r.orgID)
+}
+
+// scopeRoles 将角色查询限定在指定组织内，orgID 为0时限定为全局角色
+func scopeRoles(db *gorm.DB, orgID uint) *gorm.DB {
+	if orgID == 0 {
+		return db.Where("roles.organization_id IS NULL")
+	}
+	return db.Where("roles.organization_id = ?", orgID)
+}
+
+// Create 创建角色，按组织访问时角色属于该组织；不保存关联的权限、上级角色和用户
 func (r *roleRepository) Create(role *model.Role) error {
-	return r.db.Create(role).Error
+	role.OrganizationID = nil
+	if r.orgID != 0 {
+		orgID := r.orgID
+		role.OrganizationID = &orgID
+	}
+	return r.db.Omit(clause.Associations).Create(role).Error
 }
 
 // GetByID 根据ID获取角色
 func (r *roleRepository) GetByID(id uint) (*model.Role, error) {
 	var role model.Role
-	err := r.db.Preload("Permissions").First(&role, id).Error
+	err := r.scope(r.db).Preload("Permissions").Preload("DeniedPermissions").First(&role, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return &role, nil
+	return r.withParents(role)
 }
 
 // GetByName 根据名称获取角色
 func (r *roleRepository) GetByName(name string) (*model.Role, error) {
 	var role model.Role
-	err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	err := r.scope(r.db).Preload("Permissions").Preload("DeniedPermissions").Where("name = ?", name).First(&role).Error
 	if err != nil {
 		return nil, err
 	}
-	return &role, nil
+	return r.withParents(role)
 }
 
 // Update 更新角色
@@ -56,9 +95,42 @@ func (r *roleRepository) Update(role *model.Role) error {
 	return r.db.Save(role).Error
 }
 
-// Delete 删除角色
+// Delete 删除角色及其权限（包括拒绝的权限）、用户、成员、分组、职责分离约束、继承关联和提权申请，
+// 系统管理员角色不能删除，返回 ErrAdminRoleProtected
 func (r *roleRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Role{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		role := model.Role{}
+		if err := r.scope(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&role, id).Error; err != nil {
+			return err
+		}
+		if role.OrganizationID == nil && role.Name == model.AdminRoleName {
+			return ErrAdminRoleProtected
+		}
+
+		// 清除关联
+		for _, name := range []string{"Permissions", "DeniedPermissions", "Users", "Parents"} {
+			if err := tx.Model(&role).Association(name).Clear(); err != nil {
+				return err
+			}
+		}
+		if err := tx.Table("role_parents").Where("parent_id = ?", id).Delete(&roleParent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("membership_roles").Where("role_id = ?", id).Delete(&membershipRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("group_roles").Where("role_id = ?", id).Delete(&groupRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("separation_constraint_roles").Where("role_id = ?", id).Delete(&separationConstraintRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&model.ElevationRequest{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&role).Error
+	})
 }
 
 // List 获取角色列表
@@ -67,32 +139,62 @@ func (r *roleRepository) List(page, pageSize int) ([]model.Role, int64, error) {
 	var total int64
 
 	// 计算总数
-	if err := r.db.Model(&model.Role{}).Count(&total).Error; err != nil {
+	if err := r.scope(r.db.Model(&model.Role{})).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := r.db.Preload("Permissions").Offset(offset).Limit(pageSize).Find(&roles).Error
+	err := r.scope(r.db).Preload("Permissions").Preload("DeniedPermissions").Offset(offset).Limit(pageSize).Find(&roles).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
+	// 加载上级角色
+	if err := loadRoleParents(r.db, roles); err != nil {
+		return nil, 0, err
+	}
+
 	return roles, total, nil
 }
 
+// ListAll 获取全部角色及其上级角色
+func (r *roleRepository) ListAll() ([]model.Role, error) {
+	var roles []model.Role
+	if err := r.scope(r.db).Preload("Permissions").Preload("DeniedPermissions").Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	// 加载上级角色
+	if err := loadRoleParents(r.db, roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
 // AssignPermissions 分配权限到角色
 func (r *roleRepository) AssignPermissions(roleID uint, permissionIDs []uint) error {
+	return r.replacePermissions(roleID, "Permissions", permissionIDs)
+}
+
+// AssignDeniedPermissions 替换角色显式拒绝的权限
+func (r *roleRepository) AssignDeniedPermissions(roleID uint, permissionIDs []uint) error {
+	return r.replacePermissions(roleID, "DeniedPermissions", permissionIDs)
+}
+
+// replacePermissions 替换角色的允许或拒绝权限关联
+func (r *roleRepository) replacePermissions(roleID uint, association string, permissionIDs []uint) error {
 	// 开始事务
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		// 获取角色
 		role := model.Role{}
-		if err := tx.First(&role, roleID).Error; err != nil {
+		if err := r.scope(tx).First(&role, roleID).Error; err != nil {
 			return err
 		}
 
 		// 清除现有权限关联
-		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+		if err := tx.Model(&role).Association(association).Clear(); err != nil {
 			return err
 		}
 
@@ -107,6 +209,259 @@ func (r *roleRepository) AssignPermissions(roleID uint, permissionIDs []uint) er
 			return err
 		}
 
+		return tx.Model(&role).Association(association).Append(permissions)
+	})
+}
+
+// AddPermissions 为角色追加权限，已拥有的权限会被忽略
+func (r *roleRepository) AddPermissions(roleID uint, permissionIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 获取角色
+		role := model.Role{}
+		if err := r.scope(tx).First(&role, roleID).Error; err != nil {
+			return err
+		}
+
+		if len(permissionIDs) == 0 {
+			return nil
+		}
+
+		var permissions []model.Permission
+		if err := tx.Find(&permissions, permissionIDs).Error; err != nil {
+			return err
+		}
+
 		return tx.Model(&role).Association("Permissions").Append(permissions)
 	})
 }
+
+// SetParents 设置角色的上级角色，形成环时返回 ErrRoleCycle，拥有该角色或其下级角色的用户因此违反静态职责分离约束时返回 *SeparationError
+func (r *roleRepository) SetParents(roleID uint, parentIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockAssignments(tx); err != nil {
+			return err
+		}
+
+		// 锁定范围内的全部角色，串行化继承关系的修改，上级角色只能在同一范围内选择
+		var roles []model.Role
+		if err := r.scope(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Find(&roles).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]model.Role, len(roles))
+		for _, role := range roles {
+			byID[role.ID] = role
+		}
+
+		// 检查角色和上级角色是否存在
+		role, ok := byID[roleID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		parents := []model.Role{}
+		for _, id := range parentIDs {
+			parent, ok := byID[id]
+			if !ok {
+				return ErrRoleNotFound
+			}
+			parents = append(parents, parent)
+		}
+
+		// 检查新的继承关系是否形成环
+		edges, err := roleParentEdges(tx)
+		if err != nil {
+			return err
+		}
+		edges[roleID] = parentIDs
+		if reachable(edges, parentIDs, roleID) {
+			return ErrRoleCycle
+		}
+
+		if len(parents) == 0 {
+			return tx.Model(&role).Association("Parents").Clear()
+		}
+		if err := tx.Model(&role).Association("Parents").Replace(parents); err != nil {
+			return err
+		}
+
+		// 检查拥有该角色或其下级角色的用户
+		var inheriting []uint
+		for id := range byID {
+			if reachable(edges, []uint{id}, roleID) {
+				inheriting = append(inheriting, id)
+			}
+		}
+		userIDs, err := roleHolders(tx, inheriting)
+		if err != nil {
+			return err
+		}
+		return checkAssignments(tx, userIDs)
+	})
+}
+
+// roleHolders 获取直接、通过组织成员关系或分组（包括上级分组）拥有指定角色的用户ID
+func roleHolders(tx *gorm.DB, roleIDs []uint) ([]uint, error) {
+	var direct []uint
+	if err := tx.Table("user_roles").Where("role_id IN ?", roleIDs).Pluck("user_id", &direct).Error; err != nil {
+		return nil, err
+	}
+
+	var members []uint
+	err := tx.Table("membership_roles").Select("memberships.user_id").
+		Joins("JOIN memberships ON memberships.id = membership_roles.membership_id").
+		Where("membership_roles.role_id IN ?", roleIDs).
+		Pluck("memberships.user_id", &members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var grantedGroupIDs []uint
+	if err := tx.Table("group_roles").Where("role_id IN ?", roleIDs).Pluck("group_id", &grantedGroupIDs).Error; err != nil {
+		return nil, err
+	}
+	groupIDs, err := groupDescendants(tx, grantedGroupIDs)
+	if err != nil {
+		return nil, err
+	}
+	var groupMembers []uint
+	if len(groupIDs) > 0 {
+		if err := tx.Table("group_members").Where("group_id IN ?", groupIDs).Pluck("user_id", &groupMembers).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return append(append(direct, members...), groupMembers...), nil
+}
+
+// roleParent 角色继承关系
+type roleParent struct {
+	RoleID   uint
+	ParentID uint
+}
+
+// membershipRole 成员的组织角色关联
+type membershipRole struct {
+	MembershipID uint
+	RoleID       uint
+}
+
+// roleDeniedPermission 角色显式拒绝的权限
+type roleDeniedPermission struct {
+	RoleID       uint
+	PermissionID uint
+}
+
+// separationConstraintRole 职责分离约束包含的角色
+type separationConstraintRole struct {
+	SeparationConstraintID uint
+	RoleID                 uint
+}
+
+// roleParentEdges 获取全部继承关系，键为角色ID，值为其上级角色ID
+func roleParentEdges(db *gorm.DB) (map[uint][]uint, error) {
+	var rows []roleParent
+	if err := db.Table("role_parents").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	edges := make(map[uint][]uint)
+	for _, row := range rows {
+		edges[row.RoleID] = append(edges[row.RoleID], row.ParentID)
+	}
+	return edges, nil
+}
+
+// reachable 判断从 from 中的任一角色沿上级关系能否到达 target
+func reachable(edges map[uint][]uint, from []uint, target uint) bool {
+	visited := make(map[uint]bool)
+	queue := append([]uint{}, from...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == target {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		queue = append(queue, edges[id]...)
+	}
+	return false
+}
+
+// loadRoleParents 为角色递归填充上级角色及其权限
+func loadRoleParents(db *gorm.DB, roles []model.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	edges, err := roleParentEdges(db)
+	if err != nil {
+		return err
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	// 收集所有上级角色ID
+	var ancestorIDs []uint
+	seen := make(map[uint]bool)
+	var queue []uint
+	for _, role := range roles {
+		queue = append(queue, edges[role.ID]...)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ancestorIDs = append(ancestorIDs, id)
+		queue = append(queue, edges[id]...)
+	}
+	if len(ancestorIDs) == 0 {
+		return nil
+	}
+
+	// 加载上级角色及其权限
+	var ancestors []model.Role
+	if err := db.Preload("Permissions").Preload("DeniedPermissions").Find(&ancestors, ancestorIDs).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]model.Role, len(ancestors))
+	for _, role := range ancestors {
+		byID[role.ID] = role
+	}
+
+	// 构建继承树，path 用于防止数据中存在环时无限递归
+	var build func(id uint, path map[uint]bool) []model.Role
+	build = func(id uint, path map[uint]bool) []model.Role {
+		var parents []model.Role
+		for _, parentID := range edges[id] {
+			parent, ok := byID[parentID]
+			if !ok || path[parentID] {
+				continue
+			}
+			path[parentID] = true
+			parent.Parents = build(parentID, path)
+			delete(path, parentID)
+			parents = append(parents, parent)
+		}
+		return parents
+	}
+	for i := range roles {
+		roles[i].Parents = build(roles[i].ID, map[uint]bool{roles[i].ID: true})
+	}
+
+	return nil
+}
+
+// withParents 为单个角色加载上级角色
+func (r *roleRepository) withParents(role model.Role) (*model.Role, error) {
+	roles := []model.Role{role}
+	if err := loadRoleParents(r.db, roles); err != nil {
+		return nil, err
+	}
+	return &roles[0], nil
+}
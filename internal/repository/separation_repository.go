@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"authentication/internal/model"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrSeparationViolation 违反职责分离约束
+var ErrSeparationViolation = errors.New("违反职责分离约束")
+
+// SeparationViolation 违反的约束及涉及的角色，UserID 为修改角色分配时违反约束的用户
+type SeparationViolation struct {
+	UserID     uint     `json:"user_id,omitempty"`
+	Constraint string   `json:"constraint"`
+	Type       string   `json:"type"`
+	MaxRoles   int      `json:"max_roles"`
+	Roles      []string `json:"roles"`
+}
+
+// SeparationError 违反职责分离约束
+type SeparationError struct {
+	Violations []SeparationViolation
+}
+
+// Error 实现error接口
+func (e *SeparationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s（最多同时拥有%d个: %s）", v.Constraint, v.MaxRoles, strings.Join(v.Roles, "、")))
+	}
+	return ErrSeparationViolation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Unwrap 使 errors.Is(err, ErrSeparationViolation) 成立
+func (e *SeparationError) Unwrap() error {
+	return ErrSeparationViolation
+}
+
+// SeparationRepository 职责分离约束存储库接口
+type SeparationRepository interface {
+	Create(constraint *model.SeparationConstraint, roleIDs []uint) error
+	GetByID(id uint) (*model.SeparationConstraint, error)
+	GetByName(name string) (*model.SeparationConstraint, error)
+	Update(constraint *model.SeparationConstraint, roleIDs []uint) error
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.SeparationConstraint, int64, error)
+	Violations(roleIDs []uint, types ...string) ([]SeparationViolation, error)
+}
+
+// separationRepository 职责分离约束存储库实现
+type separationRepository struct {
+	db *gorm.DB
+}
+
+// NewSeparationRepository 创建职责分离约束存储库实例
+func NewSeparationRepository(db *gorm.DB) SeparationRepository {
+	return &separationRepository{db: db}
+}
+
+// Create 创建约束，任一角色不存在时返回 ErrRoleNotFound
+func (r *separationRepository) Create(constraint *model.SeparationConstraint, roleIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		roles, err := findAnyRoles(tx, roleIDs)
+		if err != nil {
+			return err
+		}
+		constraint.Roles = roles
+		return tx.Create(constraint).Error
+	})
+}
+
+// GetByID 根据ID获取约束及其角色
+func (r *separationRepository) GetByID(id uint) (*model.SeparationConstraint, error) {
+	var constraint model.SeparationConstraint
+	err := r.db.Preload("Roles").First(&constraint, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &constraint, nil
+}
+
+// GetByName 根据名称获取约束
+func (r *separationRepository) GetByName(name string) (*model.SeparationConstraint, error) {
+	var constraint model.SeparationConstraint
+	err := r.db.Where("name = ?", name).First(&constraint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &constraint, nil
+}
+
+// Update 更新约束并替换其角色
+func (r *separationRepository) Update(constraint *model.SeparationConstraint, roleIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		roles, err := findAnyRoles(tx, roleIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(constraint).Error; err != nil {
+			return err
+		}
+		constraint.Roles = roles
+		return tx.Model(constraint).Association("Roles").Replace(roles)
+	})
+}
+
+// Delete 删除约束及其角色关联
+func (r *separationRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		constraint := model.SeparationConstraint{}
+		if err := tx.First(&constraint, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&constraint).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&constraint).Error
+	})
+}
+
+// List 获取约束列表
+func (r *separationRepository) List(page, pageSize int) ([]model.SeparationConstraint, int64, error) {
+	var constraints []model.SeparationConstraint
+	var total int64
+
+	// 计算总数
+	if err := r.db.Model(&model.SeparationConstraint{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := r.db.Preload("Roles").Order("id").Offset(offset).Limit(pageSize).Find(&constraints).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return constraints, total, nil
+}
+
+// Violations 按指定类型的约束检查角色集合，返回违反的约束，角色按上级角色展开
+func (r *separationRepository) Violations(roleIDs []uint, types ...string) ([]SeparationViolation, error) {
+	return separationViolations(r.db, [][]uint{roleIDs}, types...)
+}
+
+// separationViolations 按指定类型的约束分别检查每组角色，返回违反的约束，同一约束只返回一次
+func separationViolations(db *gorm.DB, roleSets [][]uint, types ...string) ([]SeparationViolation, error) {
+	var constraints []model.SeparationConstraint
+	if err := db.Preload("Roles").Where("type IN ?", types).Order("id").Find(&constraints).Error; err != nil {
+		return nil, err
+	}
+	if len(constraints) == 0 {
+		return nil, nil
+	}
+
+	edges, err := roleParentEdges(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []SeparationViolation
+	reported := make(map[uint]bool)
+	for _, roleIDs := range roleSets {
+		held := expandRoles(edges, roleIDs)
+		for _, constraint := range constraints {
+			if reported[constraint.ID] {
+				continue
+			}
+			conflicts := constraint.Conflicts(held)
+			if conflicts == nil {
+				continue
+			}
+			reported[constraint.ID] = true
+			names := make([]string, len(conflicts))
+			for i, role := range conflicts {
+				names[i] = role.Name
+			}
+			violations = append(violations, SeparationViolation{
+				Constraint: constraint.Name,
+				Type:       constraint.Type,
+				MaxRoles:   constraint.MaxRoles,
+				Roles:      names,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// expandRoles 返回角色及其全部上级角色
+func expandRoles(edges map[uint][]uint, roleIDs []uint) map[uint]bool {
+	held := make(map[uint]bool)
+	queue := append([]uint{}, roleIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if held[id] {
+			continue
+		}
+		held[id] = true
+		queue = append(queue, edges[id]...)
+	}
+	return held
+}
+
+// checkAssignments 在事务中检查修改后用户分配的角色是否违反静态约束，违反时返回 *SeparationError 使事务回滚
+// 调用方需要先通过 lockAssignments 串行化角色分配的修改。
+func checkAssignments(tx *gorm.DB, userIDs []uint) error {
+	var violations []SeparationViolation
+	for _, userID := range uniqueIDs(userIDs) {
+		roleSets, err := assignedRoleSets(tx, userID)
+		if err != nil {
+			return err
+		}
+		found, err := separationViolations(tx, roleSets, model.SeparationStatic)
+		if err != nil {
+			return err
+		}
+		for _, violation := range found {
+			violation.UserID = userID
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) > 0 {
+		return &SeparationError{Violations: violations}
+	}
+	return nil
+}
+
+// organizationRole 成员在组织中的角色
+type organizationRole struct {
+	OrganizationID uint
+	RoleID         uint
+}
+
+// assignedRoleSets 获取用户在全局范围和所在的每个组织中分配的角色ID，
+// 全局范围包括直接角色、尚未到期的限时授予和分组角色，组织中另外包括该组织的角色
+func assignedRoleSets(tx *gorm.DB, userID uint) ([][]uint, error) {
+	var global []uint
+	if err := tx.Table("user_roles").Where("user_id = ? AND (valid_until IS NULL OR valid_until > ?)", userID, time.Now()).
+		Pluck("role_id", &global).Error; err != nil {
+		return nil, err
+	}
+
+	// 所在分组及其上级分组的角色
+	var memberGroupIDs []uint
+	if err := tx.Table("group_members").Where("user_id = ?", userID).Pluck("group_id", &memberGroupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(memberGroupIDs) > 0 {
+		parents, err := groupParents(tx, memberGroupIDs)
+		if err != nil {
+			return nil, err
+		}
+		groupIDs := make([]uint, 0, len(parents))
+		for id := range parents {
+			groupIDs = append(groupIDs, id)
+		}
+		var groupRoleIDs []uint
+		if err := tx.Table("group_roles").Where("group_id IN ?", groupIDs).Pluck("role_id", &groupRoleIDs).Error; err != nil {
+			return nil, err
+		}
+		global = append(global, groupRoleIDs...)
+	}
+
+	// 各组织的角色
+	var orgRoles []organizationRole
+	err := tx.Table("membership_roles").Select("memberships.organization_id, membership_roles.role_id").
+		Joins("JOIN memberships ON memberships.id = membership_roles.membership_id").
+		Where("memberships.user_id = ?", userID).
+		Scan(&orgRoles).Error
+	if err != nil {
+		return nil, err
+	}
+	byOrganization := make(map[uint][]uint)
+	var orgIDs []uint
+	for _, role := range orgRoles {
+		if _, ok := byOrganization[role.OrganizationID]; !ok {
+			orgIDs = append(orgIDs, role.OrganizationID)
+		}
+		byOrganization[role.OrganizationID] = append(byOrganization[role.OrganizationID], role.RoleID)
+	}
+	sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+
+	roleSets := [][]uint{global}
+	for _, orgID := range orgIDs {
+		roleSets = append(roleSets, append(append([]uint{}, global...), byOrganization[orgID]...))
+	}
+	return roleSets, nil
+}
+
+// findAnyRoles 根据ID获取角色（不限全局或组织），任一角色不存在时返回 ErrRoleNotFound
+func findAnyRoles(tx *gorm.DB, roleIDs []uint) ([]model.Role, error) {
+	roles := []model.Role{}
+	if len(roleIDs) == 0 {
+		return roles, nil
+	}
+
+	if err := tx.Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	if len(roles) != len(uniqueIDs(roleIDs)) {
+		found := make(map[uint]bool, len(roles))
+		for _, role := range roles {
+			found[role.ID] = true
+		}
+		for _, id := range roleIDs {
+			if !found[id] {
+				return nil, fmt.Errorf("%w: ID %d", ErrRoleNotFound, id)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// uniqueIDs 按出现顺序去除重复的ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
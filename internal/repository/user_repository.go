@@ -2,7 +2,22 @@ package repository
 
 import (
 	"authentication/internal/model"
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	// ErrRoleNotFound 要分配的角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+	// ErrLastAdmin 操作会导致系统中没有管理员
+	ErrLastAdmin = errors.New("不能移除最后一个管理员")
+	// ErrUserNotDeleted 用户未被删除
+	ErrUserNotDeleted = errors.New("用户未被删除")
+	// ErrUserConflict 用户名或邮箱已被其他用户使用
+	ErrUserConflict = errors.New("用户名或邮箱已被其他用户使用")
 )
 
 // UserRepository 用户存储库接口
@@ -12,13 +27,27 @@ type UserRepository interface {
 	GetByUsername(username string) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
 	Update(user *model.User) error
-	Delete(id uint) error
+	UpdatePassword(id uint, hashedPassword string) error
+	UpdateProfile(user *model.User) error
+	Delete(id uint, deletedBy uint, reason string) error
+	Restore(id uint) error
+	Purge(id uint) error
 	List(page, pageSize int) ([]model.User, int64, error)
+	ListDeleted(page, pageSize int) ([]model.User, int64, error)
+	SetRoles(userID uint, roleIDs []uint) error
+	AddRoles(userID uint, roleIDs []uint) error
+	RemoveRoles(userID uint, roleIDs []uint) error
+	RevokeTokens(ids []uint) (map[uint]uint, error)
+	GetTokenVersion(id uint) (uint, error)
+	IsShared(id uint) (bool, error)
+	ForOrganization(orgID uint) UserRepository
 }
 
 // userRepository 用户存储库实现
+// orgID 不为0时只能访问该组织的成员，角色操作作用于成员在该组织中的角色。
 type userRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	orgID uint
 }
 
 // NewUserRepository 创建用户存储库实例
@@ -26,49 +55,289 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-// Create 创建用户
+// ForOrganization 返回只访问指定组织成员的存储库，orgID 为0时不限制
+func (r *userRepository) ForOrganization(orgID uint) UserRepository {
+	return &userRepository{db: r.db, orgID: orgID}
+}
+
+// scope 将用户查询限定为当前组织的成员
+func (r *userRepository) scope(db *gorm.DB) *gorm.DB {
+	if r.orgID == 0 {
+		return db
+	}
+	members := r.db.Model(&model.Membership{}).Select("user_id").Where("organization_id = ?", r.orgID)
+	return db.Where("users.id IN (?)", members)
+}
+
+// Create 创建用户，按组织访问时同时将用户加入该组织，用户的角色作为其组织角色
 func (r *userRepository) Create(user *model.User) error {
-	return r.db.Create(user).Error
+	if r.orgID == 0 {
+		return r.db.Create(user).Error
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		roles := user.Roles
+		user.Roles = nil
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		membership := model.Membership{UserID: user.ID, OrganizationID: r.orgID}
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+		return tx.Model(&membership).Association("Roles").Append(roles)
+	})
 }
 
 // GetByID 根据ID获取用户
 func (r *userRepository) GetByID(id uint) (*model.User, error) {
 	var user model.User
-	err := r.db.Preload("Roles.Permissions").First(&user, id).Error
+	err := r.scope(r.db).Preload("Roles.Permissions").Preload("Roles.DeniedPermissions").First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	users := []model.User{user}
+	if err := loadRoleGrants(r.db, users, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := loadRoleParents(r.db, users[0].Roles); err != nil {
+		return nil, err
+	}
+	if err := r.loadOrganizationRoles(users); err != nil {
+		return nil, err
+	}
+	if err := loadGroupRoles(r.db, users); err != nil {
+		return nil, err
+	}
+	return &users[0], nil
 }
 
-// GetByUsername 根据用户名获取用户
+// GetByUsername 根据用户名获取用户，用户名全局唯一，不受组织范围限制
 func (r *userRepository) GetByUsername(username string) (*model.User, error) {
 	var user model.User
-	err := r.db.Preload("Roles.Permissions").Where("username = ?", username).First(&user).Error
+	err := r.db.Preload("Roles.Permissions").Preload("Roles.DeniedPermissions").Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	users := []model.User{user}
+	if err := loadRoleGrants(r.db, users, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := loadRoleParents(r.db, users[0].Roles); err != nil {
+		return nil, err
+	}
+	if err := loadGroupRoles(r.db, users); err != nil {
+		return nil, err
+	}
+	return &users[0], nil
 }
 
-// GetByEmail 根据邮箱获取用户
+// GetByEmail 根据邮箱获取用户，邮箱全局唯一，不受组织范围限制
 func (r *userRepository) GetByEmail(email string) (*model.User, error) {
 	var user model.User
-	err := r.db.Preload("Roles.Permissions").Where("email = ?", email).First(&user).Error
+	err := r.db.Preload("Roles.Permissions").Preload("Roles.DeniedPermissions").Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	users := []model.User{user}
+	if err := loadRoleGrants(r.db, users, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := loadRoleParents(r.db, users[0].Roles); err != nil {
+		return nil, err
+	}
+	if err := loadGroupRoles(r.db, users); err != nil {
+		return nil, err
+	}
+	return &users[0], nil
 }
 
-// Update 更新用户
+// Update 更新用户，停用最后一个管理员时返回 ErrLastAdmin
 func (r *userRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			return tx.Save(user).Error
+		})
+	})
+}
+
+// UpdatePassword 仅更新用户的密码哈希
+func (r *userRepository) UpdatePassword(id uint, hashedPassword string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).UpdateColumn("password", hashedPassword).Error
 }
 
-// Delete 删除用户
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&model.User{}, id).Error
+// UpdateProfile 仅更新用户可自行修改的资料字段及邮箱验证状态
+func (r *userRepository) UpdateProfile(user *model.User) error {
+	return r.db.Model(user).Select("full_name", "email", "email_verified", "verified_at").Updates(user).Error
+}
+
+// GetTokenVersion 获取用户当前的令牌版本，用户不存在或已删除时返回 gorm.ErrRecordNotFound
+func (r *userRepository) GetTokenVersion(id uint) (uint, error) {
+	var user model.User
+	err := r.db.Select("id", "token_version").First(&user, id).Error
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+// RevokeTokens 递增用户的令牌版本使已签发的令牌失效，返回各用户新的令牌版本
+func (r *userRepository) RevokeTokens(ids []uint) (map[uint]uint, error) {
+	versions := make(map[uint]uint, len(ids))
+	if len(ids) == 0 {
+		return versions, nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.User{}).Where("id IN ?", ids).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		var users []model.User
+		if err := tx.Unscoped().Select("id", "token_version").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			versions[user.ID] = user.TokenVersion
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Delete 软删除用户并记录操作人及原因，角色关联保留以便恢复，不允许删除最后一个管理员
+func (r *userRepository) Delete(id uint, deletedBy uint, reason string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			user := model.User{}
+			if err := r.scope(tx).First(&user, id).Error; err != nil {
+				return err
+			}
+
+			// 记录停用信息
+			user.Deactivate(deletedBy, reason)
+			if err := tx.Model(&user).Select("active", "deactivated_at", "deactivated_by", "deactivation_reason").Updates(&user).Error; err != nil {
+				return err
+			}
+
+			return tx.Delete(&user).Error
+		})
+	})
+}
+
+// Restore 恢复已删除的用户，用户名或邮箱已被其他用户使用时返回 ErrUserConflict
+func (r *userRepository) Restore(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		user := model.User{}
+		if err := r.scope(tx.Unscoped()).First(&user, id).Error; err != nil {
+			return err
+		}
+		if !user.DeletedAt.Valid {
+			return ErrUserNotDeleted
+		}
+
+		// 检查用户名和邮箱是否已被占用
+		var count int64
+		err := tx.Model(&model.User{}).
+			Where("(username = ? OR email = ?) AND id <> ?", user.Username, user.Email, user.ID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserConflict
+		}
+
+		// 恢复并重新启用
+		user.Reactivate()
+		user.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&user).
+			Select("active", "deactivated_at", "deactivated_by", "deactivation_reason", "deleted_at").
+			Updates(&user).Error
+	})
+}
+
+// Purge 彻底删除已软删除的用户及其关联数据
+func (r *userRepository) Purge(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		user := model.User{}
+		if err := r.scope(tx.Unscoped()).First(&user, id).Error; err != nil {
+			return err
+		}
+		if !user.DeletedAt.Valid {
+			return ErrUserNotDeleted
+		}
+
+		// 清除角色关联、分组成员及组织成员关系
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Table("group_members").Where("user_id = ?", user.ID).Delete(&groupMember{}).Error; err != nil {
+			return err
+		}
+		if err := deleteMemberships(tx, tx.Model(&model.Membership{}).Select("id").Where("user_id = ?", user.ID)); err != nil {
+			return err
+		}
+
+		// 删除以用户为主体或对象的关系元组，用户ID可能被重新使用，不能让新用户继承这些关系
+		if err := deleteUserTuples(tx, user.ID); err != nil {
+			return err
+		}
+
+		// 删除密码历史、邮箱验证记录和提权申请，申请的审批过程保留在审计日志中
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.ElevationRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.EmailVerification{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&user).Error
+	})
+}
+
+// IsShared 按组织访问时判断用户（包括已软删除的用户）是否拥有全局角色（包括限时授予）、属于分组或属于其他组织，
+// 此类用户的账号不只属于当前组织；用户不是当前组织的成员时返回 gorm.ErrRecordNotFound，不按组织访问时始终返回 false
+func (r *userRepository) IsShared(id uint) (bool, error) {
+	if r.orgID == 0 {
+		return false, nil
+	}
+
+	var members int64
+	if err := r.db.Model(&model.Membership{}).Where("user_id = ? AND organization_id = ?", id, r.orgID).Count(&members).Error; err != nil {
+		return false, err
+	}
+	if members == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+
+	queries := []*gorm.DB{
+		r.db.Table("user_roles").Where("user_id = ?", id),
+		r.db.Table("group_members").Where("user_id = ?", id),
+		r.db.Model(&model.Membership{}).Where("user_id = ? AND organization_id <> ?", id, r.orgID),
+	}
+	for _, query := range queries {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // List 获取用户列表
@@ -77,16 +346,239 @@ func (r *userRepository) List(page, pageSize int) ([]model.User, int64, error) {
 	var total int64
 
 	// 计算总数
-	if err := r.db.Model(&model.User{}).Count(&total).Error; err != nil {
+	if err := r.scope(r.db.Model(&model.User{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := r.scope(r.db).Preload("Roles").Offset(offset).Limit(pageSize).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := loadRoleGrants(r.db, users, time.Now()); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadOrganizationRoles(users); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// ListDeleted 获取已删除的用户列表
+func (r *userRepository) ListDeleted(page, pageSize int) ([]model.User, int64, error) {
+	var users []model.User
+	var total int64
+
+	query := r.scope(r.db.Unscoped().Model(&model.User{})).Where("deleted_at IS NOT NULL")
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := r.db.Preload("Roles").Offset(offset).Limit(pageSize).Find(&users).Error
+	err := query.Preload("Roles").Order("deleted_at DESC").Offset(offset).Limit(pageSize).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
 }
+
+// SetRoles 将用户的角色替换为指定角色，替换后违反静态职责分离约束时返回 *SeparationError
+func (r *userRepository) SetRoles(userID uint, roleIDs []uint) error {
+	return r.updateRoles(userID, roleIDs, true, func(association *gorm.Association, roles []model.Role) error {
+		return association.Replace(roles)
+	})
+}
+
+// AddRoles 为用户追加角色，已拥有的角色会被忽略，追加后违反静态职责分离约束时返回 *SeparationError
+func (r *userRepository) AddRoles(userID uint, roleIDs []uint) error {
+	return r.updateRoles(userID, roleIDs, true, func(association *gorm.Association, roles []model.Role) error {
+		if len(roles) == 0 {
+			return nil
+		}
+		return association.Append(roles)
+	})
+}
+
+// RemoveRoles 移除用户的指定角色
+func (r *userRepository) RemoveRoles(userID uint, roleIDs []uint) error {
+	return r.updateRoles(userID, roleIDs, false, func(association *gorm.Association, roles []model.Role) error {
+		if len(roles) == 0 {
+			return nil
+		}
+		return association.Delete(roles)
+	})
+}
+
+// updateRoles 在事务中校验用户与角色是否存在并修改角色关联，check 为 true 时检查修改后的角色是否违反静态职责分离约束
+// 按组织访问时修改的是用户在该组织中的角色，角色也只能从该组织中选择。
+func (r *userRepository) updateRoles(userID uint, roleIDs []uint, check bool, apply func(association *gorm.Association, roles []model.Role) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return guardLastAdmin(tx, func() error {
+			// 获取用户
+			user := model.User{}
+			if err := r.scope(tx).First(&user, userID).Error; err != nil {
+				return err
+			}
+
+			// 获取角色
+			roles, err := findRoles(tx, r.orgID, roleIDs)
+			if err != nil {
+				return err
+			}
+
+			association := tx.Model(&user).Association("Roles")
+			if r.orgID != 0 {
+				membership := model.Membership{}
+				if err := tx.Where("user_id = ? AND organization_id = ?", user.ID, r.orgID).First(&membership).Error; err != nil {
+					return err
+				}
+				association = tx.Model(&membership).Association("Roles")
+			}
+			if err := apply(association, roles); err != nil {
+				return err
+			}
+
+			if !check {
+				return nil
+			}
+			return checkAssignments(tx, []uint{user.ID})
+		})
+	})
+}
+
+// loadOrganizationRoles 按组织访问时为用户填充其在该组织中的角色
+func (r *userRepository) loadOrganizationRoles(users []model.User) error {
+	if r.orgID == 0 || len(users) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	var memberships []model.Membership
+	err := r.db.Preload("Roles.Permissions").Preload("Roles.DeniedPermissions").
+		Where("organization_id = ? AND user_id IN ?", r.orgID, userIDs).
+		Find(&memberships).Error
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[uint][]model.Role, len(memberships))
+	for _, membership := range memberships {
+		if err := loadRoleParents(r.db, membership.Roles); err != nil {
+			return err
+		}
+		byUser[membership.UserID] = membership.Roles
+	}
+	for i := range users {
+		users[i].OrganizationRoles = byUser[users[i].ID]
+		if users[i].OrganizationRoles == nil {
+			users[i].OrganizationRoles = []model.Role{}
+		}
+	}
+	return nil
+}
+
+// findRoles 根据ID获取指定组织（orgID 为0时为全局）的角色，任一角色不存在时返回 ErrRoleNotFound
+func findRoles(tx *gorm.DB, orgID uint, roleIDs []uint) ([]model.Role, error) {
+	roles := []model.Role{}
+	if len(roleIDs) == 0 {
+		return roles, nil
+	}
+
+	if err := scopeRoles(tx, orgID).Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint]struct{}, len(roles))
+	for _, role := range roles {
+		found[role.ID] = struct{}{}
+	}
+	for _, id := range roleIDs {
+		if _, ok := found[id]; !ok {
+			return nil, fmt.Errorf("%w: ID %d", ErrRoleNotFound, id)
+		}
+	}
+
+	return roles, nil
+}
+
+// lockAssignments 锁定管理员角色行，以串行化并发的角色分配修改，返回管理员角色，不存在时返回 nil
+func lockAssignments(tx *gorm.DB) (*model.Role, error) {
+	var adminRoles []model.Role
+	if err := scopeRoles(tx, 0).Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", model.AdminRoleName).Find(&adminRoles).Error; err != nil {
+		return nil, err
+	}
+	if len(adminRoles) == 0 {
+		return nil, nil
+	}
+	return &adminRoles[0], nil
+}
+
+// guardLastAdmin 执行可能减少管理员数量的操作，操作后没有管理员时返回 ErrLastAdmin 使事务回滚
+// 操作在 lockAssignments 的锁内执行，与其他角色分配的修改串行化。
+func guardLastAdmin(tx *gorm.DB, fn func() error) error {
+	adminRole, err := lockAssignments(tx)
+	if err != nil {
+		return err
+	}
+	if adminRole == nil {
+		return fn()
+	}
+
+	before, err := countAdmins(tx, adminRole.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	after, err := countAdmins(tx, adminRole.ID)
+	if err != nil {
+		return err
+	}
+	if before > 0 && after == 0 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// countAdmins 统计拥有管理员角色且未删除、未停用的用户数，包括永久的直接授予和通过分组（包括上级分组）获得的管理员角色，
+// 限时授予和尚未生效的授予不计入
+func countAdmins(tx *gorm.DB, adminRoleID uint) (int64, error) {
+	direct := tx.Table("user_roles").Select("user_id").
+		Where("role_id = ? AND valid_until IS NULL AND (valid_from IS NULL OR valid_from <= ?)", adminRoleID, time.Now())
+
+	// 拥有管理员角色的分组及其下级分组的成员
+	var adminGroupIDs []uint
+	if err := tx.Table("group_roles").Where("role_id = ?", adminRoleID).Pluck("group_id", &adminGroupIDs).Error; err != nil {
+		return 0, err
+	}
+	groupIDs, err := groupDescendants(tx, adminGroupIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	query := tx.Model(&model.User{}).Where("users.active = ?", true)
+	if len(groupIDs) == 0 {
+		query = query.Where("users.id IN (?)", direct)
+	} else {
+		members := tx.Table("group_members").Select("user_id").Where("group_id IN ?", groupIDs)
+		query = query.Where("(users.id IN (?) OR users.id IN (?))", direct, members)
+	}
+
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
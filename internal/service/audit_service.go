@@ -0,0 +1,48 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"log"
+)
+
+// AuditLogFilter 审计日志查询条件
+type AuditLogFilter = repository.AuditLogFilter
+
+// AuditService 审计日志服务接口
+type AuditService interface {
+	Record(actorID uint, action, targetType string, targetID uint, detail model.Attributes)
+	List(filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error)
+}
+
+// auditService 审计日志服务实现
+type auditService struct {
+	auditRepo repository.AuditLogRepository
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService(auditRepo repository.AuditLogRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record 记录审计日志，actorID 为0时视为系统操作；写入失败只记录到应用日志，不影响业务操作
+func (s *auditService) Record(actorID uint, action, targetType string, targetID uint, detail model.Attributes) {
+	entry := model.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Detail:     detail,
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+
+	if err := s.auditRepo.Create(&entry); err != nil {
+		log.Printf("写入审计日志失败 action=%s target=%s:%d: %v", action, targetType, targetID, err)
+	}
+}
+
+// List 获取审计日志
+func (s *auditService) List(filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	return s.auditRepo.List(filter, page, pageSize)
+}
@@ -4,19 +4,36 @@ import (
 	"authentication/internal/config"
 	"authentication/internal/model"
 	"authentication/internal/repository"
+	"authentication/pkg/auth"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrNotMember 用户不是该组织的成员
+	ErrNotMember = errors.New("不是该组织的成员")
+	// ErrTokenRevoked 令牌已被撤销
+	ErrTokenRevoked = errors.New("令牌已被撤销")
+	// ErrRoleNotHeld 要激活的角色不属于用户
+	ErrRoleNotHeld = errors.New("未拥有要激活的角色")
+)
+
+// defaultTokenVersionCacheSize 默认最多缓存的令牌版本数
+const defaultTokenVersionCacheSize = 10000
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 }
 
@@ -24,6 +41,17 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// OrganizationID 登录后所在的组织，未指定时进入用户加入的第一个组织
+	OrganizationID uint `json:"organization_id"`
+	// RoleIDs 本次会话激活的角色，为空时激活全部角色；存在动态职责分离约束时用于选择不冲突的角色
+	RoleIDs  []uint `json:"role_ids"`
+	ClientIP string `json:"-"` // 由处理器填充，用于按IP统计失败次数
+}
+
+// SwitchOrganizationRequest 切换组织请求，组织ID为0时切换到全局范围
+type SwitchOrganizationRequest struct {
+	OrganizationID uint   `json:"organization_id"`
+	RoleIDs        []uint `json:"role_ids"` // 切换后激活的角色，为空时激活全部角色
 }
 
 // RefreshTokenRequest 刷新令牌请求
@@ -31,6 +59,19 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// UpdateProfileRequest 修改个人资料请求，未提供的字段保持不变
+type UpdateProfileRequest struct {
+	FullName        *string `json:"full_name" binding:"omitempty,max=100"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"` // 修改邮箱时必须提供
+}
+
 // AuthService 认证服务接口
 type AuthService interface {
 	Register(req RegisterRequest) error
@@ -38,19 +79,45 @@ type AuthService interface {
 	ValidateToken(token string) (*model.TokenClaims, error)
 	RefreshToken(req RefreshTokenRequest) (*model.TokenPair, error)
 	GetUserByID(id uint) (*model.User, error)
+	ChangePassword(userID uint, req ChangePasswordRequest) error
+	UpdateProfile(userID uint, req UpdateProfileRequest) (*model.User, error)
+	SwitchOrganization(userID uint, req SwitchOrganizationRequest) (*model.TokenPair, error)
+	ListOrganizations(userID uint) ([]model.Membership, error)
+	RevokeSessions(userIDs []uint) error
+	UserPermissions(user *model.User, orgID uint, activeRoleIDs []uint) (auth.PermissionSet, error)
 }
 
 // authService 认证服务实现
 type authService struct {
-	userRepo  repository.UserRepository
-	jwtConfig config.JWTConfig
+	userRepo            repository.UserRepository
+	orgRepo             repository.OrganizationRepository
+	jwtConfig           config.JWTConfig
+	passwordService     PasswordService
+	verificationService VerificationService
+	loginProtector      LoginProtector
+	separationService   SeparationService
+
+	dummyHashOnce sync.Once
+	dummyHash     string
+
+	// tokenVersions 用户当前令牌版本的短时缓存，本实例撤销令牌时立即更新
+	tokenVersions *lruCache
 }
 
 // NewAuthService 创建认证服务实例
-func NewAuthService(userRepo repository.UserRepository, jwtConfig config.JWTConfig) AuthService {
+func NewAuthService(userRepo repository.UserRepository, orgRepo repository.OrganizationRepository, jwtConfig config.JWTConfig, passwordService PasswordService, verificationService VerificationService, loginProtector LoginProtector, separationService SeparationService) AuthService {
+	if jwtConfig.RevocationCacheSize <= 0 {
+		jwtConfig.RevocationCacheSize = defaultTokenVersionCacheSize
+	}
 	return &authService{
-		userRepo:  userRepo,
-		jwtConfig: jwtConfig,
+		userRepo:            userRepo,
+		orgRepo:             orgRepo,
+		jwtConfig:           jwtConfig,
+		passwordService:     passwordService,
+		verificationService: verificationService,
+		loginProtector:      loginProtector,
+		separationService:   separationService,
+		tokenVersions:       newLRUCache(time.Duration(jwtConfig.RevocationCheckTTL)*time.Second, jwtConfig.RevocationCacheSize),
 	}
 }
 
@@ -72,13 +139,22 @@ func (s *authService) Register(req RegisterRequest) error {
 		return fmt.Errorf("检查邮箱失败: %w", err)
 	}
 
+	// 校验密码策略
+	if err := s.passwordService.Validate(req.Password, req.Username, req.Email); err != nil {
+		return err
+	}
+
 	// 创建用户
+	now := time.Now()
 	user := model.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: req.Password, // 会在BeforeSave钩子中自动加密
-		FullName: req.FullName,
-		Active:   true,
+		Username:          req.Username,
+		Email:             req.Email,
+		FullName:          req.FullName,
+		Active:            true,
+		PasswordChangedAt: &now,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		return fmt.Errorf("加密密码失败: %w", err)
 	}
 
 	// 保存用户
@@ -86,40 +162,69 @@ func (s *authService) Register(req RegisterRequest) error {
 		return fmt.Errorf("创建用户失败: %w", err)
 	}
 
+	// 发送验证邮件，失败时用户可以稍后重新发送
+	if err := s.verificationService.SendVerification(&user); err != nil {
+		log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+	}
+
 	return nil
 }
 
 // Login 用户登录
 func (s *authService) Login(req LoginRequest) (*model.TokenPair, error) {
+	// 检查是否因失败次数过多被限制
+	if err := s.loginProtector.Check(req.Username, req.ClientIP); err != nil {
+		return nil, err
+	}
+
 	// 获取用户
 	user, err := s.userRepo.GetByUsername(req.Username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 对不存在的用户同样执行一次密码校验，使响应时间与密码错误时一致
+			s.verifyDummyPassword(req.Password)
+			s.loginProtector.RecordFailure(req.Username, req.ClientIP)
 			return nil, errors.New("用户名或密码错误")
 		}
 		return nil, fmt.Errorf("获取用户失败: %w", err)
 	}
 
-	// 检查用户是否激活
+	// 验证密码
+	ok, needsRehash := user.VerifyPassword(req.Password)
+	if !ok {
+		s.loginProtector.RecordFailure(req.Username, req.ClientIP)
+		return nil, errors.New("用户名或密码错误")
+	}
+	s.loginProtector.RecordSuccess(req.Username, req.ClientIP)
+
+	// 检查用户是否激活（在密码校验之后，避免泄露账户状态）
 	if !user.Active {
 		return nil, errors.New("用户已被禁用")
 	}
 
-	// 验证密码
-	if !user.CheckPassword(req.Password) {
-		return nil, errors.New("用户名或密码错误")
+	// 哈希算法或参数已过时，使用当前算法重新计算
+	if needsRehash {
+		if err := user.SetPassword(req.Password); err == nil {
+			if err := s.userRepo.UpdatePassword(user.ID, user.Password); err != nil {
+				log.Printf("更新用户 %d 的密码哈希失败: %v", user.ID, err)
+			}
+		}
 	}
 
-	// 获取用户权限
-	var permissions []string
-	for _, role := range user.Roles {
-		for _, perm := range role.Permissions {
-			permissions = append(permissions, perm.Code)
-		}
+	// 确定登录后所在的组织
+	orgID, err := s.resolveOrganization(user.ID, req.OrganizationID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 生成令牌对
-	tokenPair, err := s.generateTokenPair(user.ID, user.Username, permissions)
+	// 获取本次会话激活的角色的权限
+	permissions, err := s.UserPermissions(user, orgID, req.RoleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成令牌对，密码过期时令牌仅可用于修改密码
+	tokenPair, err := s.generateTokenPair(user, orgID, req.RoleIDs, permissions, s.passwordService.IsExpired(user))
 	if err != nil {
 		return nil, fmt.Errorf("生成令牌失败: %w", err)
 	}
@@ -140,6 +245,18 @@ func (s *authService) ValidateToken(tokenString string) (*model.TokenClaims, err
 		return nil, errors.New("无效的令牌类型")
 	}
 
+	// 检查令牌是否已被撤销，其他实例撤销的令牌最迟在令牌版本缓存过期后被拒绝
+	version, err := s.tokenVersion(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenRevoked
+		}
+		return nil, fmt.Errorf("获取令牌版本失败: %w", err)
+	}
+	if claims.TokenVersion != version {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
@@ -167,16 +284,47 @@ func (s *authService) RefreshToken(req RefreshTokenRequest) (*model.TokenPair, e
 		return nil, errors.New("用户已被禁用")
 	}
 
-	// 获取用户权限
-	var permissions []string
-	for _, role := range user.Roles {
-		for _, perm := range role.Permissions {
-			permissions = append(permissions, perm.Code)
-		}
+	// 检查令牌是否已被撤销
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, ErrTokenRevoked
+	}
+
+	// 获取用户权限，已被移出组织或激活的角色已被移除时无法刷新
+	permissions, err := s.UserPermissions(user, claims.OrganizationID, claims.ActiveRoleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成新令牌对
+	tokenPair, err := s.generateTokenPair(user, claims.OrganizationID, claims.ActiveRoleIDs, permissions, s.passwordService.IsExpired(user))
+	if err != nil {
+		return nil, fmt.Errorf("生成令牌失败: %w", err)
+	}
+
+	return tokenPair, nil
+}
+
+// SwitchOrganization 切换到用户加入的另一个组织或全局范围，签发新的令牌对
+func (s *authService) SwitchOrganization(userID uint, req SwitchOrganizationRequest) (*model.TokenPair, error) {
+	// 获取用户
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	// 检查用户是否激活
+	if !user.Active {
+		return nil, errors.New("用户已被禁用")
+	}
+
+	// 获取用户在目标组织中的权限
+	permissions, err := s.UserPermissions(user, req.OrganizationID, req.RoleIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	// 生成新令牌对
-	tokenPair, err := s.generateTokenPair(user.ID, user.Username, permissions)
+	tokenPair, err := s.generateTokenPair(user, req.OrganizationID, req.RoleIDs, permissions, s.passwordService.IsExpired(user))
 	if err != nil {
 		return nil, fmt.Errorf("生成令牌失败: %w", err)
 	}
@@ -184,25 +332,232 @@ func (s *authService) RefreshToken(req RefreshTokenRequest) (*model.TokenPair, e
 	return tokenPair, nil
 }
 
+// ListOrganizations 获取用户加入的组织及其在各组织中的角色
+func (s *authService) ListOrganizations(userID uint) ([]model.Membership, error) {
+	return s.orgRepo.ListMemberships(userID)
+}
+
+// RevokeSessions 撤销用户已签发的全部令牌，用户需要重新登录
+func (s *authService) RevokeSessions(userIDs []uint) error {
+	versions, err := s.userRepo.RevokeTokens(userIDs)
+	if err != nil {
+		return fmt.Errorf("撤销令牌失败: %w", err)
+	}
+	for userID, version := range versions {
+		s.tokenVersions.Set(strconv.FormatUint(uint64(userID), 10), version)
+	}
+	return nil
+}
+
+// tokenVersion 获取用户当前的令牌版本，优先使用短时缓存
+func (s *authService) tokenVersion(userID uint) (uint, error) {
+	key := strconv.FormatUint(uint64(userID), 10)
+	if version, ok := s.tokenVersions.Get(key); ok {
+		return version.(uint), nil
+	}
+	version, err := s.userRepo.GetTokenVersion(userID)
+	if err != nil {
+		return 0, err
+	}
+	s.tokenVersions.Set(key, version)
+	return version, nil
+}
+
 // GetUserByID 根据ID获取用户
 func (s *authService) GetUserByID(id uint) (*model.User, error) {
 	return s.userRepo.GetByID(id)
 }
 
+// ChangePassword 修改当前用户密码
+func (s *authService) ChangePassword(userID uint, req ChangePasswordRequest) error {
+	// 获取用户
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	// 验证原密码
+	if !user.CheckPassword(req.OldPassword) {
+		return errors.New("原密码错误")
+	}
+
+	// 设置新密码
+	return s.passwordService.ChangePassword(user, req.NewPassword)
+}
+
+// UpdateProfile 修改当前用户的个人资料，修改邮箱后需要重新验证
+func (s *authService) UpdateProfile(userID uint, req UpdateProfileRequest) (*model.User, error) {
+	// 获取用户
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		// 修改邮箱需要验证当前密码
+		if req.CurrentPassword == "" || !user.CheckPassword(req.CurrentPassword) {
+			return nil, errors.New("修改邮箱需要提供正确的当前密码")
+		}
+
+		// 检查邮箱是否已存在
+		_, err := s.userRepo.GetByEmail(*req.Email)
+		if err == nil {
+			return nil, errors.New("邮箱已存在")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("检查邮箱失败: %w", err)
+		}
+
+		user.Email = *req.Email
+		user.EmailVerified = false
+		user.VerifiedAt = nil
+	}
+
+	// 保存资料
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		return nil, fmt.Errorf("更新个人资料失败: %w", err)
+	}
+
+	// 向新邮箱发送验证邮件，失败时用户可以稍后重新发送
+	if emailChanged {
+		if err := s.verificationService.SendVerification(user); err != nil {
+			log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// verifyDummyPassword 使用固定的哈希执行一次密码校验，用于抹平用户不存在时的响应时间差异
+func (s *authService) verifyDummyPassword(password string) {
+	registry := auth.DefaultHasherRegistry()
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = registry.Hash("dummy-password-for-timing")
+	})
+	registry.Verify(password, s.dummyHash)
+}
+
+// resolveOrganization 确定登录后所在的组织
+// 指定组织时必须是其成员；未指定时进入用户加入的第一个组织，没有加入任何组织时处于全局范围。
+func (s *authService) resolveOrganization(userID, requested uint) (uint, error) {
+	if requested != 0 {
+		if _, err := s.orgRepo.GetMembership(userID, requested); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, ErrNotMember
+			}
+			return 0, fmt.Errorf("获取组织成员关系失败: %w", err)
+		}
+		return requested, nil
+	}
+
+	memberships, err := s.orgRepo.ListMemberships(userID)
+	if err != nil {
+		return 0, fmt.Errorf("获取组织成员关系失败: %w", err)
+	}
+	if len(memberships) == 0 {
+		return 0, nil
+	}
+	return memberships[0].OrganizationID, nil
+}
+
+// UserPermissions 计算用户令牌中携带的权限，集中授权决策也使用同样的计算
+// 全局角色（包括分组角色）始终可用，处于组织中时再加上用户在该组织中的角色；
+// activeRoleIDs 不为空时只激活其中的角色。激活的角色不能违反职责分离约束。
+func (s *authService) UserPermissions(user *model.User, orgID uint, activeRoleIDs []uint) (auth.PermissionSet, error) {
+	roles := append([]model.Role{}, user.Roles...)
+	roles = append(roles, user.GroupRoles...)
+
+	// 组织角色
+	if orgID != 0 {
+		membership, err := s.orgRepo.GetMembership(user.ID, orgID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return auth.PermissionSet{}, ErrNotMember
+			}
+			return auth.PermissionSet{}, fmt.Errorf("获取组织成员关系失败: %w", err)
+		}
+		roles = append(roles, membership.Roles...)
+	}
+
+	// 选择本次会话激活的角色
+	if len(activeRoleIDs) > 0 {
+		selected, err := selectRoles(roles, activeRoleIDs)
+		if err != nil {
+			return auth.PermissionSet{}, err
+		}
+		roles = selected
+	}
+
+	// 检查职责分离约束
+	roleIDs := make([]uint, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	if err := s.separationService.CheckSession(roleIDs); err != nil {
+		return auth.PermissionSet{}, err
+	}
+
+	// 包含从上级角色继承的权限，拒绝的权限优先
+	permissions := model.RolePermissionSet(roles)
+
+	// 未验证邮箱的用户按配置限制登录或权限，拒绝的权限保持不变
+	allowed, err := s.verificationService.RestrictPermissions(user, permissions.Allowed)
+	if err != nil {
+		return auth.PermissionSet{}, err
+	}
+	permissions.Allowed = allowed
+	return permissions, nil
+}
+
+// selectRoles 从用户可用的角色中选出要激活的角色，任一角色不可用时返回 ErrRoleNotHeld
+func selectRoles(roles []model.Role, activeRoleIDs []uint) ([]model.Role, error) {
+	byID := make(map[uint]model.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+
+	selected := make([]model.Role, 0, len(activeRoleIDs))
+	seen := make(map[uint]bool, len(activeRoleIDs))
+	for _, id := range activeRoleIDs {
+		role, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: ID %d", ErrRoleNotHeld, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, role)
+		}
+	}
+	return selected, nil
+}
+
 // generateTokenPair 生成访问令牌和刷新令牌对
-func (s *authService) generateTokenPair(userID uint, username string, permissions []string) (*model.TokenPair, error) {
+func (s *authService) generateTokenPair(user *model.User, orgID uint, activeRoleIDs []uint, permissionSet auth.PermissionSet, passwordExpired bool) (*model.TokenPair, error) {
+	// 压缩权限列表，避免通配符与大量权限使令牌膨胀
+	permissions := auth.CompactPermissions(permissionSet.Allowed)
+	deniedPermissions := auth.CompactPermissions(permissionSet.Denied)
+
 	// 创建访问令牌
 	accessTokenClaims := model.TokenClaims{
-		UserID:      userID,
-		Username:    username,
-		Permissions: permissions,
-		TokenType:   "access",
+		UserID:            user.ID,
+		Username:          user.Username,
+		Permissions:       permissions,
+		DeniedPermissions: deniedPermissions,
+		OrganizationID:    orgID,
+		TokenVersion:      user.TokenVersion,
+		ActiveRoleIDs:     activeRoleIDs,
+		TokenType:         "access",
+		PasswordExpired:   passwordExpired,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.jwtConfig.AccessExpire) * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    s.jwtConfig.Issuer,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
@@ -213,16 +568,21 @@ func (s *authService) generateTokenPair(userID uint, username string, permission
 
 	// 创建刷新令牌
 	refreshTokenClaims := model.TokenClaims{
-		UserID:      userID,
-		Username:    username,
-		Permissions: permissions,
-		TokenType:   "refresh",
+		UserID:            user.ID,
+		Username:          user.Username,
+		Permissions:       permissions,
+		DeniedPermissions: deniedPermissions,
+		OrganizationID:    orgID,
+		TokenVersion:      user.TokenVersion,
+		ActiveRoleIDs:     activeRoleIDs,
+		TokenType:         "refresh",
+		PasswordExpired:   passwordExpired,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.jwtConfig.RefreshExpire) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    s.jwtConfig.Issuer,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
@@ -232,9 +592,10 @@ func (s *authService) generateTokenPair(userID uint, username string, permission
 	}
 
 	return &model.TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    s.jwtConfig.AccessExpire * 60, // 转换为秒
+		AccessToken:     accessToken,
+		RefreshToken:    refreshToken,
+		ExpiresIn:       s.jwtConfig.AccessExpire * 60, // 转换为秒
+		PasswordExpired: passwordExpired,
 	}, nil
 }
 
@@ -262,5 +623,9 @@ func (s *authService) parseToken(tokenString string) (*model.TokenClaims, error)
 		return nil, errors.New("无效的令牌声明")
 	}
 
+	// 展开压缩的权限列表
+	claims.Permissions = auth.ExpandPermissions(claims.Permissions)
+	claims.DeniedPermissions = auth.ExpandPermissions(claims.DeniedPermissions)
+
 	return claims, nil
 }
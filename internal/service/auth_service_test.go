@@ -0,0 +1,82 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeTokenVersions 多个实例共享的用户令牌版本存储库，记录查询次数
+type fakeTokenVersions struct {
+	repository.UserRepository
+	versions map[uint]uint
+	reads    int
+}
+
+func (r *fakeTokenVersions) GetTokenVersion(id uint) (uint, error) {
+	r.reads++
+	version, ok := r.versions[id]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return version, nil
+}
+
+func (r *fakeTokenVersions) RevokeTokens(ids []uint) (map[uint]uint, error) {
+	versions := make(map[uint]uint, len(ids))
+	for _, id := range ids {
+		r.versions[id]++
+		versions[id] = r.versions[id]
+	}
+	return versions, nil
+}
+
+func TestValidateTokenRejectsTokensRevokedByOtherInstances(t *testing.T) {
+	users := &fakeTokenVersions{versions: map[uint]uint{7: 0}}
+	cfg := config.JWTConfig{Secret: "secret", AccessExpire: 30, RefreshExpire: 1, RevocationCheckTTL: 60}
+	now := time.Now()
+	newInstance := func() *authService {
+		s := NewAuthService(users, nil, cfg, nil, nil, nil, nil).(*authService)
+		s.tokenVersions.now = func() time.Time { return now }
+		return s
+	}
+	local, other := newInstance(), newInstance()
+
+	pair, err := local.generateTokenPair(&model.User{ID: 7}, 0, nil, auth.PermissionSet{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.ValidateToken(pair.AccessToken); err != nil {
+		t.Fatalf("ValidateToken() before revocation error = %v", err)
+	}
+
+	if err := other.RevokeSessions([]uint{7}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("revoking instance: ValidateToken() error = %v; want %v", err, ErrTokenRevoked)
+	}
+	// 其他实例在令牌版本缓存过期前仍使用缓存的版本
+	if _, err := local.ValidateToken(pair.AccessToken); err != nil {
+		t.Fatalf("cached version: ValidateToken() error = %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := local.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expired cache: ValidateToken() error = %v; want %v", err, ErrTokenRevoked)
+	}
+	if users.reads != 2 {
+		t.Errorf("token version read %d times; want 2", users.reads)
+	}
+
+	delete(users.versions, 7)
+	now = now.Add(time.Minute)
+	if _, err := local.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("deleted user: ValidateToken() error = %v; want %v", err, ErrTokenRevoked)
+	}
+}
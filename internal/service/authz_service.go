@@ -0,0 +1,325 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/policy"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 默认的缓存容量和批量检查数量
+const (
+	defaultAuthzCacheSize = 10000
+	defaultAuthzMaxBatch  = 100
+)
+
+// 授权决策代码
+const (
+	AuthzAllowed           = "allowed"
+	AuthzUserNotFound      = "user_not_found"
+	AuthzUserInactive      = "user_inactive"
+	AuthzNotMember         = "not_member"
+	AuthzRoleNotHeld       = "role_not_held"
+	AuthzSeparation        = "separation_violation"
+	AuthzEmailNotVerified  = "email_not_verified"
+	AuthzPermissionDenied  = "permission_denied"  // 权限被显式拒绝
+	AuthzPermissionMissing = "permission_missing" // 没有覆盖所需操作的权限
+	AuthzPolicyDenied      = "policy_denied"
+	AuthzError             = "error" // 求值出错，不缓存
+)
+
+var (
+	// ErrAuthzBatchTooLarge 批量检查数量超过限制
+	ErrAuthzBatchTooLarge = errors.New("批量检查的数量超过限制")
+	// ErrInvalidAuthzContext 请求上下文无效，如 time 不是 RFC3339 格式的时间
+	ErrInvalidAuthzContext = errors.New("无效的请求上下文")
+)
+
+// AuthzCheckRequest 授权检查：用户能否对资源执行操作
+type AuthzCheckRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Action string `json:"action" binding:"required"`
+	// OrganizationID 检查所在的组织，为0时只计算全局角色
+	OrganizationID uint `json:"organization_id"`
+	// RoleIDs 会话中激活的角色，为空时激活全部角色
+	RoleIDs []uint `json:"role_ids"`
+	// Resource 被访问资源的属性，owner_id 用于 :self 权限，其余属性供策略使用
+	Resource map[string]interface{} `json:"resource"`
+	// Context 请求上下文，如 ip、method、path；time 为 RFC3339 格式的时间，未提供时使用当前时间，
+	// 此时依赖适用策略的决策不缓存，避免按时间段生效的策略在缓存时间内得到过期的结果
+	Context map[string]interface{} `json:"context"`
+}
+
+// AuthzDecision 授权决策
+type AuthzDecision struct {
+	Allowed  bool     `json:"allowed"`
+	Code     string   `json:"code"`
+	Reasons  []string `json:"reasons"`
+	Policies []string `json:"policies,omitempty"` // 决定结果的策略名称
+	Cached   bool     `json:"cached"`
+}
+
+// AuthzService 集中授权决策服务，其他服务通过 HTTP 接口或在进程内调用判断用户能否执行操作，
+// 与令牌签发使用相同的有效权限计算，RBAC通过后再按资源的所有者和访问控制策略求值。
+type AuthzService interface {
+	Check(req AuthzCheckRequest) AuthzDecision
+	CheckBatch(reqs []AuthzCheckRequest) ([]AuthzDecision, error)
+}
+
+// authzSubject 缓存的主体：用户及其在指定组织、激活角色下的有效权限
+type authzSubject struct {
+	user        *model.User
+	permissions auth.PermissionSet
+	denial      *AuthzDecision // 主体无效时的决策
+	expires     time.Time      // 主体缓存的过期时间，基于该主体的决策不会缓存得更久
+}
+
+// authzService 集中授权决策服务实现
+type authzService struct {
+	userRepo      repository.UserRepository
+	authService   AuthService
+	policyService PolicyService
+	config        config.AuthzConfig
+
+	subjects  *lruCache
+	decisions *lruCache
+}
+
+// NewAuthzService 创建集中授权决策服务实例
+func NewAuthzService(userRepo repository.UserRepository, authService AuthService, policyService PolicyService, cfg config.AuthzConfig) AuthzService {
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = defaultAuthzCacheSize
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = defaultAuthzMaxBatch
+	}
+	ttl := time.Duration(cfg.CacheTTL) * time.Second
+	return &authzService{
+		userRepo:      userRepo,
+		authService:   authService,
+		policyService: policyService,
+		config:        cfg,
+		subjects:      newLRUCache(ttl, cfg.CacheSize),
+		decisions:     newLRUCache(ttl, cfg.CacheSize),
+	}
+}
+
+// Check 检查单个请求，相同的请求在缓存时间内直接返回缓存的决策，
+// 决策最多缓存到所依据的主体过期，因此角色和权限的修改最迟在一个缓存时间后生效。
+// 请求上下文无效时返回 AuthzError 决策。
+func (s *authzService) Check(req AuthzCheckRequest) AuthzDecision {
+	key, err := json.Marshal(req)
+	if err != nil {
+		decision, _ := s.evaluate(req)
+		return decision
+	}
+	if cached, ok := s.decisions.Get(string(key)); ok {
+		decision := cached.(AuthzDecision)
+		decision.Cached = true
+		return decision
+	}
+
+	decision, expires := s.evaluate(req)
+	if decision.Code != AuthzError && !expires.IsZero() {
+		s.decisions.SetUntil(string(key), decision, expires)
+	}
+	return decision
+}
+
+// CheckBatch 按顺序检查多个请求，返回与请求一一对应的决策
+func (s *authzService) CheckBatch(reqs []AuthzCheckRequest) ([]AuthzDecision, error) {
+	if len(reqs) > s.config.MaxBatch {
+		return nil, fmt.Errorf("%w: 最多%d个", ErrAuthzBatchTooLarge, s.config.MaxBatch)
+	}
+	for i, req := range reqs {
+		if _, err := requestContext(req.Context); err != nil {
+			return nil, fmt.Errorf("第%d个检查: %w", i+1, err)
+		}
+	}
+
+	decisions := make([]AuthzDecision, len(reqs))
+	for i, req := range reqs {
+		decisions[i] = s.Check(req)
+	}
+	return decisions, nil
+}
+
+// evaluate 获取主体并求值，返回决策及决策可以缓存到的时间，零值表示不缓存
+func (s *authzService) evaluate(req AuthzCheckRequest) (AuthzDecision, time.Time) {
+	context, err := requestContext(req.Context)
+	if err != nil {
+		return deny(AuthzError, err.Error()), time.Time{}
+	}
+	subject, err := s.subject(req)
+	if err != nil {
+		log.Printf("授权检查获取用户 %d 失败: %v", req.UserID, err)
+		return deny(AuthzError, "获取用户权限失败"), time.Time{}
+	}
+	decision, usedPolicies := s.decide(req, subject, context)
+	if usedPolicies && req.Context["time"] == nil {
+		// 策略按当前时间求值，结果可能随时间变化
+		return decision, time.Time{}
+	}
+	return decision, subject.expires
+}
+
+// decide 依次检查主体、RBAC权限（包括 :any 与 :self 所有权权限）和访问控制策略，
+// 同时返回决策是否依赖适用的访问控制策略
+func (s *authzService) decide(req AuthzCheckRequest, subject *authzSubject, context map[string]interface{}) (AuthzDecision, bool) {
+	if subject.denial != nil {
+		return *subject.denial, false
+	}
+	user, permissions := subject.user, subject.permissions
+
+	// RBAC，拒绝的权限优先
+	anyCode := req.Action + ":" + auth.ScopeAny
+	selfCode := req.Action + ":" + auth.ScopeSelf
+	var reasons []string
+	switch {
+	case permissions.Denies(req.Action):
+		return deny(AuthzPermissionDenied, "权限 "+req.Action+" 被显式拒绝"), false
+	case permissions.Has(req.Action):
+		reasons = append(reasons, "拥有权限 "+req.Action)
+	case permissions.Has(anyCode):
+		reasons = append(reasons, "拥有权限 "+anyCode)
+	case permissions.Has(selfCode):
+		ownerID, ok := resourceOwner(req.Resource)
+		if !ok || ownerID != user.ID {
+			return deny(AuthzPermissionMissing, "仅拥有权限 "+selfCode+"，只能访问自己的资源"), false
+		}
+		reasons = append(reasons, "拥有权限 "+selfCode+"，资源属于本人")
+	case permissions.Denies(anyCode), permissions.Denies(selfCode):
+		return deny(AuthzPermissionDenied, "权限 "+req.Action+" 的所有权范围被显式拒绝"), false
+	default:
+		return deny(AuthzPermissionMissing, "没有权限 "+req.Action), false
+	}
+
+	// 访问控制策略只能进一步收紧权限
+	attrs := policy.SubjectAttributes(user, permissions.Allowed)
+	attrs["organization_id"] = req.OrganizationID
+	result := s.policyService.Evaluate(policy.Request{
+		Action:   req.Action,
+		Subject:  attrs,
+		Resource: req.Resource,
+		Context:  context,
+	})
+	applicable := result.Effect != policy.DecisionNotApplicable
+	if result.Denied() {
+		decision := deny(AuthzPolicyDenied, "访问被策略拒绝: "+result.Reason)
+		decision.Policies = result.Policies
+		return decision, applicable
+	}
+	if result.Effect == policy.DecisionAllow {
+		reasons = append(reasons, "满足访问控制策略")
+	}
+
+	return AuthzDecision{Allowed: true, Code: AuthzAllowed, Reasons: reasons, Policies: result.Policies}, applicable
+}
+
+// subject 获取用户及其有效权限，结果按用户、组织和激活的角色缓存
+func (s *authzService) subject(req AuthzCheckRequest) (*authzSubject, error) {
+	key := fmt.Sprintf("%d:%d:%v", req.UserID, req.OrganizationID, req.RoleIDs)
+	if cached, ok := s.subjects.Get(key); ok {
+		return cached.(*authzSubject), nil
+	}
+
+	subject := &authzSubject{expires: s.subjects.Expiry()}
+	user, err := s.userRepo.GetByID(req.UserID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		subject.denial = denial(AuthzUserNotFound, "用户不存在")
+	case err != nil:
+		return nil, err
+	case !user.Active:
+		subject.denial = denial(AuthzUserInactive, "用户已被禁用")
+	default:
+		subject.user = user
+		subject.permissions, err = s.authService.UserPermissions(user, req.OrganizationID, req.RoleIDs)
+		if err != nil {
+			code := subjectErrorCode(err)
+			if code == AuthzError {
+				return nil, err
+			}
+			subject.denial = denial(code, err.Error())
+		}
+	}
+
+	s.subjects.SetUntil(key, subject, subject.expires)
+	return subject, nil
+}
+
+// subjectErrorCode 返回计算有效权限失败时的决策代码，非预期的错误返回 AuthzError
+func subjectErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotMember):
+		return AuthzNotMember
+	case errors.Is(err, ErrRoleNotHeld):
+		return AuthzRoleNotHeld
+	case errors.Is(err, ErrSeparationViolation):
+		return AuthzSeparation
+	case errors.Is(err, ErrEmailNotVerified):
+		return AuthzEmailNotVerified
+	default:
+		return AuthzError
+	}
+}
+
+// deny 构造拒绝的决策
+func deny(code, reason string) AuthzDecision {
+	return AuthzDecision{Code: code, Reasons: []string{reason}}
+}
+
+// denial 构造拒绝的决策指针，用于缓存无效的主体
+func denial(code, reason string) *AuthzDecision {
+	decision := deny(code, reason)
+	return &decision
+}
+
+// requestContext 构造策略中 request 变量的属性，JSON中 RFC3339 格式的 time 解析为时间，
+// 使策略可以使用时间函数，未提供 time 时使用当前时间
+func requestContext(ctx map[string]interface{}) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{}, len(ctx)+1)
+	for key, value := range ctx {
+		attrs[key] = value
+	}
+	switch value := attrs["time"].(type) {
+	case nil:
+		attrs["time"] = time.Now()
+	case time.Time:
+	case string:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: time 必须为 RFC3339 格式的时间", ErrInvalidAuthzContext)
+		}
+		attrs["time"] = t
+	default:
+		return nil, fmt.Errorf("%w: time 必须为 RFC3339 格式的时间", ErrInvalidAuthzContext)
+	}
+	return attrs, nil
+}
+
+// resourceOwner 解析资源属性中的 owner_id，JSON中的数字解析为 float64
+func resourceOwner(resource map[string]interface{}) (uint, bool) {
+	switch owner := resource["owner_id"].(type) {
+	case float64:
+		return uint(owner), owner >= 0 && owner == float64(uint(owner))
+	case int:
+		return uint(owner), owner >= 0
+	case uint:
+		return owner, true
+	case string:
+		id, err := strconv.ParseUint(owner, 10, 32)
+		return uint(id), err == nil
+	default:
+		return 0, false
+	}
+}
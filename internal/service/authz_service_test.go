@@ -0,0 +1,304 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/policy"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeAuthzUsers 按ID返回测试用户的用户存储库，未实现的方法不会被授权检查调用
+type fakeAuthzUsers struct {
+	repository.UserRepository
+	users map[uint]*model.User
+}
+
+func (r *fakeAuthzUsers) GetByID(id uint) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// fakeAuthzPermissions 按用户ID返回有效权限或错误的认证服务
+type fakeAuthzPermissions struct {
+	AuthService
+	permissions map[uint]auth.PermissionSet
+	errs        map[uint]error
+}
+
+func (s *fakeAuthzPermissions) UserPermissions(user *model.User, orgID uint, activeRoleIDs []uint) (auth.PermissionSet, error) {
+	if err := s.errs[user.ID]; err != nil {
+		return auth.PermissionSet{}, err
+	}
+	return s.permissions[user.ID], nil
+}
+
+// fakeAuthzPolicies 返回固定决策的策略服务，记录最近一次求值的请求
+type fakeAuthzPolicies struct {
+	PolicyService
+	decision policy.Decision
+	last     policy.Request
+}
+
+func (s *fakeAuthzPolicies) Evaluate(req policy.Request) policy.Decision {
+	s.last = req
+	return s.decision
+}
+
+// authzFixture 使用测试用户、权限和策略的授权服务，缓存使用可控时钟
+type authzFixture struct {
+	service     *authzService
+	users       *fakeAuthzUsers
+	permissions *fakeAuthzPermissions
+	policies    *fakeAuthzPolicies
+	clock       *time.Time
+}
+
+// newAuthzFixture 创建授权服务：用户1为审计员，拥有全部读取权限并拒绝 *:delete；用户2只能读取自己；
+// 用户3已停用；用户4的有效权限计算返回 errs 中的错误
+func newAuthzFixture(cfg config.AuthzConfig) *authzFixture {
+	f := &authzFixture{
+		users: &fakeAuthzUsers{users: map[uint]*model.User{
+			1: {ID: 1, Active: true},
+			2: {ID: 2, Active: true},
+			3: {ID: 3, Active: false},
+			4: {ID: 4, Active: true},
+		}},
+		permissions: &fakeAuthzPermissions{
+			permissions: map[uint]auth.PermissionSet{
+				1: auth.NewPermissionSet([]string{"*:read", "*:read:any", "user:delete:self", "user:*"}, []string{"*:delete"}),
+				2: auth.NewPermissionSet([]string{"user:read:self", "user:delete:any"}, []string{"user:delete:self"}),
+			},
+			errs: map[uint]error{},
+		},
+		policies: &fakeAuthzPolicies{decision: policy.Decision{Effect: policy.DecisionNotApplicable}},
+	}
+	f.service = NewAuthzService(f.users, f.permissions, f.policies, cfg).(*authzService)
+
+	now := time.Unix(1700000000, 0)
+	f.clock = &now
+	f.service.subjects.now = func() time.Time { return *f.clock }
+	f.service.decisions.now = func() time.Time { return *f.clock }
+	return f
+}
+
+func TestAuthzDenyCoversScopes(t *testing.T) {
+	s := newAuthzFixture(config.AuthzConfig{}).service
+	own := map[string]interface{}{"owner_id": float64(1)}
+
+	tests := []struct {
+		name   string
+		req    AuthzCheckRequest
+		code   string
+		reason string
+	}{
+		{"auditor reads", AuthzCheckRequest{UserID: 1, Action: "user:read"}, AuthzAllowed, "拥有权限 user:read"},
+		{"auditor deletes", AuthzCheckRequest{UserID: 1, Action: "user:delete"}, AuthzPermissionDenied, "权限 user:delete 被显式拒绝"},
+		{"auditor deletes own resource", AuthzCheckRequest{UserID: 1, Action: "user:delete", Resource: own}, AuthzPermissionDenied, "权限 user:delete 被显式拒绝"},
+		{"auditor deletes roles", AuthzCheckRequest{UserID: 1, Action: "role:delete"}, AuthzPermissionDenied, "权限 role:delete 被显式拒绝"},
+		{"scoped deny leaves any scope", AuthzCheckRequest{UserID: 2, Action: "user:delete"}, AuthzAllowed, "拥有权限 user:delete:any"},
+		{"self scope on other resource", AuthzCheckRequest{UserID: 2, Action: "user:read", Resource: own}, AuthzPermissionMissing, "仅拥有权限 user:read:self，只能访问自己的资源"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := s.Check(tt.req)
+			if decision.Code != tt.code || decision.Allowed != (tt.code == AuthzAllowed) {
+				t.Fatalf("Check() = %+v; want code %s", decision, tt.code)
+			}
+			if len(decision.Reasons) == 0 || decision.Reasons[0] != tt.reason {
+				t.Errorf("Reasons = %v; want %q", decision.Reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAuthzDecisionMatrix(t *testing.T) {
+	own := map[string]interface{}{"owner_id": float64(2)}
+	other := map[string]interface{}{"owner_id": "1"}
+
+	tests := []struct {
+		name     string
+		req      AuthzCheckRequest
+		subject  error           // 用户4计算有效权限时返回的错误
+		policy   policy.Decision // 为空时策略不适用
+		code     string
+		reasons  []string
+		policies []string
+	}{
+		{name: "user not found", req: AuthzCheckRequest{UserID: 9, Action: "user:read"},
+			code: AuthzUserNotFound, reasons: []string{"用户不存在"}},
+		{name: "user inactive", req: AuthzCheckRequest{UserID: 3, Action: "user:read"},
+			code: AuthzUserInactive, reasons: []string{"用户已被禁用"}},
+		{name: "not member", req: AuthzCheckRequest{UserID: 4, Action: "user:read"}, subject: ErrNotMember,
+			code: AuthzNotMember, reasons: []string{ErrNotMember.Error()}},
+		{name: "role not held", req: AuthzCheckRequest{UserID: 4, Action: "user:read"}, subject: ErrRoleNotHeld,
+			code: AuthzRoleNotHeld, reasons: []string{ErrRoleNotHeld.Error()}},
+		{name: "separation", req: AuthzCheckRequest{UserID: 4, Action: "user:read"}, subject: &SeparationError{Violations: []SeparationViolation{{Constraint: "c", MaxRoles: 1, Roles: []string{"a", "b"}}}},
+			code: AuthzSeparation, reasons: []string{"违反职责分离约束: c（最多同时拥有1个: a、b）"}},
+		{name: "email not verified", req: AuthzCheckRequest{UserID: 4, Action: "user:read"}, subject: ErrEmailNotVerified,
+			code: AuthzEmailNotVerified, reasons: []string{ErrEmailNotVerified.Error()}},
+		{name: "subject error", req: AuthzCheckRequest{UserID: 4, Action: "user:read"}, subject: errors.New("数据库不可用"),
+			code: AuthzError, reasons: []string{"获取用户权限失败"}},
+		{name: "permission denied", req: AuthzCheckRequest{UserID: 1, Action: "user:delete"},
+			code: AuthzPermissionDenied, reasons: []string{"权限 user:delete 被显式拒绝"}},
+		{name: "scope deny leaves any scope", req: AuthzCheckRequest{UserID: 2, Action: "user:delete", Resource: own},
+			code: AuthzAllowed, reasons: []string{"拥有权限 user:delete:any"}},
+		{name: "permission missing", req: AuthzCheckRequest{UserID: 2, Action: "role:list"},
+			code: AuthzPermissionMissing, reasons: []string{"没有权限 role:list"}},
+		{name: "self scope on own resource", req: AuthzCheckRequest{UserID: 2, Action: "user:read", Resource: own},
+			code: AuthzAllowed, reasons: []string{"拥有权限 user:read:self，资源属于本人"}},
+		{name: "self scope on other resource", req: AuthzCheckRequest{UserID: 2, Action: "user:read", Resource: other},
+			code: AuthzPermissionMissing, reasons: []string{"仅拥有权限 user:read:self，只能访问自己的资源"}},
+		{name: "self scope without owner", req: AuthzCheckRequest{UserID: 2, Action: "user:read"},
+			code: AuthzPermissionMissing, reasons: []string{"仅拥有权限 user:read:self，只能访问自己的资源"}},
+		{name: "policy denied", req: AuthzCheckRequest{UserID: 1, Action: "user:read"},
+			policy: policy.Decision{Effect: policy.DecisionDeny, Policies: []string{"office-hours"}, Reason: "命中拒绝策略"},
+			code:   AuthzPolicyDenied, reasons: []string{"访问被策略拒绝: 命中拒绝策略"}, policies: []string{"office-hours"}},
+		{name: "policy allowed", req: AuthzCheckRequest{UserID: 1, Action: "user:read"},
+			policy: policy.Decision{Effect: policy.DecisionAllow, Policies: []string{"same-department"}},
+			code:   AuthzAllowed, reasons: []string{"拥有权限 user:read", "满足访问控制策略"}, policies: []string{"same-department"}},
+		{name: "policy not applicable", req: AuthzCheckRequest{UserID: 1, Action: "user:read"},
+			code: AuthzAllowed, reasons: []string{"拥有权限 user:read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthzFixture(config.AuthzConfig{})
+			if tt.subject != nil {
+				f.permissions.errs[4] = tt.subject
+			}
+			if tt.policy.Effect != "" {
+				f.policies.decision = tt.policy
+			}
+
+			decision := f.service.Check(tt.req)
+			if decision.Code != tt.code || decision.Allowed != (tt.code == AuthzAllowed) {
+				t.Fatalf("Check() = %+v; want code %s", decision, tt.code)
+			}
+			if !equalStrings(decision.Reasons, tt.reasons) {
+				t.Errorf("Reasons = %q; want %q", decision.Reasons, tt.reasons)
+			}
+			if !equalStrings(decision.Policies, tt.policies) {
+				t.Errorf("Policies = %q; want %q", decision.Policies, tt.policies)
+			}
+		})
+	}
+}
+
+func TestAuthzDecisionCache(t *testing.T) {
+	f := newAuthzFixture(config.AuthzConfig{CacheTTL: 10})
+	req := AuthzCheckRequest{UserID: 2, Action: "role:list"}
+
+	if decision := f.service.Check(req); decision.Allowed || decision.Cached {
+		t.Fatalf("first Check() = %+v; want uncached denial", decision)
+	}
+	if decision := f.service.Check(req); decision.Allowed || !decision.Cached {
+		t.Fatalf("second Check() = %+v; want cached denial", decision)
+	}
+
+	// 求值出错的决策不缓存
+	f.permissions.errs[4] = errors.New("数据库不可用")
+	failing := AuthzCheckRequest{UserID: 4, Action: "role:list"}
+	f.service.Check(failing)
+	if decision := f.service.Check(failing); decision.Cached {
+		t.Fatalf("error decision cached: %+v", decision)
+	}
+}
+
+// TestAuthzDecisionBoundedBySubject 决策基于缓存的主体求值时，最多缓存到主体过期，
+// 权限的修改最迟在一个缓存时间后生效，而不是两个
+func TestAuthzDecisionBoundedBySubject(t *testing.T) {
+	f := newAuthzFixture(config.AuthzConfig{CacheTTL: 10})
+	start := *f.clock
+
+	// 主体在 start 缓存，10秒后过期
+	f.service.Check(AuthzCheckRequest{UserID: 2, Action: "user:read"})
+
+	// 8秒后基于缓存的主体求值另一个请求
+	*f.clock = start.Add(8 * time.Second)
+	req := AuthzCheckRequest{UserID: 2, Action: "role:list"}
+	if decision := f.service.Check(req); decision.Allowed {
+		t.Fatalf("Check() = %+v; want denial", decision)
+	}
+
+	// 授予权限，主体过期后决策随之失效
+	f.permissions.permissions[2] = auth.NewPermissionSet([]string{"role:list"}, nil)
+	*f.clock = start.Add(9 * time.Second)
+	if decision := f.service.Check(req); decision.Allowed || !decision.Cached {
+		t.Fatalf("Check() before subject expiry = %+v; want cached denial", decision)
+	}
+	*f.clock = start.Add(10 * time.Second)
+	if decision := f.service.Check(req); !decision.Allowed || decision.Cached {
+		t.Fatalf("Check() after subject expiry = %+v; want fresh allow", decision)
+	}
+}
+
+func TestAuthzRequestTime(t *testing.T) {
+	f := newAuthzFixture(config.AuthzConfig{})
+	decision := f.service.Check(AuthzCheckRequest{UserID: 1, Action: "user:read", Context: map[string]interface{}{"time": "2024-03-01T09:30:00+08:00"}})
+	if !decision.Allowed {
+		t.Fatalf("Check() = %+v; want allow", decision)
+	}
+	want := time.Date(2024, 3, 1, 1, 30, 0, 0, time.UTC)
+	if got, ok := f.policies.last.Context["time"].(time.Time); !ok || !got.Equal(want) {
+		t.Fatalf("request.time = %#v; want %v", f.policies.last.Context["time"], want)
+	}
+
+	for _, value := range []interface{}{"09:30", float64(1700000000)} {
+		reqs := []AuthzCheckRequest{
+			{UserID: 1, Action: "user:read"},
+			{UserID: 1, Action: "user:read", Context: map[string]interface{}{"time": value}},
+		}
+		if _, err := f.service.CheckBatch(reqs); !errors.Is(err, ErrInvalidAuthzContext) {
+			t.Errorf("CheckBatch() with time %v error = %v; want %v", value, err, ErrInvalidAuthzContext)
+		}
+		if decision := f.service.Check(reqs[1]); decision.Code != AuthzError {
+			t.Errorf("Check() with time %v = %+v; want code %s", value, decision, AuthzError)
+		}
+	}
+}
+
+// TestAuthzCurrentTimeNotCached 未提供 time 时依赖适用策略的决策按当前时间求值，不缓存
+func TestAuthzCurrentTimeNotCached(t *testing.T) {
+	f := newAuthzFixture(config.AuthzConfig{CacheTTL: 10})
+	f.policies.decision = policy.Decision{Effect: policy.DecisionAllow, Policies: []string{"office-hours"}}
+
+	tests := []struct {
+		name   string
+		req    AuthzCheckRequest
+		cached bool
+	}{
+		{"current time", AuthzCheckRequest{UserID: 1, Action: "user:read"}, false},
+		{"explicit time", AuthzCheckRequest{UserID: 1, Action: "user:list", Context: map[string]interface{}{"time": "2024-03-01T09:30:00Z"}}, true},
+		{"rbac denial", AuthzCheckRequest{UserID: 2, Action: "role:list"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.service.Check(tt.req)
+			if decision := f.service.Check(tt.req); decision.Cached != tt.cached {
+				t.Fatalf("second Check() = %+v; want cached %v", decision, tt.cached)
+			}
+		})
+	}
+}
+
+// equalStrings 比较两个字符串列表，nil 与空列表视为相同
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
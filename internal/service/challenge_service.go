@@ -0,0 +1,132 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"context"
+	"sync"
+	"time"
+)
+
+// 需要人机验证的操作
+const (
+	ChallengeActionLogin    = "login"
+	ChallengeActionRegister = "register"
+)
+
+// ChallengeService 人机验证服务接口，根据风险阈值决定是否需要验证
+type ChallengeService interface {
+	Enabled() bool
+	Required(action, ip string) bool
+	Issue() (interface{}, error)
+	Verify(ctx context.Context, response, ip string) error
+	RecordRegistration(ip string)
+}
+
+// challengeService 人机验证服务实现
+type challengeService struct {
+	verifier       ChallengeVerifier
+	loginProtector LoginProtector
+	cfg            config.ChallengeConfig
+
+	mu            sync.Mutex
+	registrations map[string][]time.Time // 按IP记录的注册时间
+	lastSweep     time.Time
+}
+
+// NewChallengeService 创建人机验证服务实例
+func NewChallengeService(verifier ChallengeVerifier, loginProtector LoginProtector, cfg config.ChallengeConfig) ChallengeService {
+	return &challengeService{
+		verifier:       verifier,
+		loginProtector: loginProtector,
+		cfg:            cfg,
+		registrations:  make(map[string][]time.Time),
+		lastSweep:      time.Now(),
+	}
+}
+
+// Enabled 是否启用人机验证
+func (s *challengeService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// Required 判断本次操作是否需要人机验证
+// 登录根据该IP的失败次数判断，注册根据该IP在窗口内的注册次数判断。
+func (s *challengeService) Required(action, ip string) bool {
+	if !s.cfg.Enabled {
+		return false
+	}
+
+	switch action {
+	case ChallengeActionLogin:
+		return s.loginProtector.IPFailures(ip) >= s.cfg.LoginFailureThreshold
+	case ChallengeActionRegister:
+		return s.registrationCount(ip) >= s.cfg.RegisterThreshold
+	default:
+		return false
+	}
+}
+
+// Issue 下发挑战或前端所需的验证参数
+func (s *challengeService) Issue() (interface{}, error) {
+	if issuer, ok := s.verifier.(ChallengeIssuer); ok {
+		return issuer.Issue()
+	}
+	return nil, nil
+}
+
+// Verify 校验客户端提交的验证结果
+func (s *challengeService) Verify(ctx context.Context, response, ip string) error {
+	if response == "" {
+		return ErrChallengeRequired
+	}
+	return s.verifier.Verify(ctx, response, ip)
+}
+
+// RecordRegistration 记录一次成功的注册
+func (s *challengeService) RecordRegistration(ip string) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	s.registrations[ip] = append(s.recent(ip, now), now)
+}
+
+// registrationCount 获取IP在窗口内的注册次数
+func (s *challengeService) registrationCount(ip string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.recent(ip, time.Now()))
+}
+
+// recent 返回IP在窗口内的注册时间，并丢弃过期记录
+func (s *challengeService) recent(ip string, now time.Time) []time.Time {
+	window := time.Duration(s.cfg.RegisterWindow) * time.Minute
+	times := s.registrations[ip]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) > window {
+		i++
+	}
+	if i == len(times) {
+		delete(s.registrations, ip)
+		return nil
+	}
+	s.registrations[ip] = times[i:]
+	return times[i:]
+}
+
+// sweep 定期清理过期的注册记录，防止内存无限增长
+func (s *challengeService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Duration(s.cfg.RegisterWindow)*time.Minute {
+		return
+	}
+	for ip := range s.registrations {
+		s.recent(ip, now)
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/pkg/auth"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrChallengeRequired 需要完成人机验证
+	ErrChallengeRequired = errors.New("请先完成人机验证")
+	// ErrChallengeFailed 人机验证未通过
+	ErrChallengeFailed = errors.New("人机验证未通过，请重试")
+)
+
+// ChallengeVerifier 人机验证校验接口
+// response 为客户端提交的验证结果，remoteIP 为客户端IP，部分服务商会用于风险判断。
+type ChallengeVerifier interface {
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// ChallengeIssuer 可由服务端下发挑战的验证方式，如工作量证明；
+// 第三方验证码通常只需下发站点密钥等前端参数。
+type ChallengeIssuer interface {
+	Issue() (interface{}, error)
+}
+
+// minPowSecretLength 工作量证明挑战签名密钥的最小长度
+const minPowSecretLength = 16
+
+// NewChallengeVerifier 根据配置创建人机验证实例。
+// 启用工作量证明时必须配置独立的签名密钥，不能复用 jwtSecret，以免泄露其中一个影响另一个。
+func NewChallengeVerifier(cfg config.ChallengeConfig, jwtSecret string) (ChallengeVerifier, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", "pow":
+		if cfg.Enabled {
+			if len(cfg.Pow.Secret) < minPowSecretLength {
+				return nil, fmt.Errorf("工作量证明签名密钥 challenge.pow.secret 至少需要%d个字符", minPowSecretLength)
+			}
+			if cfg.Pow.Secret == jwtSecret {
+				return nil, errors.New("工作量证明签名密钥 challenge.pow.secret 不能与JWT密钥相同")
+			}
+		}
+		return NewPowVerifier(cfg.Pow, cfg.Pow.Secret), nil
+	case "hcaptcha":
+		return NewCaptchaVerifier("hcaptcha", "https://api.hcaptcha.com/siteverify", cfg.Captcha), nil
+	case "turnstile":
+		return NewCaptchaVerifier("turnstile", "https://challenges.cloudflare.com/turnstile/v0/siteverify", cfg.Captcha), nil
+	default:
+		return nil, fmt.Errorf("不支持的人机验证方式: %s", cfg.Provider)
+	}
+}
+
+// powVerifier 基于hashcash工作量证明的人机验证，无需依赖外部服务
+type powVerifier struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+
+	mu        sync.Mutex
+	used      map[string]time.Time // 已使用的挑战及其过期时间，防止重放
+	lastSweep time.Time
+}
+
+// NewPowVerifier 创建工作量证明人机验证实例
+func NewPowVerifier(cfg config.PowConfig, secret string) ChallengeVerifier {
+	difficulty := cfg.Difficulty
+	if difficulty <= 0 {
+		difficulty = 20
+	}
+	ttl := time.Duration(cfg.Expire) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &powVerifier{
+		secret:     []byte(secret),
+		difficulty: difficulty,
+		ttl:        ttl,
+		used:       make(map[string]time.Time),
+		lastSweep:  time.Now(),
+	}
+}
+
+// Issue 下发一个新的工作量证明挑战
+func (v *powVerifier) Issue() (interface{}, error) {
+	return auth.NewPowChallenge(v.secret, v.difficulty, v.ttl)
+}
+
+// Verify 校验工作量证明结果，每个挑战只能使用一次
+func (v *powVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	challenge, expiresAt, err := auth.VerifyPowSolution(v.secret, response)
+	if err != nil {
+		return ErrChallengeFailed
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	v.sweep(now)
+	if _, ok := v.used[challenge]; ok {
+		return ErrChallengeFailed
+	}
+	v.used[challenge] = expiresAt
+	return nil
+}
+
+// sweep 清理已过期的挑战记录
+func (v *powVerifier) sweep(now time.Time) {
+	if now.Sub(v.lastSweep) < v.ttl {
+		return
+	}
+	for challenge, expiresAt := range v.used {
+		if now.After(expiresAt) {
+			delete(v.used, challenge)
+		}
+	}
+	v.lastSweep = now
+}
+
+// CaptchaChallenge 第三方验证码的前端参数
+type CaptchaChallenge struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"site_key"`
+}
+
+// captchaVerifier 适配hCaptcha、Turnstile等使用 siteverify 接口的验证码服务
+// 接口接收表单参数 secret、response、remoteip，返回包含 success 字段的JSON。
+type captchaVerifier struct {
+	provider  string
+	verifyURL string
+	siteKey   string
+	secret    string
+	client    *http.Client
+}
+
+// NewCaptchaVerifier 创建第三方验证码校验实例，配置中未指定地址时使用 defaultURL
+func NewCaptchaVerifier(provider, defaultURL string, cfg config.CaptchaConfig) ChallengeVerifier {
+	verifyURL := cfg.VerifyURL
+	if verifyURL == "" {
+		verifyURL = defaultURL
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &captchaVerifier{
+		provider:  provider,
+		verifyURL: verifyURL,
+		siteKey:   cfg.SiteKey,
+		secret:    cfg.Secret,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Issue 返回前端渲染验证码所需的参数
+func (v *captchaVerifier) Issue() (interface{}, error) {
+	return &CaptchaChallenge{Provider: v.provider, SiteKey: v.siteKey}, nil
+}
+
+// Verify 调用服务商接口校验验证码结果
+func (v *captchaVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return ErrChallengeFailed
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", response)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("创建验证码校验请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求验证码服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("验证码服务返回异常状态: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析验证码服务响应失败: %w", err)
+	}
+	if !result.Success {
+		return ErrChallengeFailed
+	}
+	return nil
+}
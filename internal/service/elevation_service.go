@@ -0,0 +1,318 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/event"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultApproverPermission 未配置时审批人需要拥有的权限
+const defaultApproverPermission = "elevation:approve"
+
+var (
+	// ErrElevationNotPending 提权申请已被处理
+	ErrElevationNotPending = repository.ErrElevationNotPending
+	// ErrElevationPending 已有同一角色的待审批申请
+	ErrElevationPending = errors.New("已有该角色的待审批申请")
+	// ErrSelfApproval 不能审批自己的申请
+	ErrSelfApproval = errors.New("不能审批自己的提权申请")
+	// ErrRoleAlreadyHeld 用户在申请的时间内已拥有该角色
+	ErrRoleAlreadyHeld = errors.New("已拥有该角色")
+	// ErrInvalidDuration 申请的时长超出允许范围
+	ErrInvalidDuration = errors.New("申请的时长超出允许范围")
+)
+
+// CreateElevationRequest 提交提权申请请求
+type CreateElevationRequest struct {
+	RoleID        uint   `json:"role_id" binding:"required"`
+	Duration      int    `json:"duration" binding:"required,min=1"` // 分钟
+	Justification string `json:"justification" binding:"required,max=1000"`
+}
+
+// ReviewElevationRequest 审批提权申请请求
+type ReviewElevationRequest struct {
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+// ElevationService 临时提权服务接口
+type ElevationService interface {
+	Request(userID uint, req CreateElevationRequest) (*model.ElevationRequest, error)
+	List(userID uint, status string, page, pageSize int) ([]model.ElevationRequest, int64, error)
+	Approve(id, reviewerID uint, req ReviewElevationRequest) (*model.ElevationRequest, error)
+	Deny(id, reviewerID uint, req ReviewElevationRequest) (*model.ElevationRequest, error)
+	ApproverPermission() string
+	ForOperator(permissions auth.PermissionSet) ElevationService
+}
+
+// elevationService 临时提权服务实现
+type elevationService struct {
+	elevationRepo    repository.ElevationRepository
+	roleRepo         repository.RoleRepository
+	grantRepo        repository.RoleGrantRepository
+	roleGrantService RoleGrantService
+	auditService     AuditService
+	publisher        event.Publisher
+	config           config.ElevationConfig
+	operator         *auth.PermissionSet
+}
+
+// NewElevationService 创建临时提权服务实例
+func NewElevationService(elevationRepo repository.ElevationRepository, roleRepo repository.RoleRepository, grantRepo repository.RoleGrantRepository, roleGrantService RoleGrantService, auditService AuditService, publisher event.Publisher, cfg config.ElevationConfig) ElevationService {
+	if cfg.ApproverPermission == "" {
+		cfg.ApproverPermission = defaultApproverPermission
+	}
+	return &elevationService{
+		elevationRepo:    elevationRepo,
+		roleRepo:         roleRepo,
+		grantRepo:        grantRepo,
+		roleGrantService: roleGrantService,
+		auditService:     auditService,
+		publisher:        publisher,
+		config:           cfg,
+	}
+}
+
+// ForOperator 返回以指定审批人的有效权限审批的服务，
+// 审批人只能通过申请自己拥有其全部权限（包括继承的权限）的角色的申请。
+func (s *elevationService) ForOperator(permissions auth.PermissionSet) ElevationService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
+// ApproverPermission 审批人需要拥有的权限
+func (s *elevationService) ApproverPermission() string {
+	return s.config.ApproverPermission
+}
+
+// Request 提交提权申请，并通知拥有审批权限的用户
+func (s *elevationService) Request(userID uint, req CreateElevationRequest) (*model.ElevationRequest, error) {
+	if s.config.MaxDuration > 0 && req.Duration > s.config.MaxDuration {
+		return nil, fmt.Errorf("%w: 最长 %d 分钟", ErrInvalidDuration, s.config.MaxDuration)
+	}
+
+	// 只能申请全局角色
+	role, err := s.roleRepo.GetByID(req.RoleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("获取角色失败: %w", err)
+	}
+
+	// 已拥有该角色或已有待审批的申请时不能重复申请
+	if err := s.checkNotHeld(userID, role.ID, time.Now().Add(time.Duration(req.Duration)*time.Minute)); err != nil {
+		return nil, err
+	}
+	pending, err := s.elevationRepo.HasPending(userID, role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("检查待审批申请失败: %w", err)
+	}
+	if pending {
+		return nil, ErrElevationPending
+	}
+
+	request := model.ElevationRequest{
+		UserID:        userID,
+		RoleID:        role.ID,
+		Duration:      req.Duration,
+		Justification: req.Justification,
+		Status:        model.ElevationPending,
+	}
+	if err := s.elevationRepo.Create(&request); err != nil {
+		return nil, fmt.Errorf("创建提权申请失败: %w", err)
+	}
+
+	s.auditService.Record(userID, "elevation.requested", "elevation", request.ID, model.Attributes{
+		"role_id":       role.ID,
+		"role":          role.Name,
+		"duration":      req.Duration,
+		"justification": req.Justification,
+	})
+
+	// 通知审批人，申请人本人不参与审批
+	approvers, err := s.approvers(userID)
+	if err != nil {
+		log.Printf("获取提权申请 %d 的审批人失败: %v", request.ID, err)
+	}
+	s.publish(event.TypeElevationRequested, &request, map[string]interface{}{"approver_ids": approvers})
+
+	return s.elevationRepo.GetByID(request.ID)
+}
+
+// List 获取提权申请列表，userID 为0时获取全部用户的申请
+func (s *elevationService) List(userID uint, status string, page, pageSize int) ([]model.ElevationRequest, int64, error) {
+	return s.elevationRepo.List(userID, status, page, pageSize)
+}
+
+// Approve 通过提权申请，向申请人授予到期自动回收的角色；审批人必须拥有该角色的全部权限，
+// 否则拒绝审批并记录审计日志
+func (s *elevationService) Approve(id, reviewerID uint, req ReviewElevationRequest) (*model.ElevationRequest, error) {
+	request, err := s.pendingRequest(id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 审批人不能授予自己没有的权限
+	role, err := s.roleRepo.GetByID(request.RoleID)
+	if err != nil {
+		return nil, fmt.Errorf("获取角色失败: %w", err)
+	}
+	if err := checkGrantable(s.operator, []model.Role{*role}); err != nil {
+		s.auditService.Record(reviewerID, "elevation.approval_rejected", "elevation", request.ID, model.Attributes{
+			"user_id": request.UserID,
+			"role_id": request.RoleID,
+			"reason":  err.Error(),
+		})
+		return nil, err
+	}
+
+	// 审批时用户可能已通过其他途径获得该角色
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(request.Duration) * time.Minute)
+	if err := s.checkNotHeld(request.UserID, request.RoleID, expiresAt); err != nil {
+		return nil, err
+	}
+
+	// 先记录审批结果，保证并发审批时只授予一次
+	request.Status = model.ElevationApproved
+	request.ReviewerID = &reviewerID
+	request.ReviewComment = req.Comment
+	request.ReviewedAt = &now
+	request.ExpiresAt = &expiresAt
+	if err := s.elevationRepo.Review(request); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roleGrantService.Grant(request.UserID, reviewerID, GrantRoleRequest{RoleID: request.RoleID, ValidUntil: &expiresAt}); err != nil {
+		if reopenErr := s.elevationRepo.Reopen(request.ID); reopenErr != nil {
+			log.Printf("回滚提权申请 %d 的审批结果失败: %v", request.ID, reopenErr)
+		}
+		return nil, fmt.Errorf("授予角色失败: %w", err)
+	}
+
+	s.auditService.Record(reviewerID, "elevation.approved", "elevation", request.ID, model.Attributes{
+		"user_id":           request.UserID,
+		"role_id":           request.RoleID,
+		"expires_at":        expiresAt,
+		"comment":           req.Comment,
+		"grantable_checked": s.operator != nil,
+	})
+	s.publish(event.TypeElevationApproved, request, map[string]interface{}{"expires_at": expiresAt})
+
+	return s.elevationRepo.GetByID(request.ID)
+}
+
+// Deny 拒绝提权申请
+func (s *elevationService) Deny(id, reviewerID uint, req ReviewElevationRequest) (*model.ElevationRequest, error) {
+	request, err := s.pendingRequest(id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request.Status = model.ElevationDenied
+	request.ReviewerID = &reviewerID
+	request.ReviewComment = req.Comment
+	request.ReviewedAt = &now
+	if err := s.elevationRepo.Review(request); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(reviewerID, "elevation.denied", "elevation", request.ID, model.Attributes{
+		"user_id": request.UserID,
+		"role_id": request.RoleID,
+		"comment": req.Comment,
+	})
+	s.publish(event.TypeElevationDenied, request, nil)
+
+	return s.elevationRepo.GetByID(request.ID)
+}
+
+// pendingRequest 获取待审批的申请，审批人不能是申请人
+func (s *elevationService) pendingRequest(id, reviewerID uint) (*model.ElevationRequest, error) {
+	request, err := s.elevationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != model.ElevationPending {
+		return nil, ErrElevationNotPending
+	}
+	if request.UserID == reviewerID {
+		s.auditService.Record(reviewerID, "elevation.self_approval_rejected", "elevation", request.ID, nil)
+		return nil, ErrSelfApproval
+	}
+	return request, nil
+}
+
+// checkNotHeld 检查用户在 until 之前是否已一直拥有该角色
+func (s *elevationService) checkNotHeld(userID, roleID uint, until time.Time) error {
+	grants, err := s.grantRepo.ListByUser(userID)
+	if err != nil {
+		return fmt.Errorf("获取用户角色失败: %w", err)
+	}
+
+	now := time.Now()
+	for _, grant := range grants {
+		if grant.RoleID != roleID || !grant.ActiveAt(now) {
+			continue
+		}
+		if grant.ValidUntil == nil || !grant.ValidUntil.Before(until) {
+			return ErrRoleAlreadyHeld
+		}
+	}
+	return nil
+}
+
+// approvers 获取直接拥有审批权限（包括继承的权限）的用户ID，不包括申请人
+func (s *elevationService) approvers(requesterID uint) ([]uint, error) {
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var roleIDs []uint
+	for _, role := range roles {
+		if role.HasPermission(s.config.ApproverPermission) {
+			roleIDs = append(roleIDs, role.ID)
+		}
+	}
+
+	userIDs, err := s.grantRepo.ListUserIDsByRoles(roleIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	approvers := make([]uint, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != requesterID {
+			approvers = append(approvers, id)
+		}
+	}
+	return approvers, nil
+}
+
+// publish 发布提权申请相关的事件，发送失败只记录日志
+func (s *elevationService) publish(eventType string, request *model.ElevationRequest, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"request_id": request.ID,
+		"user_id":    request.UserID,
+		"role_id":    request.RoleID,
+		"duration":   request.Duration,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	if err := s.publisher.Publish(event.New(eventType, data)); err != nil {
+		log.Printf("发送提权申请事件失败 request=%d type=%s: %v", request.ID, eventType, err)
+	}
+}
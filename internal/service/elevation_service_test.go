@@ -0,0 +1,73 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/event"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+)
+
+// fakeElevations 保存一个待审批申请的提权申请存储库
+type fakeElevations struct {
+	repository.ElevationRepository
+	request model.ElevationRequest
+}
+
+func (r *fakeElevations) GetByID(id uint) (*model.ElevationRequest, error) {
+	copied := r.request
+	return &copied, nil
+}
+
+func (r *fakeElevations) Review(request *model.ElevationRequest) error {
+	r.request = *request
+	return nil
+}
+
+// fakePublisher 丢弃事件的发布者
+type fakePublisher struct{}
+
+func (fakePublisher) Publish(event.Event) error {
+	return nil
+}
+
+func TestApproveRequiresGrantableRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		reviewer   []string
+		roleID     uint
+		wantErr    error
+		wantAction string
+	}{
+		{"approver cannot approve admin", []string{"elevation:approve"}, 1, ErrPrivilegeEscalation, "elevation.approval_rejected"},
+		{"approver lacks inherited permission", []string{"elevation:approve", "user:list"}, 2, ErrPrivilegeEscalation, "elevation.approval_rejected"},
+		{"approver holds role permissions", []string{"elevation:approve", "user:list", "user:read"}, 2, nil, "elevation.approved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elevations := &fakeElevations{request: model.ElevationRequest{ID: 8, UserID: 5, RoleID: tt.roleID, Duration: 30, Status: model.ElevationPending}}
+			grants := &fakeGrants{}
+			audit := &fakeAudit{}
+			roles := newFakeRoles()
+			grantService := NewRoleGrantService(grants, roles, nil, audit, fakePublisher{}, config.RoleGrantConfig{})
+			s := NewElevationService(elevations, roles, grants, grantService, audit, fakePublisher{}, config.ElevationConfig{}).
+				ForOperator(auth.NewPermissionSet(tt.reviewer, nil))
+
+			_, err := s.Approve(8, 7, ReviewElevationRequest{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Approve() error = %v; want %v", err, tt.wantErr)
+			}
+			if granted := len(grants.granted) == 1; granted != (tt.wantErr == nil) {
+				t.Errorf("role granted = %v", granted)
+			}
+			if last := audit.actions[len(audit.actions)-1]; last != tt.wantAction {
+				t.Errorf("audit actions = %v; want last %s", audit.actions, tt.wantAction)
+			}
+			if tt.wantErr != nil && elevations.request.Status != model.ElevationPending {
+				t.Errorf("status = %s; want pending", elevations.request.Status)
+			}
+		})
+	}
+}
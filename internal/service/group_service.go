@@ -0,0 +1,170 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrGroupNotFound 分组不存在
+	ErrGroupNotFound = repository.ErrGroupNotFound
+	// ErrGroupCycle 分组嵌套关系形成环
+	ErrGroupCycle = repository.ErrGroupCycle
+	// ErrGroupHasChildren 分组下还有子分组
+	ErrGroupHasChildren = repository.ErrGroupHasChildren
+	// ErrUserNotFound 要添加的用户不存在
+	ErrUserNotFound = repository.ErrUserNotFound
+)
+
+// GroupMembersRequest 添加或移除分组成员请求
+type GroupMembersRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required"`
+}
+
+// GroupService 用户分组服务接口
+type GroupService interface {
+	Create(group *model.Group) error
+	GetByID(id uint) (*model.Group, error)
+	Update(group *model.Group) error
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.Group, int64, error)
+	ListMembers(groupID uint) ([]model.User, error)
+	AddMembers(groupID uint, userIDs []uint) error
+	RemoveMembers(groupID uint, userIDs []uint) error
+	SetRoles(groupID uint, roleIDs []uint) (*model.Group, error)
+	ForOperator(permissions auth.PermissionSet) GroupService
+}
+
+// groupService 用户分组服务实现
+type groupService struct {
+	groupRepo repository.GroupRepository
+	roleRepo  repository.RoleRepository
+	operator  *auth.PermissionSet
+}
+
+// NewGroupService 创建用户分组服务实例
+func NewGroupService(groupRepo repository.GroupRepository, roleRepo repository.RoleRepository) GroupService {
+	return &groupService{groupRepo: groupRepo, roleRepo: roleRepo}
+}
+
+// ForOperator 返回以指定操作者的有效权限修改分组的服务，
+// 分组成员获得分组的角色，操作者只能分配、加入或继承自己拥有其全部权限的分组角色。
+func (s *groupService) ForOperator(permissions auth.PermissionSet) GroupService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
+// Create 创建分组
+func (s *groupService) Create(group *model.Group) error {
+	// 检查分组名称是否已存在
+	_, err := s.groupRepo.GetByName(group.Name)
+	if err == nil {
+		return errors.New("分组名称已存在")
+	}
+
+	return s.groupRepo.Create(group)
+}
+
+// GetByID 根据ID获取分组
+func (s *groupService) GetByID(id uint) (*model.Group, error) {
+	return s.groupRepo.GetByID(id)
+}
+
+// Update 更新分组
+func (s *groupService) Update(group *model.Group) error {
+	// 检查分组是否存在
+	existing, err := s.groupRepo.GetByID(group.ID)
+	if err != nil {
+		return fmt.Errorf("分组不存在: %w", err)
+	}
+
+	// 如果分组名称已更改，检查新名称是否已存在
+	if existing.Name != group.Name {
+		if _, err := s.groupRepo.GetByName(group.Name); err == nil {
+			return errors.New("分组名称已存在")
+		}
+	}
+
+	// 移到新的上级分组后，成员获得新上级分组的角色
+	if group.ParentID != nil && (existing.ParentID == nil || *existing.ParentID != *group.ParentID) {
+		if err := s.checkGroupRoles(*group.ParentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGroupNotFound
+			}
+			return err
+		}
+	}
+
+	return s.groupRepo.Update(group)
+}
+
+// Delete 删除分组
+func (s *groupService) Delete(id uint) error {
+	return s.groupRepo.Delete(id)
+}
+
+// List 获取分组列表
+func (s *groupService) List(page, pageSize int) ([]model.Group, int64, error) {
+	return s.groupRepo.List(page, pageSize)
+}
+
+// ListMembers 获取分组的直接成员
+func (s *groupService) ListMembers(groupID uint) ([]model.User, error) {
+	return s.groupRepo.ListMembers(groupID)
+}
+
+// AddMembers 将用户加入分组，操作者必须拥有分组（包括上级分组）角色的全部权限
+func (s *groupService) AddMembers(groupID uint, userIDs []uint) error {
+	if err := s.checkGroupRoles(groupID); err != nil {
+		return err
+	}
+	return s.groupRepo.AddMembers(groupID, userIDs)
+}
+
+// RemoveMembers 将用户移出分组
+func (s *groupService) RemoveMembers(groupID uint, userIDs []uint) error {
+	return s.groupRepo.RemoveMembers(groupID, userIDs)
+}
+
+// SetRoles 替换分组的角色，成员在下次登录或刷新令牌时获得新权限；操作者必须拥有这些角色的全部权限
+func (s *groupService) SetRoles(groupID uint, roleIDs []uint) (*model.Group, error) {
+	if s.operator != nil {
+		roles := make([]model.Role, 0, len(roleIDs))
+		for _, roleID := range roleIDs {
+			role, err := s.roleRepo.GetByID(roleID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, fmt.Errorf("%w: ID %d", ErrRoleNotFound, roleID)
+				}
+				return nil, fmt.Errorf("获取角色失败: %w", err)
+			}
+			roles = append(roles, *role)
+		}
+		if err := checkGrantable(s.operator, roles); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.groupRepo.SetRoles(groupID, roleIDs); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetByID(groupID)
+}
+
+// checkGroupRoles 检查操作者能否授予成员通过分组获得的角色
+func (s *groupService) checkGroupRoles(groupID uint) error {
+	if s.operator == nil {
+		return nil
+	}
+	roles, err := s.groupRepo.EffectiveRoles(groupID)
+	if err != nil {
+		return err
+	}
+	return checkGrantable(s.operator, roles)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginBlockedError 登录因失败次数过多被拒绝
+type LoginBlockedError struct {
+	Locked     bool          // true 表示已锁定，false 表示处于递增延迟中
+	RetryAfter time.Duration // 可以再次尝试的等待时间
+}
+
+// Error 实现error接口
+func (e *LoginBlockedError) Error() string {
+	seconds := int(e.RetryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if e.Locked {
+		return fmt.Sprintf("登录失败次数过多，账户已临时锁定，请%d秒后再试", seconds)
+	}
+	return fmt.Sprintf("登录尝试过于频繁，请%d秒后再试", seconds)
+}
+
+// LockoutStatus 锁定状态
+type LockoutStatus struct {
+	Failures      int        `json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	Locked        bool       `json:"locked"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// LoginProtector 登录防暴力破解接口，分别按用户名和IP统计失败次数
+type LoginProtector interface {
+	Check(username, ip string) error
+	RecordFailure(username, ip string)
+	RecordSuccess(username, ip string)
+	Status(username string) LockoutStatus
+	Unlock(username string)
+	IPFailures(ip string) int
+}
+
+// attemptRecord 失败记录
+type attemptRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginProtector 基于内存的登录防暴力破解实现
+type loginProtector struct {
+	cfg       config.LoginProtectionConfig
+	mu        sync.Mutex
+	users     map[string]*attemptRecord
+	ips       map[string]*attemptRecord
+	lastSweep time.Time
+}
+
+// NewLoginProtector 创建登录防暴力破解实例
+func NewLoginProtector(cfg config.LoginProtectionConfig) LoginProtector {
+	return &loginProtector{
+		cfg:       cfg,
+		users:     make(map[string]*attemptRecord),
+		ips:       make(map[string]*attemptRecord),
+		lastSweep: time.Now(),
+	}
+}
+
+// Check 检查是否允许本次登录尝试
+func (p *loginProtector) Check(username, ip string) error {
+	if !p.cfg.Enabled {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if err := p.checkRecord(p.current(p.ips, ip, now), now); err != nil {
+		return err
+	}
+	return p.checkRecord(p.current(p.users, normalizeUsername(username), now), now)
+}
+
+// RecordFailure 记录一次失败的登录
+func (p *loginProtector) RecordFailure(username, ip string) {
+	if !p.cfg.Enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.sweep(now)
+	p.fail(p.users, normalizeUsername(username), now, p.cfg.MaxAttempts, p.cfg.LockoutDuration)
+	p.fail(p.ips, ip, now, p.cfg.IPMaxAttempts, p.cfg.IPLockoutDuration)
+}
+
+// RecordSuccess 登录成功后清除该用户名的失败记录，IP记录保留以防撞库
+func (p *loginProtector) RecordSuccess(username, ip string) {
+	if !p.cfg.Enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.users, normalizeUsername(username))
+}
+
+// Status 获取用户名的锁定状态
+func (p *loginProtector) Status(username string) LockoutStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	record := p.current(p.users, normalizeUsername(username), now)
+	if record == nil {
+		return LockoutStatus{}
+	}
+
+	lastFailure := record.lastFailure
+	status := LockoutStatus{Failures: record.failures, LastFailureAt: &lastFailure}
+	if now.Before(record.lockedUntil) {
+		lockedUntil := record.lockedUntil
+		status.Locked = true
+		status.LockedUntil = &lockedUntil
+	}
+	return status
+}
+
+// Unlock 解除用户名的锁定并清除失败记录
+func (p *loginProtector) Unlock(username string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.users, normalizeUsername(username))
+}
+
+// IPFailures 获取IP在统计窗口内的失败次数
+func (p *loginProtector) IPFailures(ip string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if record := p.current(p.ips, ip, time.Now()); record != nil {
+		return record.failures
+	}
+	return 0
+}
+
+// current 获取未过期的失败记录，超过统计窗口且未锁定的记录会被清除
+func (p *loginProtector) current(records map[string]*attemptRecord, key string, now time.Time) *attemptRecord {
+	record, ok := records[key]
+	if !ok {
+		return nil
+	}
+	if p.expired(record, now) {
+		delete(records, key)
+		return nil
+	}
+	return record
+}
+
+// expired 判断记录是否已超过统计窗口
+func (p *loginProtector) expired(record *attemptRecord, now time.Time) bool {
+	window := time.Duration(p.cfg.Window) * time.Minute
+	return now.After(record.lockedUntil) && now.Sub(record.lastFailure) > window
+}
+
+// checkRecord 根据失败记录判断是否处于锁定或延迟中
+func (p *loginProtector) checkRecord(record *attemptRecord, now time.Time) error {
+	if record == nil {
+		return nil
+	}
+
+	// 锁定中
+	if now.Before(record.lockedUntil) {
+		return &LoginBlockedError{Locked: true, RetryAfter: record.lockedUntil.Sub(now)}
+	}
+
+	// 递增延迟：超过免延迟次数后，每次失败的等待时间翻倍
+	excess := record.failures - p.cfg.FreeAttempts
+	if excess <= 0 || p.cfg.BaseDelay <= 0 {
+		return nil
+	}
+	delay := time.Duration(p.cfg.BaseDelay) * time.Second
+	maxDelay := time.Duration(p.cfg.MaxDelay) * time.Second
+	for i := 1; i < excess && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	if wait := record.lastFailure.Add(delay).Sub(now); wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// fail 增加失败次数，达到阈值时锁定
+func (p *loginProtector) fail(records map[string]*attemptRecord, key string, now time.Time, maxAttempts, lockoutMinutes int) {
+	record := p.current(records, key, now)
+	if record == nil {
+		record = &attemptRecord{}
+		records[key] = record
+	}
+
+	record.failures++
+	record.lastFailure = now
+	if maxAttempts > 0 && record.failures >= maxAttempts {
+		record.lockedUntil = now.Add(time.Duration(lockoutMinutes) * time.Minute)
+		record.failures = 0
+	}
+}
+
+// sweep 定期清理过期的记录，防止内存无限增长
+func (p *loginProtector) sweep(now time.Time) {
+	if now.Sub(p.lastSweep) < time.Duration(p.cfg.Window)*time.Minute {
+		return
+	}
+	for _, records := range []map[string]*attemptRecord{p.users, p.ips} {
+		for key, record := range records {
+			if p.expired(record, now) {
+				delete(records, key)
+			}
+		}
+	}
+	p.lastSweep = now
+}
+
+// normalizeUsername 统一用户名大小写，防止通过大小写变化绕过限制
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry 缓存项
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// lruCache 进程内的短时缓存，超过容量时淘汰最久未使用的项，读写和淘汰均为 O(1)
+type lruCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的在前
+}
+
+// newLRUCache 创建短时缓存，ttl 或 size 不大于0时不缓存
+func newLRUCache(ttl time.Duration, size int) *lruCache {
+	return &lruCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get 获取未过期的缓存值
+func (c *lruCache) Get(key string) (interface{}, bool) {
+	if c.ttl <= 0 || c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set 写入缓存值，缓存 ttl 时间
+func (c *lruCache) Set(key string, value interface{}) {
+	c.SetUntil(key, value, time.Time{})
+}
+
+// SetUntil 写入缓存值，最多缓存到 expires，expires 为零值或晚于 ttl 时缓存 ttl 时间，
+// 用于使依赖其他缓存项的值不比依赖项存活得更久
+func (c *lruCache) SetUntil(key string, value interface{}, expires time.Time) {
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if limit := now.Add(c.ttl); expires.IsZero() || expires.After(limit) {
+		expires = limit
+	}
+	if !now.Before(expires) {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+}
+
+// Expiry 返回现在写入的缓存项的过期时间
+func (c *lruCache) Expiry() time.Time {
+	return c.now().Add(c.ttl)
+}
+
+// remove 删除缓存项
+func (c *lruCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestLRUCache 创建使用可控时钟的缓存
+func newTestLRUCache(ttl time.Duration, size int) (*lruCache, *time.Time) {
+	cache := newLRUCache(ttl, size)
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := newTestLRUCache(time.Minute, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, key)
+	}
+
+	// 访问 a 后 b 成为最久未使用的项
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	cache.Set("d", "d")
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b not evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if value, ok := cache.Get(key); !ok || value != key {
+			t.Errorf("Get(%q) = %v, %v; want %q", key, value, ok, key)
+		}
+	}
+	if cache.order.Len() != 3 || len(cache.entries) != 3 {
+		t.Errorf("size = %d/%d; want 3", cache.order.Len(), len(cache.entries))
+	}
+}
+
+func TestLRUCacheOverwriteKeepsSize(t *testing.T) {
+	cache, _ := newTestLRUCache(time.Minute, 2)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("a", 3)
+	cache.Set("c", 4)
+
+	if value, ok := cache.Get("a"); !ok || value != 3 {
+		t.Errorf("Get(a) = %v, %v; want 3", value, ok)
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b not evicted")
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	cache, now := newTestLRUCache(10*time.Second, 10)
+	start := *now
+
+	cache.Set("ttl", 1)
+	cache.SetUntil("short", 2, start.Add(3*time.Second))
+	cache.SetUntil("long", 3, start.Add(time.Hour))
+	cache.SetUntil("past", 4, start)
+
+	if _, ok := cache.Get("past"); ok {
+		t.Error("value expiring now was cached")
+	}
+
+	*now = start.Add(3 * time.Second)
+	if _, ok := cache.Get("short"); ok {
+		t.Error("short not expired")
+	}
+	if _, ok := cache.Get("ttl"); !ok {
+		t.Error("ttl expired early")
+	}
+
+	// SetUntil 不能超过 ttl
+	*now = start.Add(10 * time.Second)
+	for _, key := range []string{"ttl", "long"} {
+		if _, ok := cache.Get(key); ok {
+			t.Errorf("%s not expired after ttl", key)
+		}
+	}
+	if cache.order.Len() != 0 {
+		t.Errorf("expired entries kept: %d", cache.order.Len())
+	}
+}
+
+func TestLRUCacheDisabled(t *testing.T) {
+	for _, cache := range []*lruCache{newLRUCache(0, 10), newLRUCache(time.Minute, 0)} {
+		cache.Set("a", 1)
+		if _, ok := cache.Get("a"); ok {
+			t.Errorf("disabled cache (ttl=%v, size=%d) returned a value", cache.ttl, cache.size)
+		}
+	}
+}
+
+func BenchmarkLRUCacheSetFull(b *testing.B) {
+	cache := newLRUCache(time.Minute, 10000)
+	for i := 0; i < b.N; i++ {
+		cache.Set(fmt.Sprint(i), i)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SetMemberRequest 添加组织成员或修改其组织角色的请求
+type SetMemberRequest struct {
+	UserID  uint   `json:"user_id" binding:"required"`
+	RoleIDs []uint `json:"role_ids"` // 该组织中的角色
+}
+
+// OrganizationService 组织服务接口
+type OrganizationService interface {
+	Create(org *model.Organization) error
+	GetByID(id uint) (*model.Organization, error)
+	Update(org *model.Organization) error
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.Organization, int64, error)
+	ListMembers(orgID uint) ([]model.Membership, error)
+	SetMember(orgID uint, req SetMemberRequest) (*model.Membership, error)
+	RemoveMember(orgID, userID uint) error
+	ForOperator(permissions auth.PermissionSet) OrganizationService
+}
+
+// organizationService 组织服务实现
+type organizationService struct {
+	orgRepo        repository.OrganizationRepository
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	operator       *auth.PermissionSet
+}
+
+// NewOrganizationService 创建组织服务实例
+func NewOrganizationService(orgRepo repository.OrganizationRepository, roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository) OrganizationService {
+	return &organizationService{
+		orgRepo:        orgRepo,
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+	}
+}
+
+// ForOperator 返回以指定操作者的有效权限设置成员角色的服务，
+// 操作者只能分配自己拥有其全部权限（包括继承的权限）的组织角色。
+func (s *organizationService) ForOperator(permissions auth.PermissionSet) OrganizationService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
+// Create 创建组织，同时创建只能管理本组织的组织管理员角色
+func (s *organizationService) Create(org *model.Organization) error {
+	// 检查组织名称是否已存在
+	_, err := s.orgRepo.GetByName(org.Name)
+	if err == nil {
+		return errors.New("组织名称已存在")
+	}
+
+	// 组织管理员角色的权限
+	permissions, err := s.permissionRepo.ListAll()
+	if err != nil {
+		return fmt.Errorf("获取权限列表失败: %w", err)
+	}
+	var granted []model.Permission
+	for _, perm := range permissions {
+		if auth.HasPermission(model.OrgAdminPermissions, perm.Code) {
+			granted = append(granted, perm)
+		}
+	}
+
+	org.Roles = []model.Role{{
+		Name:        model.OrgAdminRoleName,
+		Description: "组织管理员，只能管理本组织的成员和角色",
+		Permissions: granted,
+	}}
+	return s.orgRepo.Create(org)
+}
+
+// GetByID 根据ID获取组织
+func (s *organizationService) GetByID(id uint) (*model.Organization, error) {
+	return s.orgRepo.GetByID(id)
+}
+
+// Update 更新组织
+func (s *organizationService) Update(org *model.Organization) error {
+	// 检查组织是否存在
+	existing, err := s.orgRepo.GetByID(org.ID)
+	if err != nil {
+		return fmt.Errorf("组织不存在: %w", err)
+	}
+
+	// 如果组织名称已更改，检查新名称是否已存在
+	if existing.Name != org.Name {
+		if _, err := s.orgRepo.GetByName(org.Name); err == nil {
+			return errors.New("组织名称已存在")
+		}
+	}
+
+	return s.orgRepo.Update(org)
+}
+
+// Delete 删除组织及其成员关系和组织角色
+func (s *organizationService) Delete(id uint) error {
+	return s.orgRepo.Delete(id)
+}
+
+// List 获取组织列表
+func (s *organizationService) List(page, pageSize int) ([]model.Organization, int64, error) {
+	return s.orgRepo.List(page, pageSize)
+}
+
+// ListMembers 获取组织成员
+func (s *organizationService) ListMembers(orgID uint) ([]model.Membership, error) {
+	if _, err := s.orgRepo.GetByID(orgID); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(orgID)
+}
+
+// SetMember 将用户加入组织或替换其组织角色，操作者必须拥有这些角色的全部权限
+func (s *organizationService) SetMember(orgID uint, req SetMemberRequest) (*model.Membership, error) {
+	if err := s.checkRoles(orgID, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.SetMember(orgID, req.UserID, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.GetMembership(req.UserID, orgID)
+}
+
+// checkRoles 检查要分配的组织角色是否存在，以及操作者能否授予这些角色
+func (s *organizationService) checkRoles(orgID uint, roleIDs []uint) error {
+	if s.operator == nil {
+		return nil
+	}
+	orgRoles := s.roleRepo.ForOrganization(orgID)
+	roles := make([]model.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := orgRoles.GetByID(roleID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: ID %d", ErrRoleNotFound, roleID)
+			}
+			return fmt.Errorf("获取角色失败: %w", err)
+		}
+		roles = append(roles, *role)
+	}
+	return checkGrantable(s.operator, roles)
+}
+
+// RemoveMember 将用户移出组织
+func (s *organizationService) RemoveMember(orgID, userID uint) error {
+	return s.orgRepo.RemoveMember(orgID, userID)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+)
+
+// fakeOrganizations 记录成员设置的组织存储库
+type fakeOrganizations struct {
+	repository.OrganizationRepository
+	members map[uint][]uint
+}
+
+func (r *fakeOrganizations) SetMember(orgID, userID uint, roleIDs []uint) error {
+	r.members[userID] = roleIDs
+	return nil
+}
+
+func (r *fakeOrganizations) GetMembership(userID, orgID uint) (*model.Membership, error) {
+	return &model.Membership{UserID: userID, OrganizationID: orgID}, nil
+}
+
+func TestSetMemberRequiresGrantableRoles(t *testing.T) {
+	roles := &fakeRoles{roles: map[uint]*model.Role{
+		1: {ID: 1, Name: model.OrgAdminRoleName, Permissions: []model.Permission{{Code: "user:*"}, {Code: "role:*"}}},
+		2: {ID: 2, Name: "member", Permissions: []model.Permission{{Code: "user:read"}}},
+	}}
+
+	tests := []struct {
+		name     string
+		operator []string
+		roleIDs  []uint
+		wantErr  error
+	}{
+		{"member manager cannot grant org admin", []string{"organization:member"}, []uint{1}, ErrPrivilegeEscalation},
+		{"member manager adds without roles", []string{"organization:member"}, nil, nil},
+		{"grants held permissions", []string{"organization:member", "user:read"}, []uint{2}, nil},
+		{"org admin grants org admin", []string{"organization:member", "user:*", "role:*"}, []uint{1, 2}, nil},
+		{"unknown role", []string{"*"}, []uint{9}, ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := &fakeOrganizations{members: map[uint][]uint{}}
+			s := NewOrganizationService(orgs, roles, nil).ForOperator(auth.NewPermissionSet(tt.operator, nil))
+
+			_, err := s.SetMember(3, SetMemberRequest{UserID: 5, RoleIDs: tt.roleIDs})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetMember() error = %v; want %v", err, tt.wantErr)
+			}
+			if _, set := orgs.members[5]; set != (tt.wantErr == nil) {
+				t.Errorf("member set = %v", set)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/pkg/auth"
+	"fmt"
+)
+
+// NewHasherRegistry 根据配置创建密码哈希算法注册表
+// 配置的算法用于计算新哈希，其余内置算法及旧系统算法仅用于校验已有哈希。
+func NewHasherRegistry(cfg config.PasswordHashConfig) (*auth.HasherRegistry, error) {
+	bcryptHasher := auth.NewBcryptHasher(cfg.Bcrypt.Cost)
+	argon2idHasher := auth.NewArgon2idHasher(auth.Argon2idParams{
+		Memory:      cfg.Argon2id.Memory,
+		Iterations:  cfg.Argon2id.Iterations,
+		Parallelism: cfg.Argon2id.Parallelism,
+		SaltLength:  cfg.Argon2id.SaltLength,
+		KeyLength:   cfg.Argon2id.KeyLength,
+	})
+	if err := argon2idHasher.Params().Validate(); err != nil {
+		return nil, err
+	}
+
+	var registry *auth.HasherRegistry
+	switch cfg.Algorithm {
+	case "", "argon2id":
+		registry = auth.NewHasherRegistry(argon2idHasher, cfg.Pepper, bcryptHasher)
+	case "bcrypt":
+		registry = auth.NewHasherRegistry(bcryptHasher, cfg.Pepper, argon2idHasher)
+	default:
+		return nil, fmt.Errorf("不支持的密码哈希算法: %s", cfg.Algorithm)
+	}
+
+	if cfg.PepperRotation {
+		registry.SetPreviousPepper(cfg.PreviousPepper)
+	}
+
+	// 注册旧系统算法，用于校验导入的用户密码
+	for _, h := range auth.LegacyHashers() {
+		registry.Register(h)
+	}
+
+	return registry, nil
+}
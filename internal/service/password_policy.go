@@ -0,0 +1,205 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/pkg/auth"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// 密码策略违规代码
+const (
+	ViolationTooShort         = "too_short"
+	ViolationTooLong          = "too_long"
+	ViolationMissingUpper     = "missing_upper"
+	ViolationMissingLower     = "missing_lower"
+	ViolationMissingDigit     = "missing_digit"
+	ViolationMissingSymbol    = "missing_symbol"
+	ViolationContainsUserInfo = "contains_user_info"
+	ViolationBanned           = "banned"
+	ViolationTooWeak          = "too_weak"
+	ViolationBreached         = "breached"
+)
+
+// PasswordViolation 密码策略违规项
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError 密码不符合策略
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error 实现error接口
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "密码不符合安全策略: " + strings.Join(messages, "; ")
+}
+
+// PasswordPolicy 密码策略接口
+type PasswordPolicy interface {
+	Validate(password, username, email string) error
+}
+
+// passwordPolicy 密码策略实现
+type passwordPolicy struct {
+	cfg           config.PasswordPolicyConfig
+	banned        map[string]struct{}
+	breachChecker auth.BreachChecker
+}
+
+// NewPasswordPolicy 创建密码策略实例
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) (PasswordPolicy, error) {
+	banned := make(map[string]struct{})
+	if cfg.BannedListFile != "" {
+		file, err := os.Open(cfg.BannedListFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取禁用密码列表失败: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			if line != "" && !strings.HasPrefix(line, "#") {
+				banned[line] = struct{}{}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取禁用密码列表失败: %w", err)
+		}
+	}
+
+	// 加载泄露密码语料
+	var breachChecker auth.BreachChecker
+	if cfg.Breach.Enabled {
+		var err error
+		switch {
+		case cfg.Breach.FilterFile != "":
+			breachChecker, err = auth.LoadBloomFilter(cfg.Breach.FilterFile)
+		case cfg.Breach.HashFile != "":
+			breachChecker, err = auth.OpenHashFileChecker(cfg.Breach.HashFile, cfg.Breach.MinCount)
+		default:
+			err = errors.New("未配置泄露密码语料文件")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("加载泄露密码语料失败: %w", err)
+		}
+	}
+
+	return &passwordPolicy{
+		cfg:           cfg,
+		banned:        banned,
+		breachChecker: breachChecker,
+	}, nil
+}
+
+// Validate 校验密码是否符合策略，不符合时返回 *PasswordPolicyError
+func (p *passwordPolicy) Validate(password, username, email string) error {
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	// 长度检查
+	length := len([]rune(password))
+	if length < p.cfg.MinLength {
+		add(ViolationTooShort, fmt.Sprintf("密码长度不能少于%d个字符", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		add(ViolationTooLong, fmt.Sprintf("密码长度不能超过%d个字符", p.cfg.MaxLength))
+	}
+
+	// 字符类别检查
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		add(ViolationMissingUpper, "密码必须包含大写字母")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		add(ViolationMissingLower, "密码必须包含小写字母")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		add(ViolationMissingDigit, "密码必须包含数字")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		add(ViolationMissingSymbol, "密码必须包含特殊字符")
+	}
+
+	// 个人信息检查
+	userInputs := userInfoTokens(username, email)
+	if p.cfg.DisallowUserInfo {
+		lower := strings.ToLower(password)
+		for _, input := range userInputs {
+			if len(input) >= 3 && strings.Contains(lower, input) {
+				add(ViolationContainsUserInfo, "密码不能包含用户名或邮箱")
+				break
+			}
+		}
+	}
+
+	// 禁用列表检查
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		add(ViolationBanned, "密码过于常见，请更换")
+	}
+
+	// 强度评分检查
+	if p.cfg.MinStrengthScore > 0 {
+		result := auth.EstimateStrength(password, p.banned, userInputs)
+		if result.Score < p.cfg.MinStrengthScore {
+			add(ViolationTooWeak, fmt.Sprintf("密码强度不足（%d/4），请使用更复杂的密码", result.Score))
+		}
+	}
+
+	// 泄露密码检查
+	if p.breachChecker != nil {
+		breached, err := p.breachChecker.IsBreached(password)
+		if err != nil {
+			return fmt.Errorf("检查泄露密码失败: %w", err)
+		}
+		if breached {
+			add(ViolationBreached, "该密码已出现在公开泄露的数据中，请更换")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// userInfoTokens 从用户名和邮箱中提取不允许出现在密码中的片段
+func userInfoTokens(username, email string) []string {
+	var tokens []string
+	if username != "" {
+		tokens = append(tokens, strings.ToLower(username))
+	}
+	if email != "" {
+		email = strings.ToLower(email)
+		tokens = append(tokens, email)
+		if at := strings.Index(email, "@"); at > 0 {
+			tokens = append(tokens, email[:at])
+		}
+	}
+	return tokens
+}
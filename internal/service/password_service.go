@@ -0,0 +1,115 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"fmt"
+	"time"
+)
+
+// ViolationReused 密码与近期使用过的密码重复
+const ViolationReused = "reused"
+
+// PasswordService 密码管理服务接口，统一处理密码策略、历史密码和密码过期
+type PasswordService interface {
+	Validate(password, username, email string) error
+	ChangePassword(user *model.User, newPassword string) error
+	IsExpired(user *model.User) bool
+}
+
+// passwordService 密码管理服务实现
+type passwordService struct {
+	userRepo    repository.UserRepository
+	historyRepo repository.PasswordHistoryRepository
+	policy      PasswordPolicy
+	cfg         config.PasswordPolicyConfig
+}
+
+// NewPasswordService 创建密码管理服务实例
+func NewPasswordService(userRepo repository.UserRepository, historyRepo repository.PasswordHistoryRepository, policy PasswordPolicy, cfg config.PasswordPolicyConfig) PasswordService {
+	return &passwordService{
+		userRepo:    userRepo,
+		historyRepo: historyRepo,
+		policy:      policy,
+		cfg:         cfg,
+	}
+}
+
+// Validate 校验新密码是否符合密码策略
+func (s *passwordService) Validate(password, username, email string) error {
+	return s.policy.Validate(password, username, email)
+}
+
+// ChangePassword 校验并设置用户的新密码，同时记录历史密码
+func (s *passwordService) ChangePassword(user *model.User, newPassword string) error {
+	// 校验密码策略
+	if err := s.policy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	// 检查是否与最近使用过的密码重复
+	if s.cfg.HistorySize > 0 {
+		reused := user.CheckPassword(newPassword)
+		if !reused && s.cfg.HistorySize > 1 {
+			histories, err := s.historyRepo.ListRecent(user.ID, s.cfg.HistorySize-1)
+			if err != nil {
+				return fmt.Errorf("获取历史密码失败: %w", err)
+			}
+			for _, history := range histories {
+				if history.Matches(newPassword) {
+					reused = true
+					break
+				}
+			}
+		}
+		if reused {
+			return &PasswordPolicyError{Violations: []PasswordViolation{{
+				Code:    ViolationReused,
+				Message: fmt.Sprintf("不能使用最近%d次使用过的密码", s.cfg.HistorySize),
+			}}}
+		}
+	}
+
+	// 记录旧密码
+	previous := user.Password
+	if s.cfg.HistorySize > 1 && previous != "" {
+		if err := s.historyRepo.Create(&model.PasswordHistory{UserID: user.ID, Password: previous}); err != nil {
+			return fmt.Errorf("记录历史密码失败: %w", err)
+		}
+		if err := s.historyRepo.Prune(user.ID, s.cfg.HistorySize-1); err != nil {
+			return fmt.Errorf("清理历史密码失败: %w", err)
+		}
+	}
+
+	// 更新密码
+	now := time.Now()
+	if err := user.SetPassword(newPassword); err != nil {
+		return fmt.Errorf("加密密码失败: %w", err)
+	}
+	user.PasswordChangedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("更新密码失败: %w", err)
+	}
+
+	return nil
+}
+
+// IsExpired 检查用户密码是否已过期，有效期取用户各角色配置中最短的一个
+func (s *passwordService) IsExpired(user *model.User) bool {
+	days := s.cfg.Expiry.DefaultDays
+	for _, role := range user.Roles {
+		if roleDays, ok := s.cfg.Expiry.Roles[role.Name]; ok && roleDays > 0 && (days <= 0 || roleDays < days) {
+			days = roleDays
+		}
+	}
+	if days <= 0 {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(days)*24*time.Hour
+}
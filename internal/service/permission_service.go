@@ -3,10 +3,21 @@ package service
 import (
 	"authentication/internal/model"
 	"authentication/internal/repository"
+	"authentication/pkg/auth"
 	"errors"
 	"fmt"
+	"log"
 )
 
+// ErrInvalidPermissionCode 权限代码格式无效
+var ErrInvalidPermissionCode = errors.New("无效的权限代码，应为以 : 分隔的小写字母、数字、_、- 或 * 组成的段")
+
+// PermissionSyncResult 权限同步结果
+type PermissionSyncResult struct {
+	Created []string           `json:"created"` // 新创建的权限代码
+	Orphans []model.Permission `json:"orphans"` // 数据库中存在但未被任何路由使用的权限
+}
+
 // PermissionService 权限服务接口
 type PermissionService interface {
 	Create(permission *model.Permission) error
@@ -15,22 +26,33 @@ type PermissionService interface {
 	Update(permission *model.Permission) error
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.Permission, int64, error)
+	Sync() (*PermissionSyncResult, error)
+	Orphans() ([]model.Permission, error)
 }
 
 // permissionService 权限服务实现
 type permissionService struct {
 	permissionRepo repository.PermissionRepository
+	roleRepo       repository.RoleRepository
+	registry       *auth.PermissionRegistry
 }
 
-// NewPermissionService 创建权限服务实例
-func NewPermissionService(permissionRepo repository.PermissionRepository) PermissionService {
+// NewPermissionService 创建权限服务实例，registry 为路由中声明的权限代码
+func NewPermissionService(permissionRepo repository.PermissionRepository, roleRepo repository.RoleRepository, registry *auth.PermissionRegistry) PermissionService {
 	return &permissionService{
 		permissionRepo: permissionRepo,
+		roleRepo:       roleRepo,
+		registry:       registry,
 	}
 }
 
 // Create 创建权限
 func (s *permissionService) Create(permission *model.Permission) error {
+	// 检查权限代码格式
+	if !auth.ValidPermissionCode(permission.Code) {
+		return ErrInvalidPermissionCode
+	}
+
 	// 检查权限代码是否已存在
 	_, err := s.permissionRepo.GetByCode(permission.Code)
 	if err == nil {
@@ -59,8 +81,12 @@ func (s *permissionService) Update(permission *model.Permission) error {
 		return fmt.Errorf("权限不存在: %w", err)
 	}
 
-	// 如果权限代码已更改，检查新代码是否已存在
+	// 如果权限代码已更改，检查新代码格式及是否已存在
 	if existingPermission.Code != permission.Code {
+		if !auth.ValidPermissionCode(permission.Code) {
+			return ErrInvalidPermissionCode
+		}
+
 		_, err := s.permissionRepo.GetByCode(permission.Code)
 		if err == nil {
 			return errors.New("权限代码已存在")
@@ -87,3 +113,81 @@ func (s *permissionService) Delete(id uint) error {
 func (s *permissionService) List(page, pageSize int) ([]model.Permission, int64, error) {
 	return s.permissionRepo.List(page, pageSize)
 }
+
+// Sync 将路由声明的权限同步到数据库
+// 缺少的权限会被创建并授予管理员角色，使管理员始终拥有全部权限；
+// 数据库中未被任何路由使用的权限只记录日志，不会被删除。
+func (s *permissionService) Sync() (*PermissionSyncResult, error) {
+	existing, err := s.permissionRepo.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("获取权限列表失败: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, perm := range existing {
+		known[perm.Code] = true
+	}
+
+	// 创建缺少的权限
+	result := &PermissionSyncResult{Created: []string{}}
+	var createdIDs []uint
+	for _, code := range s.registry.Codes() {
+		if known[code] {
+			continue
+		}
+		perm := model.Permission{Code: code, Name: code, Description: "由路由声明自动创建"}
+		if err := s.permissionRepo.Create(&perm); err != nil {
+			return nil, fmt.Errorf("创建权限 %s 失败: %w", code, err)
+		}
+		result.Created = append(result.Created, code)
+		createdIDs = append(createdIDs, perm.ID)
+	}
+
+	// 新权限授予管理员角色
+	if len(createdIDs) > 0 {
+		if admin, err := s.roleRepo.GetByName(model.AdminRoleName); err == nil {
+			if err := s.roleRepo.AddPermissions(admin.ID, createdIDs); err != nil {
+				return nil, fmt.Errorf("授予管理员权限失败: %w", err)
+			}
+		}
+		log.Printf("已创建路由声明的权限: %v", result.Created)
+	}
+
+	// 标记未被使用的权限
+	if result.Orphans, err = s.Orphans(); err != nil {
+		return nil, err
+	}
+	if len(result.Orphans) > 0 {
+		codes := make([]string, len(result.Orphans))
+		for i, perm := range result.Orphans {
+			codes[i] = perm.Code
+		}
+		log.Printf("以下权限未被任何路由使用: %v", codes)
+	}
+
+	return result, nil
+}
+
+// Orphans 获取数据库中未被任何路由使用的权限，通配符权限覆盖任一路由权限时不视为未使用
+func (s *permissionService) Orphans() ([]model.Permission, error) {
+	permissions, err := s.permissionRepo.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("获取权限列表失败: %w", err)
+	}
+
+	declared := s.registry.Codes()
+	orphans := []model.Permission{}
+	for _, perm := range permissions {
+		used := false
+		for _, code := range declared {
+			if auth.MatchPermission(perm.Code, code) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			orphans = append(orphans, perm)
+		}
+	}
+
+	return orphans, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/policy"
+	"authentication/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrInvalidPolicy 访问控制策略定义无效
+var ErrInvalidPolicy = policy.ErrInvalidPolicy
+
+// PolicyService 访问控制策略服务接口
+type PolicyService interface {
+	Create(p *model.Policy) error
+	GetByID(id uint) (*model.Policy, error)
+	Update(p *model.Policy) error
+	Delete(id uint) error
+	List() ([]model.Policy, error)
+	Reload() error
+	Evaluate(req policy.Request) policy.Decision
+	Run(ctx context.Context)
+}
+
+// policyService 访问控制策略服务实现
+type policyService struct {
+	policyRepo repository.PolicyRepository
+	engine     policy.Engine
+	cfg        config.PolicyConfig
+}
+
+// NewPolicyService 创建访问控制策略服务实例
+func NewPolicyService(policyRepo repository.PolicyRepository, engine policy.Engine, cfg config.PolicyConfig) PolicyService {
+	return &policyService{
+		policyRepo: policyRepo,
+		engine:     engine,
+		cfg:        cfg,
+	}
+}
+
+// Create 创建策略并重新加载策略引擎
+func (s *policyService) Create(p *model.Policy) error {
+	if err := s.validate(p); err != nil {
+		return err
+	}
+
+	// 检查策略名称是否已存在
+	if _, err := s.policyRepo.GetByName(p.Name); err == nil {
+		return errors.New("策略名称已存在")
+	}
+
+	if err := s.policyRepo.Create(p); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// GetByID 根据ID获取策略
+func (s *policyService) GetByID(id uint) (*model.Policy, error) {
+	return s.policyRepo.GetByID(id)
+}
+
+// Update 更新策略并重新加载策略引擎
+func (s *policyService) Update(p *model.Policy) error {
+	// 检查策略是否存在
+	existing, err := s.policyRepo.GetByID(p.ID)
+	if err != nil {
+		return fmt.Errorf("策略不存在: %w", err)
+	}
+
+	if err := s.validate(p); err != nil {
+		return err
+	}
+
+	// 如果策略名称已更改，检查新名称是否已存在
+	if existing.Name != p.Name {
+		if _, err := s.policyRepo.GetByName(p.Name); err == nil {
+			return errors.New("策略名称已存在")
+		}
+	}
+
+	if err := s.policyRepo.Update(p); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// Delete 删除策略并重新加载策略引擎
+func (s *policyService) Delete(id uint) error {
+	if err := s.policyRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// List 获取数据库中的策略，配置文件中的策略不在此列出
+func (s *policyService) List() ([]model.Policy, error) {
+	return s.policyRepo.ListAll()
+}
+
+// Reload 合并配置文件与数据库中的策略并重新加载策略引擎
+func (s *policyService) Reload() error {
+	stored, err := s.policyRepo.ListAll()
+	if err != nil {
+		return fmt.Errorf("获取策略列表失败: %w", err)
+	}
+
+	policies := make([]model.Policy, 0, len(s.cfg.Policies)+len(stored))
+	for _, rule := range s.cfg.Policies {
+		policies = append(policies, model.Policy{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Effect:      rule.Effect,
+			Action:      rule.Action,
+			Condition:   rule.Condition,
+			Enabled:     true,
+		})
+	}
+	policies = append(policies, stored...)
+
+	return s.engine.Load(policies)
+}
+
+// Run 按配置的间隔重新加载策略，直到 ctx 结束；间隔为0时直接返回。
+// 策略的增删改只会立即重新加载处理该请求的实例，其他实例依靠定期重新加载保持一致。
+func (s *policyService) Run(ctx context.Context) {
+	if s.cfg.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.ReloadInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Reload(); err != nil {
+			log.Printf("重新加载访问控制策略失败: %v", err)
+		}
+	}
+}
+
+// Evaluate 对授权请求求值，未启用时始终返回不适用
+func (s *policyService) Evaluate(req policy.Request) policy.Decision {
+	if !s.cfg.Enabled {
+		return policy.Decision{Effect: policy.DecisionNotApplicable}
+	}
+	return s.engine.Evaluate(req)
+}
+
+// validate 检查策略定义，名称不能与配置文件中的策略重复
+func (s *policyService) validate(p *model.Policy) error {
+	for _, rule := range s.cfg.Policies {
+		if rule.Name == p.Name {
+			return fmt.Errorf("%w: 策略名称 %s 已在配置文件中定义", ErrInvalidPolicy, p.Name)
+		}
+	}
+	return s.engine.Validate(*p)
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/relation"
+	"authentication/internal/repository"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 一次最多写入和删除的元组数量
+const maxRelationWrites = 1000
+
+// 一致性模式，决定在哪个版本的快照上求值
+const (
+	ConsistencyMinimizeLatency = "minimize_latency"  // 使用短时间内缓存的最新版本，默认模式
+	ConsistencyAtLeastAsFresh  = "at_least_as_fresh" // 不早于令牌的版本，提供令牌且未指定模式时使用
+	ConsistencyAtExactSnapshot = "at_exact_snapshot" // 令牌对应的版本
+	ConsistencyFullyConsistent = "fully_consistent"  // 数据库中的最新版本
+)
+
+var (
+	// ErrInvalidTuple 对象、关系或主体无效
+	ErrInvalidTuple = relation.ErrInvalidTuple
+	// ErrUnknownRelation 命名空间或关系未定义
+	ErrUnknownRelation = relation.ErrUnknownRelation
+	// ErrInvalidConsistencyToken 一致性令牌无效
+	ErrInvalidConsistencyToken = relation.ErrInvalidToken
+	// ErrRelationDepth 关系展开超过最大深度
+	ErrRelationDepth = relation.ErrMaxDepth
+	// ErrRelationExclusionCycle 关系的排除规则形成了环
+	ErrRelationExclusionCycle = relation.ErrExclusionCycle
+	// ErrEmptyRelationWrite 没有要写入或删除的元组
+	ErrEmptyRelationWrite = errors.New("没有要写入或删除的元组")
+)
+
+// RelationTupleFilter 关系元组查询条件
+type RelationTupleFilter = repository.RelationTupleFilter
+
+// Consistency 一致性要求，Token 为写入元组或此前检查返回的令牌
+type Consistency struct {
+	Mode  string `json:"mode" binding:"omitempty,oneof=minimize_latency at_least_as_fresh at_exact_snapshot fully_consistent"`
+	Token string `json:"token"`
+}
+
+// RelationTupleKey 元组的文本形式：对象 doc:readme、关系 viewer、主体 user:42 或 group:eng#member
+type RelationTupleKey struct {
+	Object   string `json:"object" binding:"required"`
+	Relation string `json:"relation" binding:"required"`
+	Subject  string `json:"subject" binding:"required"`
+}
+
+// WriteRelationsRequest 写入和删除元组请求，在同一事务中生效
+type WriteRelationsRequest struct {
+	Writes  []RelationTupleKey `json:"writes" binding:"dive"`
+	Deletes []RelationTupleKey `json:"deletes" binding:"dive"`
+}
+
+// RelationCheckRequest 检查主体是否拥有对象的关系
+type RelationCheckRequest struct {
+	Object      string      `json:"object" binding:"required"`
+	Relation    string      `json:"relation" binding:"required"`
+	Subject     string      `json:"subject" binding:"required"`
+	Consistency Consistency `json:"consistency"`
+}
+
+// RelationExpandRequest 展开对象的关系
+type RelationExpandRequest struct {
+	Object      string      `json:"object" binding:"required"`
+	Relation    string      `json:"relation" binding:"required"`
+	Consistency Consistency `json:"consistency"`
+}
+
+// ListObjectsRequest 列出主体拥有关系的对象，Cursor 为上一页结果的 NextCursor，
+// 翻页时应以第一页的 CheckedAt 和 at_exact_snapshot 模式在同一快照上继续
+type ListObjectsRequest struct {
+	Namespace   string      `json:"namespace" binding:"required"`
+	Relation    string      `json:"relation" binding:"required"`
+	Subject     string      `json:"subject" binding:"required"`
+	Cursor      string      `json:"cursor"`
+	Limit       int         `json:"limit" binding:"omitempty,min=1,max=1000"`
+	Consistency Consistency `json:"consistency"`
+}
+
+// RelationCheckResult 关系检查结果，CheckedAt 为求值所用快照的一致性令牌
+type RelationCheckResult struct {
+	Allowed   bool   `json:"allowed"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// RelationExpandResult 关系展开结果
+type RelationExpandResult struct {
+	Tree      *relation.ExpandNode `json:"tree"`
+	CheckedAt string               `json:"checked_at"`
+}
+
+// ListObjectsResult 主体拥有关系的对象ID，NextCursor 为空表示已列出全部对象
+type ListObjectsResult struct {
+	Objects    []string `json:"objects"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	CheckedAt  string   `json:"checked_at"`
+}
+
+// RelationService 基于关系的访问控制服务接口
+type RelationService interface {
+	Write(req WriteRelationsRequest) (string, error)
+	List(filter RelationTupleFilter, page, pageSize int) ([]model.RelationTuple, int64, error)
+	Check(req RelationCheckRequest) (*RelationCheckResult, error)
+	Expand(req RelationExpandRequest) (*RelationExpandResult, error)
+	ListObjects(req ListObjectsRequest) (*ListObjectsResult, error)
+}
+
+// relationService 基于关系的访问控制服务实现。
+// 写入按版本号串行化，同一版本的快照不会再变化，因此检查结果按版本缓存，无需失效。
+type relationService struct {
+	relationRepo repository.RelationRepository
+	schema       *relation.Schema
+	checker      relation.Checker
+	snapshotTTL  time.Duration
+
+	mu        sync.Mutex
+	latest    uint64    // 已知的最新版本号
+	fetchedAt time.Time // 最近一次读取最新版本号的时间
+
+	results *lruCache
+}
+
+// NewRelationService 创建基于关系的访问控制服务实例，命名空间配置无效时返回错误
+func NewRelationService(relationRepo repository.RelationRepository, cfg config.RelationConfig) (RelationService, error) {
+	schema, err := relation.NewSchema(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = defaultAuthzCacheSize
+	}
+	return &relationService{
+		relationRepo: relationRepo,
+		schema:       schema,
+		checker:      relation.NewChecker(schema, relationRepo, cfg.MaxDepth),
+		snapshotTTL:  time.Duration(cfg.SnapshotTTL) * time.Millisecond,
+		results:      newLRUCache(time.Duration(cfg.CacheTTL)*time.Second, cfg.CacheSize),
+	}, nil
+}
+
+// UserSubject 返回系统用户作为主体的文本形式，如 user:42
+func UserSubject(userID uint) string {
+	return relation.UserNamespace + ":" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// Write 写入和删除元组，返回包含新版本号的一致性令牌
+func (s *relationService) Write(req WriteRelationsRequest) (string, error) {
+	if len(req.Writes)+len(req.Deletes) == 0 {
+		return "", ErrEmptyRelationWrite
+	}
+	if len(req.Writes)+len(req.Deletes) > maxRelationWrites {
+		return "", fmt.Errorf("%w: 一次最多写入和删除%d个元组", ErrInvalidTuple, maxRelationWrites)
+	}
+
+	writes, err := s.parseTuples(req.Writes)
+	if err != nil {
+		return "", err
+	}
+	deletes, err := s.parseTuples(req.Deletes)
+	if err != nil {
+		return "", err
+	}
+
+	revision, err := s.relationRepo.Write(writes, deletes)
+	if err != nil {
+		return "", fmt.Errorf("写入关系元组失败: %w", err)
+	}
+	s.observe(revision, false)
+	return relation.EncodeToken(revision), nil
+}
+
+// List 按条件获取当前有效的元组
+func (s *relationService) List(filter RelationTupleFilter, page, pageSize int) ([]model.RelationTuple, int64, error) {
+	return s.relationRepo.List(filter, page, pageSize)
+}
+
+// Check 检查主体是否拥有对象的关系
+func (s *relationService) Check(req RelationCheckRequest) (*RelationCheckResult, error) {
+	object, err := s.parseObject(req.Object, req.Relation)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := s.parseSubject(req.Subject)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.revision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d|%s#%s@%s", revision, object, req.Relation, subject)
+	if cached, ok := s.results.Get(key); ok {
+		return &RelationCheckResult{Allowed: cached.(bool), CheckedAt: relation.EncodeToken(revision)}, nil
+	}
+
+	allowed, err := s.checker.Check(revision, object, req.Relation, subject)
+	if err != nil {
+		return nil, err
+	}
+	s.results.Set(key, allowed)
+	return &RelationCheckResult{Allowed: allowed, CheckedAt: relation.EncodeToken(revision)}, nil
+}
+
+// Expand 展开对象的关系
+func (s *relationService) Expand(req RelationExpandRequest) (*RelationExpandResult, error) {
+	object, err := s.parseObject(req.Object, req.Relation)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.revision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.checker.Expand(revision, object, req.Relation)
+	if err != nil {
+		return nil, err
+	}
+	return &RelationExpandResult{Tree: tree, CheckedAt: relation.EncodeToken(revision)}, nil
+}
+
+// ListObjects 分页列出命名空间中主体拥有该关系的对象ID，未指定每页数量时使用默认数量
+func (s *relationService) ListObjects(req ListObjectsRequest) (*ListObjectsResult, error) {
+	if err := s.schema.ValidateRelation(relation.Object{Namespace: req.Namespace}, req.Relation); err != nil {
+		return nil, err
+	}
+	subject, err := s.parseSubject(req.Subject)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.revision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, next, err := s.checker.ListObjects(revision, req.Namespace, req.Relation, subject, req.Cursor, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &ListObjectsResult{Objects: objects, NextCursor: next, CheckedAt: relation.EncodeToken(revision)}, nil
+}
+
+// revision 按一致性要求确定求值所用的版本号
+func (s *relationService) revision(consistency Consistency) (uint64, error) {
+	mode := consistency.Mode
+	if mode == "" {
+		mode = ConsistencyMinimizeLatency
+		if consistency.Token != "" {
+			mode = ConsistencyAtLeastAsFresh
+		}
+	}
+
+	switch mode {
+	case ConsistencyFullyConsistent:
+		return s.latestRevision(true)
+	case ConsistencyAtLeastAsFresh, ConsistencyAtExactSnapshot:
+		requested, err := relation.DecodeToken(consistency.Token)
+		if err != nil {
+			return 0, err
+		}
+		latest, err := s.latestRevision(false)
+		if err != nil {
+			return 0, err
+		}
+		// 缓存的版本早于令牌时重新读取，令牌的版本仍不存在时无效
+		if requested > latest {
+			if latest, err = s.latestRevision(true); err != nil {
+				return 0, err
+			}
+			if requested > latest {
+				return 0, ErrInvalidConsistencyToken
+			}
+		}
+		if mode == ConsistencyAtExactSnapshot {
+			return requested, nil
+		}
+		return latest, nil
+	default:
+		return s.latestRevision(false)
+	}
+}
+
+// latestRevision 获取最新版本号，refresh 为 false 时可以使用 snapshotTTL 内缓存的版本号
+func (s *relationService) latestRevision(refresh bool) (uint64, error) {
+	s.mu.Lock()
+	if !refresh && s.snapshotTTL > 0 && time.Since(s.fetchedAt) < s.snapshotTTL {
+		latest := s.latest
+		s.mu.Unlock()
+		return latest, nil
+	}
+	s.mu.Unlock()
+
+	revision, err := s.relationRepo.CurrentRevision()
+	if err != nil {
+		return 0, fmt.Errorf("获取关系元组版本失败: %w", err)
+	}
+	return s.observe(revision, true), nil
+}
+
+// observe 记录已知的版本号，版本号只增不减，返回记录后的最新版本号
+func (s *relationService) observe(revision uint64, fetched bool) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision > s.latest {
+		s.latest = revision
+	}
+	if fetched {
+		s.fetchedAt = time.Now()
+	}
+	return s.latest
+}
+
+// parseTuples 解析并校验元组
+func (s *relationService) parseTuples(keys []RelationTupleKey) ([]model.RelationTuple, error) {
+	tuples := make([]model.RelationTuple, 0, len(keys))
+	for _, key := range keys {
+		object, err := s.parseObject(key.Object, key.Relation)
+		if err != nil {
+			return nil, err
+		}
+		subject, err := s.parseSubject(key.Subject)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, model.RelationTuple{
+			Namespace:        object.Namespace,
+			ObjectID:         object.ID,
+			Relation:         key.Relation,
+			SubjectNamespace: subject.Namespace,
+			SubjectID:        subject.ID,
+			SubjectRelation:  subject.Relation,
+		})
+	}
+	return tuples, nil
+}
+
+// parseObject 解析对象并检查其命名空间定义了该关系
+func (s *relationService) parseObject(text, relationName string) (relation.Object, error) {
+	object, err := relation.ParseObject(text)
+	if err != nil {
+		return relation.Object{}, err
+	}
+	if err := s.schema.ValidateRelation(object, relationName); err != nil {
+		return relation.Object{}, err
+	}
+	return object, nil
+}
+
+// parseSubject 解析并校验主体
+func (s *relationService) parseSubject(text string) (relation.Subject, error) {
+	subject, err := relation.ParseSubject(text)
+	if err != nil {
+		return relation.Subject{}, err
+	}
+	if err := s.schema.ValidateSubject(subject); err != nil {
+		return relation.Subject{}, err
+	}
+	return subject, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/event"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidGrantWindow 角色授予的有效期无效
+var ErrInvalidGrantWindow = errors.New("无效的角色有效期")
+
+// GrantRoleRequest 授予用户角色请求，未指定的时间表示不限制
+type GrantRoleRequest struct {
+	RoleID     uint       `json:"role_id" binding:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+}
+
+// SweepResult 一次清理的结果
+type SweepResult struct {
+	Notified int `json:"notified"` // 发送即将到期通知的授予数
+	Expired  int `json:"expired"`  // 删除的到期授予数
+	Revoked  int `json:"revoked"`  // 撤销令牌的用户数
+}
+
+// RoleGrantService 限时角色服务接口
+type RoleGrantService interface {
+	Grant(userID, grantedBy uint, req GrantRoleRequest) (*model.UserRole, error)
+	ListByUser(userID uint) ([]model.UserRole, error)
+	Sweep(now time.Time) (*SweepResult, error)
+	Run(ctx context.Context)
+	ForOperator(permissions auth.PermissionSet) RoleGrantService
+}
+
+// roleGrantService 限时角色服务实现
+type roleGrantService struct {
+	grantRepo    repository.RoleGrantRepository
+	roleRepo     repository.RoleRepository
+	authService  AuthService
+	auditService AuditService
+	publisher    event.Publisher
+	config       config.RoleGrantConfig
+	operator     *auth.PermissionSet
+}
+
+// NewRoleGrantService 创建限时角色服务实例
+func NewRoleGrantService(grantRepo repository.RoleGrantRepository, roleRepo repository.RoleRepository, authService AuthService, auditService AuditService, publisher event.Publisher, cfg config.RoleGrantConfig) RoleGrantService {
+	return &roleGrantService{
+		grantRepo:    grantRepo,
+		roleRepo:     roleRepo,
+		authService:  authService,
+		auditService: auditService,
+		publisher:    publisher,
+		config:       cfg,
+	}
+}
+
+// ForOperator 返回以指定操作者的有效权限授予角色的服务，
+// 操作者只能授予自己拥有其全部权限（包括继承的权限）的角色。
+func (s *roleGrantService) ForOperator(permissions auth.PermissionSet) RoleGrantService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
+// Grant 授予用户全局角色，已拥有该角色时替换原有的有效期
+// 新的有效期当前不生效时撤销用户的令牌，使此前获得的权限立即失效。
+func (s *roleGrantService) Grant(userID, grantedBy uint, req GrantRoleRequest) (*model.UserRole, error) {
+	if s.operator != nil {
+		role, err := s.roleRepo.GetByID(req.RoleID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: ID %d", ErrRoleNotFound, req.RoleID)
+			}
+			return nil, fmt.Errorf("获取角色失败: %w", err)
+		}
+		if err := checkGrantable(s.operator, []model.Role{*role}); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if req.ValidUntil != nil {
+		if !req.ValidUntil.After(now) {
+			return nil, fmt.Errorf("%w: 结束时间必须晚于当前时间", ErrInvalidGrantWindow)
+		}
+		if req.ValidFrom != nil && !req.ValidUntil.After(*req.ValidFrom) {
+			return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidGrantWindow)
+		}
+	}
+
+	grant := model.UserRole{
+		UserID:     userID,
+		RoleID:     req.RoleID,
+		ValidFrom:  req.ValidFrom,
+		ValidUntil: req.ValidUntil,
+		GrantedBy:  &grantedBy,
+	}
+	if err := s.grantRepo.Grant(&grant); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(grantedBy, "role_grant.granted", "user", userID, model.Attributes{
+		"role_id":     req.RoleID,
+		"valid_from":  req.ValidFrom,
+		"valid_until": req.ValidUntil,
+	})
+
+	if !grant.ActiveAt(now) {
+		if err := s.authService.RevokeSessions([]uint{userID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &grant, nil
+}
+
+// ListByUser 获取用户的全部角色授予
+func (s *roleGrantService) ListByUser(userID uint) ([]model.UserRole, error) {
+	return s.grantRepo.ListByUser(userID)
+}
+
+// Sweep 发送即将到期通知，删除已到期的授予并撤销受影响用户的令牌
+func (s *roleGrantService) Sweep(now time.Time) (*SweepResult, error) {
+	result := &SweepResult{}
+
+	// 即将到期通知，发送失败的授予在下次清理时重试
+	if s.config.NotifyBefore > 0 {
+		expiring, err := s.grantRepo.ListExpiring(now, now.Add(time.Duration(s.config.NotifyBefore)*time.Minute))
+		if err != nil {
+			return nil, fmt.Errorf("获取即将到期的授予失败: %w", err)
+		}
+
+		var notified []model.UserRole
+		for _, grant := range expiring {
+			if err := s.publisher.Publish(grantEvent(event.TypeRoleGrantExpiring, grant)); err != nil {
+				log.Printf("发送角色即将到期事件失败 user=%d role=%d: %v", grant.UserID, grant.RoleID, err)
+				continue
+			}
+			notified = append(notified, grant)
+		}
+		if err := s.grantRepo.MarkNotified(notified, now); err != nil {
+			return nil, fmt.Errorf("记录到期通知失败: %w", err)
+		}
+		result.Notified = len(notified)
+	}
+
+	// 删除到期的授予
+	expired, err := s.grantRepo.DeleteExpired(now)
+	if err != nil {
+		return nil, fmt.Errorf("删除到期的授予失败: %w", err)
+	}
+	result.Expired = len(expired)
+	if len(expired) == 0 {
+		return result, nil
+	}
+	for _, grant := range expired {
+		s.auditService.Record(0, "role_grant.expired", "user", grant.UserID, model.Attributes{
+			"role_id":     grant.RoleID,
+			"valid_until": grant.ValidUntil,
+			"granted_by":  grant.GrantedBy,
+		})
+	}
+
+	// 撤销受影响用户的令牌，令牌中仍携带已到期角色的权限
+	seen := make(map[uint]bool)
+	var userIDs []uint
+	for _, grant := range expired {
+		if !seen[grant.UserID] {
+			seen[grant.UserID] = true
+			userIDs = append(userIDs, grant.UserID)
+		}
+	}
+	if err := s.authService.RevokeSessions(userIDs); err != nil {
+		return nil, err
+	}
+	result.Revoked = len(userIDs)
+
+	for _, grant := range expired {
+		if err := s.publisher.Publish(grantEvent(event.TypeRoleGrantExpired, grant)); err != nil {
+			log.Printf("发送角色到期事件失败 user=%d role=%d: %v", grant.UserID, grant.RoleID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// Run 按配置的间隔执行清理，直到 ctx 结束；间隔为0时直接返回
+func (s *roleGrantService) Run(ctx context.Context) {
+	if s.config.SweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.SweepInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sweep(time.Now()); err != nil {
+			log.Printf("清理到期角色失败: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// grantEvent 构造角色授予相关的事件
+func grantEvent(eventType string, grant model.UserRole) event.Event {
+	return event.New(eventType, map[string]interface{}{
+		"user_id":     grant.UserID,
+		"role_id":     grant.RoleID,
+		"valid_until": grant.ValidUntil,
+		"granted_by":  grant.GrantedBy,
+	})
+}
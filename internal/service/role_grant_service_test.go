@@ -0,0 +1,67 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+)
+
+// fakeGrants 记录授予的角色存储库
+type fakeGrants struct {
+	repository.RoleGrantRepository
+	granted []model.UserRole
+}
+
+func (r *fakeGrants) Grant(grant *model.UserRole) error {
+	r.granted = append(r.granted, *grant)
+	return nil
+}
+
+func (r *fakeGrants) ListByUser(userID uint) ([]model.UserRole, error) {
+	return nil, nil
+}
+
+// fakeAudit 记录审计动作的审计服务
+type fakeAudit struct {
+	AuditService
+	actions []string
+	details []model.Attributes
+}
+
+func (s *fakeAudit) Record(actorID uint, action, targetType string, targetID uint, detail model.Attributes) {
+	s.actions = append(s.actions, action)
+	s.details = append(s.details, detail)
+}
+
+func TestRoleGrantRequiresGrantableRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator []string
+		roleID   uint
+		wantErr  error
+	}{
+		{"assign only cannot grant admin", []string{"user:assign"}, 1, ErrPrivilegeEscalation},
+		{"missing inherited permission", []string{"user:assign", "user:list"}, 2, ErrPrivilegeEscalation},
+		{"holds inherited permissions", []string{"user:assign", "user:list", "user:read"}, 2, nil},
+		{"admin grants admin", []string{"*"}, 1, nil},
+		{"unknown role", []string{"*"}, 9, ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grants := &fakeGrants{}
+			s := NewRoleGrantService(grants, newFakeRoles(), nil, &fakeAudit{}, nil, config.RoleGrantConfig{}).
+				ForOperator(auth.NewPermissionSet(tt.operator, nil))
+
+			_, err := s.Grant(5, 7, GrantRoleRequest{RoleID: tt.roleID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Grant() error = %v; want %v", err, tt.wantErr)
+			}
+			if granted := len(grants.granted) == 1; granted != (tt.wantErr == nil) {
+				t.Errorf("role granted = %v", granted)
+			}
+		})
+	}
+}
@@ -3,10 +3,49 @@ package service
 import (
 	"authentication/internal/model"
 	"authentication/internal/repository"
+	"authentication/pkg/auth"
 	"errors"
 	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleCycle 角色继承关系形成环
+	ErrRoleCycle = repository.ErrRoleCycle
+	// ErrAdminRoleProtected 系统管理员角色不能删除或重命名
+	ErrAdminRoleProtected = repository.ErrAdminRoleProtected
+	// ErrPrivilegeEscalation 操作者试图授予自己没有的权限
+	ErrPrivilegeEscalation = errors.New("不能授予自己没有的权限")
+	// ErrPlatformPermission 组织角色不能拥有平台级权限
+	ErrPlatformPermission = errors.New("组织角色不能拥有平台级权限")
 )
 
+// InheritedPermission 继承的权限及其来源角色
+type InheritedPermission struct {
+	model.Permission
+	InheritedFrom []string `json:"inherited_from"`
+}
+
+// RolePermissions 角色的直接权限与继承权限，以及直接拒绝与继承拒绝的权限
+type RolePermissions struct {
+	RoleID          uint                  `json:"role_id"`
+	RoleName        string                `json:"role_name"`
+	Direct          []model.Permission    `json:"direct"`
+	Inherited       []InheritedPermission `json:"inherited"`
+	Denied          []model.Permission    `json:"denied"`
+	InheritedDenied []InheritedPermission `json:"inherited_denied"`
+}
+
+// RoleNode 角色继承关系中的节点
+type RoleNode struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentIDs   []uint `json:"parent_ids"`
+	ChildIDs    []uint `json:"child_ids"`
+}
+
 // RoleService 角色服务接口
 type RoleService interface {
 	Create(role *model.Role) error
@@ -16,12 +55,20 @@ type RoleService interface {
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.Role, int64, error)
 	AssignPermissions(roleID uint, permissionIDs []uint) error
+	AssignDeniedPermissions(roleID uint, permissionIDs []uint) error
+	SetParents(roleID uint, parentIDs []uint) error
+	GetPermissions(roleID uint) (*RolePermissions, error)
+	Hierarchy() ([]RoleNode, error)
+	ForOrganization(orgID uint) RoleService
+	ForOperator(permissions auth.PermissionSet) RoleService
 }
 
 // roleService 角色服务实现
 type roleService struct {
 	roleRepo       repository.RoleRepository
 	permissionRepo repository.PermissionRepository
+	orgID          uint
+	operator       *auth.PermissionSet
 }
 
 // NewRoleService 创建角色服务实例
@@ -32,6 +79,27 @@ func NewRoleService(roleRepo repository.RoleRepository, permissionRepo repositor
 	}
 }
 
+// ForOrganization 返回管理指定组织角色的服务，orgID 为0时管理全局角色
+func (s *roleService) ForOrganization(orgID uint) RoleService {
+	if orgID == 0 {
+		return s
+	}
+	return &roleService{
+		roleRepo:       s.roleRepo.ForOrganization(orgID),
+		permissionRepo: s.permissionRepo,
+		orgID:          orgID,
+		operator:       s.operator,
+	}
+}
+
+// ForOperator 返回以指定操作者的有效权限修改权限和继承关系的服务，
+// 操作者只能为角色新增自己能够授予的权限，只能将自己拥有其全部权限的角色设为上级角色。
+func (s *roleService) ForOperator(permissions auth.PermissionSet) RoleService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
 // Create 创建角色
 func (s *roleService) Create(role *model.Role) error {
 	// 检查角色名是否已存在
@@ -62,8 +130,11 @@ func (s *roleService) Update(role *model.Role) error {
 		return fmt.Errorf("角色不存在: %w", err)
 	}
 
-	// 如果角色名已更改，检查新名称是否已存在
+	// 如果角色名已更改，检查新名称是否已存在，系统管理员角色不能重命名
 	if existingRole.Name != role.Name {
+		if existingRole.OrganizationID == nil && existingRole.Name == model.AdminRoleName {
+			return ErrAdminRoleProtected
+		}
 		_, err := s.roleRepo.GetByName(role.Name)
 		if err == nil {
 			return errors.New("角色名已存在")
@@ -91,22 +162,168 @@ func (s *roleService) List(page, pageSize int) ([]model.Role, int64, error) {
 	return s.roleRepo.List(page, pageSize)
 }
 
-// AssignPermissions 分配权限到角色
+// AssignPermissions 分配权限到角色，组织角色只能拥有 model.OrgAdminPermissions 范围内的权限；
+// 操作者必须能够授予角色新增的每个权限
 func (s *roleService) AssignPermissions(roleID uint, permissionIDs []uint) error {
+	role, permissions, err := s.checkAssignment(roleID, permissionIDs)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(role.Permissions))
+	for _, perm := range role.Permissions {
+		current[perm.Code] = true
+	}
+	for _, perm := range permissions {
+		if s.orgID != 0 && !auth.HasPermission(model.OrgAdminPermissions, perm.Code) {
+			return fmt.Errorf("%w: %s", ErrPlatformPermission, perm.Code)
+		}
+		if s.operator != nil && !current[perm.Code] && !s.operator.CanGrant(perm.Code) {
+			return fmt.Errorf("%w: 权限 %s", ErrPrivilegeEscalation, perm.Code)
+		}
+	}
+
+	// 分配权限
+	return s.roleRepo.AssignPermissions(roleID, permissionIDs)
+}
+
+// AssignDeniedPermissions 替换角色显式拒绝的权限，拒绝的权限被下级角色继承，并优先于其他角色授予的权限
+func (s *roleService) AssignDeniedPermissions(roleID uint, permissionIDs []uint) error {
+	if _, _, err := s.checkAssignment(roleID, permissionIDs); err != nil {
+		return err
+	}
+
+	// 分配拒绝的权限
+	return s.roleRepo.AssignDeniedPermissions(roleID, permissionIDs)
+}
+
+// checkAssignment 检查角色和要分配的权限是否存在，返回角色和这些权限
+func (s *roleService) checkAssignment(roleID uint, permissionIDs []uint) (*model.Role, []model.Permission, error) {
 	// 检查角色是否存在
-	_, err := s.roleRepo.GetByID(roleID)
+	role, err := s.roleRepo.GetByID(roleID)
 	if err != nil {
-		return fmt.Errorf("角色不存在: %w", err)
+		return nil, nil, fmt.Errorf("角色不存在: %w", err)
 	}
 
 	// 检查所有权限是否存在
+	permissions := make([]model.Permission, 0, len(permissionIDs))
 	for _, permID := range permissionIDs {
-		_, err := s.permissionRepo.GetByID(permID)
+		perm, err := s.permissionRepo.GetByID(permID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("权限ID %d 不存在: %w", permID, err)
+		}
+		permissions = append(permissions, *perm)
+	}
+	return role, permissions, nil
+}
+
+// SetParents 设置角色的上级角色，操作者必须拥有上级角色的全部权限（包括其继承的权限）
+func (s *roleService) SetParents(roleID uint, parentIDs []uint) error {
+	parents := make([]model.Role, 0, len(parentIDs))
+	for _, parentID := range parentIDs {
+		if parentID == roleID {
+			return ErrRoleCycle
+		}
+		parent, err := s.roleRepo.GetByID(parentID)
 		if err != nil {
-			return fmt.Errorf("权限ID %d 不存在: %w", permID, err)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: ID %d", ErrRoleNotFound, parentID)
+			}
+			return fmt.Errorf("获取角色失败: %w", err)
 		}
+		parents = append(parents, *parent)
 	}
 
-	// 分配权限
-	return s.roleRepo.AssignPermissions(roleID, permissionIDs)
+	// 继承上级角色等同于获得其全部权限
+	if err := checkGrantable(s.operator, parents); err != nil {
+		return err
+	}
+	return s.roleRepo.SetParents(roleID, parentIDs)
+}
+
+// GetPermissions 获取角色的直接权限和从上级角色继承的权限，以及直接和继承的拒绝权限
+func (s *roleService) GetPermissions(roleID uint) (*RolePermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	denied := role.DeniedPermissions
+	if denied == nil {
+		denied = []model.Permission{}
+	}
+	return &RolePermissions{
+		RoleID:          role.ID,
+		RoleName:        role.Name,
+		Direct:          role.Permissions,
+		Inherited:       inheritedPermissions(role, func(r model.Role) []model.Permission { return r.Permissions }),
+		Denied:          denied,
+		InheritedDenied: inheritedPermissions(role, func(r model.Role) []model.Permission { return r.DeniedPermissions }),
+	}, nil
+}
+
+// inheritedPermissions 汇总从上级角色继承的权限及其来源，角色直接拥有的权限不再列为继承权限
+func inheritedPermissions(role *model.Role, permissionsOf func(r model.Role) []model.Permission) []InheritedPermission {
+	inherited := []InheritedPermission{}
+
+	direct := make(map[string]bool)
+	for _, perm := range permissionsOf(*role) {
+		direct[perm.Code] = true
+	}
+
+	index := make(map[string]int)
+	for _, ancestor := range role.Ancestors() {
+		for _, perm := range permissionsOf(ancestor) {
+			if direct[perm.Code] {
+				continue
+			}
+			if i, ok := index[perm.Code]; ok {
+				inherited[i].InheritedFrom = append(inherited[i].InheritedFrom, ancestor.Name)
+				continue
+			}
+			index[perm.Code] = len(inherited)
+			inherited = append(inherited, InheritedPermission{Permission: perm, InheritedFrom: []string{ancestor.Name}})
+		}
+	}
+	return inherited
+}
+
+// checkGrantable 检查操作者能否授予角色的全部权限（包括继承的权限），operator 为 nil 时不检查
+func checkGrantable(operator *auth.PermissionSet, roles []model.Role) error {
+	if operator == nil {
+		return nil
+	}
+	for _, role := range roles {
+		for _, permission := range role.EffectivePermissions() {
+			if !operator.CanGrant(permission.Code) {
+				return fmt.Errorf("%w: 角色 %s 的权限 %s", ErrPrivilegeEscalation, role.Name, permission.Code)
+			}
+		}
+	}
+	return nil
+}
+
+// Hierarchy 获取全部角色的继承关系
+func (s *roleService) Hierarchy() ([]RoleNode, error) {
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]RoleNode, len(roles))
+	index := make(map[uint]int, len(roles))
+	for i, role := range roles {
+		nodes[i] = RoleNode{ID: role.ID, Name: role.Name, Description: role.Description, ParentIDs: []uint{}, ChildIDs: []uint{}}
+		index[role.ID] = i
+	}
+	for _, role := range roles {
+		for _, parent := range role.Parents {
+			nodes[index[role.ID]].ParentIDs = append(nodes[index[role.ID]].ParentIDs, parent.ID)
+			if i, ok := index[parent.ID]; ok {
+				nodes[i].ChildIDs = append(nodes[i].ChildIDs, role.ID)
+			}
+		}
+	}
+
+	return nodes, nil
 }
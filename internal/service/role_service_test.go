@@ -0,0 +1,59 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakePermissions 按ID返回测试权限的权限存储库
+type fakePermissions struct {
+	repository.PermissionRepository
+	permissions map[uint]string
+}
+
+func (r *fakePermissions) GetByID(id uint) (*model.Permission, error) {
+	code, ok := r.permissions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.Permission{ID: id, Code: code}, nil
+}
+
+func TestAssignPermissionsRequiresGrantable(t *testing.T) {
+	permissions := &fakePermissions{permissions: map[uint]string{1: "*", 2: "user:list", 3: "user:read", 4: "role:assign"}}
+
+	tests := []struct {
+		name          string
+		operator      []string
+		denied        []string
+		permissionIDs []uint
+		wantErr       error
+	}{
+		{"cannot add wildcard", []string{"role:assign", "user:list"}, nil, []uint{2, 1}, ErrPrivilegeEscalation},
+		{"cannot add unheld permission", []string{"role:assign", "user:list"}, nil, []uint{2, 3}, ErrPrivilegeEscalation},
+		{"keeps existing unheld permission", []string{"role:assign"}, nil, []uint{2}, nil},
+		{"adds held permission", []string{"role:assign", "user:read"}, nil, []uint{2, 3, 4}, nil},
+		{"admin adds wildcard", []string{"*"}, nil, []uint{1}, nil},
+		{"denied permission cannot be added", []string{"*"}, []string{"role:assign"}, []uint{4}, ErrPrivilegeEscalation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := newFakeRoles()
+			s := NewRoleService(roles, permissions).ForOperator(auth.NewPermissionSet(tt.operator, tt.denied))
+
+			// 角色2 viewer 已直接拥有 user:list
+			err := s.AssignPermissions(2, tt.permissionIDs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AssignPermissions() error = %v; want %v", err, tt.wantErr)
+			}
+			if _, assigned := roles.assigned[2]; assigned != (tt.wantErr == nil) {
+				t.Errorf("permissions assigned = %v", assigned)
+			}
+		})
+	}
+}
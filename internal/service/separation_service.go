@@ -0,0 +1,150 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSeparationViolation 违反职责分离约束
+	ErrSeparationViolation = repository.ErrSeparationViolation
+	// ErrInvalidConstraint 职责分离约束定义无效
+	ErrInvalidConstraint = errors.New("无效的职责分离约束")
+)
+
+// SeparationViolation 违反的约束及涉及的角色
+type SeparationViolation = repository.SeparationViolation
+
+// SeparationError 违反职责分离约束
+type SeparationError = repository.SeparationError
+
+// SeparationConstraintRequest 创建或更新职责分离约束请求
+type SeparationConstraintRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"omitempty,max=200"`
+	Type        string `json:"type" binding:"required,oneof=static dynamic"`
+	MaxRoles    int    `json:"max_roles"` // 为0时默认为1，即角色互斥
+	RoleIDs     []uint `json:"role_ids" binding:"required"`
+}
+
+// SeparationService 职责分离服务接口
+type SeparationService interface {
+	Create(req SeparationConstraintRequest) (*model.SeparationConstraint, error)
+	GetByID(id uint) (*model.SeparationConstraint, error)
+	Update(id uint, req SeparationConstraintRequest) (*model.SeparationConstraint, error)
+	Delete(id uint) error
+	List(page, pageSize int) ([]model.SeparationConstraint, int64, error)
+	CheckAssignment(roleIDs []uint) error
+	CheckSession(roleIDs []uint) error
+}
+
+// separationService 职责分离服务实现
+type separationService struct {
+	separationRepo repository.SeparationRepository
+}
+
+// NewSeparationService 创建职责分离服务实例
+func NewSeparationService(separationRepo repository.SeparationRepository) SeparationService {
+	return &separationService{separationRepo: separationRepo}
+}
+
+// Create 创建职责分离约束
+func (s *separationService) Create(req SeparationConstraintRequest) (*model.SeparationConstraint, error) {
+	constraint := model.SeparationConstraint{}
+	if err := applyConstraintRequest(&constraint, req); err != nil {
+		return nil, err
+	}
+
+	// 检查约束名称是否已存在
+	if _, err := s.separationRepo.GetByName(req.Name); err == nil {
+		return nil, errors.New("约束名称已存在")
+	}
+
+	if err := s.separationRepo.Create(&constraint, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	return s.separationRepo.GetByID(constraint.ID)
+}
+
+// GetByID 根据ID获取职责分离约束
+func (s *separationService) GetByID(id uint) (*model.SeparationConstraint, error) {
+	return s.separationRepo.GetByID(id)
+}
+
+// Update 更新职责分离约束，已分配的角色不受影响，新的约束在下次分配角色或签发令牌时生效
+func (s *separationService) Update(id uint, req SeparationConstraintRequest) (*model.SeparationConstraint, error) {
+	constraint, err := s.separationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果约束名称已更改，检查新名称是否已存在
+	if constraint.Name != req.Name {
+		if _, err := s.separationRepo.GetByName(req.Name); err == nil {
+			return nil, errors.New("约束名称已存在")
+		}
+	}
+
+	if err := applyConstraintRequest(constraint, req); err != nil {
+		return nil, err
+	}
+	if err := s.separationRepo.Update(constraint, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	return s.separationRepo.GetByID(id)
+}
+
+// Delete 删除职责分离约束
+func (s *separationService) Delete(id uint) error {
+	return s.separationRepo.Delete(id)
+}
+
+// List 获取职责分离约束列表
+func (s *separationService) List(page, pageSize int) ([]model.SeparationConstraint, int64, error) {
+	return s.separationRepo.List(page, pageSize)
+}
+
+// CheckAssignment 检查分配给用户的全部角色是否违反静态约束
+func (s *separationService) CheckAssignment(roleIDs []uint) error {
+	return s.check(roleIDs, model.SeparationStatic)
+}
+
+// CheckSession 检查会话中生效的角色是否违反约束，静态和动态约束都会检查
+func (s *separationService) CheckSession(roleIDs []uint) error {
+	return s.check(roleIDs, model.SeparationStatic, model.SeparationDynamic)
+}
+
+// check 按指定类型的约束检查角色集合，角色按上级角色展开
+func (s *separationService) check(roleIDs []uint, types ...string) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+
+	violations, err := s.separationRepo.Violations(roleIDs, types...)
+	if err != nil {
+		return fmt.Errorf("检查职责分离约束失败: %w", err)
+	}
+	if len(violations) > 0 {
+		return &SeparationError{Violations: violations}
+	}
+	return nil
+}
+
+// applyConstraintRequest 校验请求并填充约束
+func applyConstraintRequest(constraint *model.SeparationConstraint, req SeparationConstraintRequest) error {
+	maxRoles := req.MaxRoles
+	if maxRoles == 0 {
+		maxRoles = 1
+	}
+	if maxRoles < 0 || maxRoles >= len(req.RoleIDs) {
+		return fmt.Errorf("%w: max_roles 必须小于角色数量", ErrInvalidConstraint)
+	}
+
+	constraint.Name = req.Name
+	constraint.Description = req.Description
+	constraint.Type = req.Type
+	constraint.MaxRoles = maxRoles
+	return nil
+}
@@ -3,28 +3,228 @@ package service
 import (
 	"authentication/internal/model"
 	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleNotFound 要分配的角色不存在
+	ErrRoleNotFound = repository.ErrRoleNotFound
+	// ErrLastAdmin 操作会导致系统中没有管理员
+	ErrLastAdmin = repository.ErrLastAdmin
+	// ErrUserNotDeleted 用户未被删除
+	ErrUserNotDeleted = repository.ErrUserNotDeleted
+	// ErrUserConflict 用户名或邮箱已被其他用户使用
+	ErrUserConflict = repository.ErrUserConflict
+	// ErrSharedAccount 用户不只属于当前组织，其账号只能在全局范围内修改
+	ErrSharedAccount = errors.New("用户拥有全局角色或属于其他组织，只能在全局范围内修改其账号")
 )
 
+// CreateUserRequest 管理员创建用户请求
+type CreateUserRequest struct {
+	Username      string           `json:"username" binding:"required,min=3,max=50"`
+	Email         string           `json:"email" binding:"required,email"`
+	Password      string           `json:"password" binding:"required"`
+	FullName      string           `json:"full_name"`
+	Department    string           `json:"department" binding:"omitempty,max=100"`
+	Attributes    model.Attributes `json:"attributes"` // 访问控制策略使用的自定义属性
+	Active        *bool            `json:"active"`
+	EmailVerified bool             `json:"email_verified"` // 为true时视为邮箱已验证，不发送验证邮件
+	RoleIDs       []uint           `json:"role_ids"`
+}
+
+// AssignRolesRequest 分配用户角色请求
+type AssignRolesRequest struct {
+	RoleIDs []uint `json:"role_ids" binding:"required"`
+}
+
+// ImportUserRequest 导入用户请求，密码为旧系统中的哈希
+type ImportUserRequest struct {
+	Username          string     `json:"username" binding:"required,min=3,max=50"`
+	Email             string     `json:"email" binding:"required,email"`
+	FullName          string     `json:"full_name"`
+	PasswordHash      string     `json:"password_hash" binding:"required"`
+	Active            *bool      `json:"active"`
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	EmailVerified     bool       `json:"email_verified"` // 旧系统中已验证邮箱时为true，否则导入后发送验证邮件
+}
+
+// ImportUsersRequest 批量导入用户请求
+type ImportUsersRequest struct {
+	Users []ImportUserRequest `json:"users" binding:"required,dive"`
+}
+
+// ImportFailure 导入失败的用户
+type ImportFailure struct {
+	Username string `json:"username"`
+	Error    string `json:"error"`
+}
+
+// ImportUsersResult 批量导入结果
+type ImportUsersResult struct {
+	Imported int             `json:"imported"`
+	Failed   []ImportFailure `json:"failed"`
+}
+
 // UserService 用户服务接口
 type UserService interface {
+	Create(req CreateUserRequest) (*model.User, error)
 	GetByID(id uint) (*model.User, error)
 	List(page, pageSize int) ([]model.User, int64, error)
 	Update(user *model.User) error
-	Delete(id uint) error
+	Delete(id uint, deletedBy uint, reason string) error
+	Restore(id uint) error
+	Purge(id uint) error
+	ListDeleted(page, pageSize int) ([]model.User, int64, error)
+	ResetPassword(id uint, password string) error
+	Import(req ImportUsersRequest) (*ImportUsersResult, error)
+	GetLockoutStatus(id uint) (*LockoutStatus, error)
+	Unlock(id uint) error
+	SetRoles(id uint, roleIDs []uint) (*model.User, error)
+	AddRoles(id uint, roleIDs []uint) (*model.User, error)
+	RemoveRoles(id uint, roleIDs []uint) (*model.User, error)
+	ForOrganization(orgID uint) UserService
+	ForOperator(permissions auth.PermissionSet) UserService
 }
 
 // userService 用户服务实现
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo            repository.UserRepository
+	roleRepo            repository.RoleRepository
+	passwordService     PasswordService
+	verificationService VerificationService
+	loginProtector      LoginProtector
+	separationService   SeparationService
+	orgID               uint
+	operator            *auth.PermissionSet
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, passwordService PasswordService, verificationService VerificationService, loginProtector LoginProtector, separationService SeparationService) UserService {
+	return &userService{
+		userRepo:            userRepo,
+		roleRepo:            roleRepo,
+		passwordService:     passwordService,
+		verificationService: verificationService,
+		loginProtector:      loginProtector,
+		separationService:   separationService,
+	}
+}
+
+// ForOrganization 返回只管理指定组织成员的服务，orgID 为0时不限制
+// 此时创建的用户会加入该组织，角色操作作用于成员在该组织中的角色。
+func (s *userService) ForOrganization(orgID uint) UserService {
+	if orgID == 0 {
+		return s
+	}
 	return &userService{
-		userRepo: userRepo,
+		userRepo:            s.userRepo.ForOrganization(orgID),
+		roleRepo:            s.roleRepo.ForOrganization(orgID),
+		passwordService:     s.passwordService,
+		verificationService: s.verificationService,
+		loginProtector:      s.loginProtector,
+		separationService:   s.separationService,
+		orgID:               orgID,
+		operator:            s.operator,
 	}
 }
 
+// ForOperator 返回以指定操作者的有效权限分配和移除角色的服务，
+// 操作者只能分配和移除自己拥有其全部权限（包括继承的权限）的角色。
+func (s *userService) ForOperator(permissions auth.PermissionSet) UserService {
+	scoped := *s
+	scoped.operator = &permissions
+	return &scoped
+}
+
+// Create 管理员创建用户
+func (s *userService) Create(req CreateUserRequest) (*model.User, error) {
+	// 检查用户名是否已存在
+	_, err := s.userRepo.GetByUsername(req.Username)
+	if err == nil {
+		return nil, errors.New("用户名已存在")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("检查用户名失败: %w", err)
+	}
+
+	// 检查邮箱是否已存在
+	_, err = s.userRepo.GetByEmail(req.Email)
+	if err == nil {
+		return nil, errors.New("邮箱已存在")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("检查邮箱失败: %w", err)
+	}
+
+	// 校验密码策略
+	if err := s.passwordService.Validate(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
+	// 检查所有角色是否存在
+	roles := []model.Role{}
+	for _, roleID := range req.RoleIDs {
+		role, err := s.roleRepo.GetByID(roleID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: ID %d", ErrRoleNotFound, roleID)
+			}
+			return nil, fmt.Errorf("获取角色失败: %w", err)
+		}
+		roles = append(roles, *role)
+	}
+
+	// 检查操作者能否授予这些角色
+	if err := checkGrantable(s.operator, roles); err != nil {
+		return nil, err
+	}
+
+	// 检查职责分离约束
+	if err := s.separationService.CheckAssignment(req.RoleIDs); err != nil {
+		return nil, err
+	}
+
+	// 创建用户
+	now := time.Now()
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	user := model.User{
+		Username:          req.Username,
+		Email:             req.Email,
+		FullName:          req.FullName,
+		Department:        req.Department,
+		Attributes:        req.Attributes,
+		Active:            active,
+		Roles:             roles,
+		PasswordChangedAt: &now,
+		EmailVerified:     req.EmailVerified,
+	}
+	if req.EmailVerified {
+		user.VerifiedAt = &now
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		return nil, fmt.Errorf("加密密码失败: %w", err)
+	}
+
+	// 保存用户
+	if err := s.userRepo.Create(&user); err != nil {
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+
+	// 发送验证邮件，失败时用户可以稍后重新发送
+	if err := s.verificationService.SendVerification(&user); err != nil {
+		log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+	}
+
+	return s.userRepo.GetByID(user.ID)
+}
+
 // GetByID 根据ID获取用户
 func (s *userService) GetByID(id uint) (*model.User, error) {
 	return s.userRepo.GetByID(id)
@@ -35,12 +235,219 @@ func (s *userService) List(page, pageSize int) ([]model.User, int64, error) {
 	return s.userRepo.List(page, pageSize)
 }
 
-// Update 更新用户
+// Update 更新用户，不允许停用最后一个管理员；按组织访问时不能修改不只属于该组织的用户的邮箱和启用状态
 func (s *userService) Update(user *model.User) error {
+	if s.orgID != 0 {
+		existing, err := s.userRepo.GetByID(user.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Email != user.Email || existing.Active != user.Active {
+			if err := s.checkOwned(user.ID); err != nil {
+				return err
+			}
+		}
+	}
 	return s.userRepo.Update(user)
 }
 
-// Delete 删除用户
-func (s *userService) Delete(id uint) error {
-	return s.userRepo.Delete(id)
+// Delete 软删除用户，记录操作人及原因
+func (s *userService) Delete(id uint, deletedBy uint, reason string) error {
+	if err := s.checkOwned(id); err != nil {
+		return err
+	}
+	return s.userRepo.Delete(id, deletedBy, reason)
+}
+
+// Restore 恢复已删除的用户
+func (s *userService) Restore(id uint) error {
+	if err := s.checkOwned(id); err != nil {
+		return err
+	}
+	return s.userRepo.Restore(id)
+}
+
+// Purge 彻底删除已软删除的用户
+func (s *userService) Purge(id uint) error {
+	if err := s.checkOwned(id); err != nil {
+		return err
+	}
+	return s.userRepo.Purge(id)
+}
+
+// ListDeleted 获取已删除的用户列表
+func (s *userService) ListDeleted(page, pageSize int) ([]model.User, int64, error) {
+	return s.userRepo.ListDeleted(page, pageSize)
+}
+
+// ResetPassword 管理员重置用户密码
+func (s *userService) ResetPassword(id uint, password string) error {
+	// 获取用户
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %w", err)
+	}
+	if err := s.checkOwned(id); err != nil {
+		return err
+	}
+
+	// 设置新密码
+	return s.passwordService.ChangePassword(user, password)
+}
+
+// GetLockoutStatus 获取用户的登录锁定状态
+func (s *userService) GetLockoutStatus(id uint) (*LockoutStatus, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在: %w", err)
+	}
+
+	status := s.loginProtector.Status(user.Username)
+	return &status, nil
+}
+
+// Unlock 解除用户的登录锁定
+func (s *userService) Unlock(id uint) error {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %w", err)
+	}
+	if err := s.checkOwned(id); err != nil {
+		return err
+	}
+
+	s.loginProtector.Unlock(user.Username)
+	return nil
+}
+
+// SetRoles 将用户的角色替换为指定角色
+func (s *userService) SetRoles(id uint, roleIDs []uint) (*model.User, error) {
+	if err := s.checkRoles(roleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetRoles(id, roleIDs); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(id)
+}
+
+// AddRoles 为用户追加角色
+func (s *userService) AddRoles(id uint, roleIDs []uint) (*model.User, error) {
+	if err := s.checkRoles(roleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.AddRoles(id, roleIDs); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(id)
+}
+
+// RemoveRoles 移除用户的指定角色，操作者只能移除自己能够授予的角色
+func (s *userService) RemoveRoles(id uint, roleIDs []uint) (*model.User, error) {
+	if err := s.checkRoles(roleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.RemoveRoles(id, roleIDs); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(id)
+}
+
+// checkOwned 按组织访问时检查用户是否只属于当前组织，
+// 拥有全局角色或属于其他组织的用户的密码、启用状态、锁定、删除和恢复只能在全局范围内修改
+func (s *userService) checkOwned(id uint) error {
+	shared, err := s.userRepo.IsShared(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return fmt.Errorf("检查用户所属范围失败: %w", err)
+	}
+	if shared {
+		return ErrSharedAccount
+	}
+	return nil
+}
+
+// checkRoles 检查要分配或移除的角色是否存在，以及操作者能否授予这些角色
+func (s *userService) checkRoles(roleIDs []uint) error {
+	if s.operator == nil {
+		return nil
+	}
+	roles := make([]model.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := s.roleRepo.GetByID(roleID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: ID %d", ErrRoleNotFound, roleID)
+			}
+			return fmt.Errorf("获取角色失败: %w", err)
+		}
+		roles = append(roles, *role)
+	}
+	return checkGrantable(s.operator, roles)
+}
+
+// Import 批量导入旧系统用户，保留原密码哈希，用户首次登录成功后会自动升级为当前算法
+func (s *userService) Import(req ImportUsersRequest) (*ImportUsersResult, error) {
+	result := &ImportUsersResult{Failed: []ImportFailure{}}
+	registry := auth.DefaultHasherRegistry()
+
+	for _, item := range req.Users {
+		fail := func(message string) {
+			result.Failed = append(result.Failed, ImportFailure{Username: item.Username, Error: message})
+		}
+
+		// 完整解析密码哈希，拒绝格式错误或参数超出范围的哈希，避免登录时崩溃或耗尽资源
+		if err := registry.Validate(item.PasswordHash); err != nil {
+			fail(err.Error())
+			continue
+		}
+
+		// 检查用户名和邮箱是否已存在
+		if _, err := s.userRepo.GetByUsername(item.Username); err == nil {
+			fail("用户名已存在")
+			continue
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("检查用户名失败: %w", err)
+		}
+		if _, err := s.userRepo.GetByEmail(item.Email); err == nil {
+			fail("邮箱已存在")
+			continue
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("检查邮箱失败: %w", err)
+		}
+
+		// 创建用户
+		active := true
+		if item.Active != nil {
+			active = *item.Active
+		}
+		user := model.User{
+			Username:          item.Username,
+			Email:             item.Email,
+			Password:          item.PasswordHash,
+			FullName:          item.FullName,
+			Active:            active,
+			PasswordChangedAt: item.PasswordChangedAt,
+			EmailVerified:     item.EmailVerified,
+		}
+		if item.EmailVerified {
+			now := time.Now()
+			user.VerifiedAt = &now
+		}
+		if err := s.userRepo.Create(&user); err != nil {
+			fail(fmt.Sprintf("创建用户失败: %v", err))
+			continue
+		}
+
+		// 发送验证邮件，失败时用户可以稍后重新发送
+		if err := s.verificationService.SendVerification(&user); err != nil {
+			log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+		}
+
+		result.Imported++
+	}
+
+	return result, nil
 }
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeRoles 按ID返回测试角色的角色存储库，记录替换的权限
+type fakeRoles struct {
+	repository.RoleRepository
+	roles    map[uint]*model.Role
+	assigned map[uint][]uint
+}
+
+func (r *fakeRoles) GetByID(id uint) (*model.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (r *fakeRoles) AssignPermissions(roleID uint, permissionIDs []uint) error {
+	if r.assigned == nil {
+		r.assigned = make(map[uint][]uint)
+	}
+	r.assigned[roleID] = permissionIDs
+	return nil
+}
+
+// ForOrganization 测试角色不区分组织
+func (r *fakeRoles) ForOrganization(orgID uint) repository.RoleRepository {
+	return r
+}
+
+// newFakeRoles 创建测试角色：1为系统管理员；2为 viewer，继承3 reader 的 user:read 权限
+func newFakeRoles() *fakeRoles {
+	reader := model.Role{ID: 3, Name: "reader", Permissions: []model.Permission{{Code: "user:read"}}}
+	return &fakeRoles{roles: map[uint]*model.Role{
+		1: {ID: 1, Name: model.AdminRoleName, Permissions: []model.Permission{{Code: "*"}}},
+		2: {ID: 2, Name: "viewer", Permissions: []model.Permission{{Code: "user:list"}}, Parents: []model.Role{reader}},
+		3: &reader,
+	}}
+}
+
+// fakeUsers 按ID判断账号是否只属于当前组织的用户存储库，记录创建、恢复的用户和移除的角色
+type fakeUsers struct {
+	repository.UserRepository
+	shared   map[uint]bool
+	restored []uint
+	removed  []uint
+	created  []model.User
+}
+
+func (r *fakeUsers) GetByID(id uint) (*model.User, error) {
+	return &model.User{ID: id}, nil
+}
+
+func (r *fakeUsers) GetByUsername(username string) (*model.User, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUsers) GetByEmail(email string) (*model.User, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUsers) Create(user *model.User) error {
+	user.ID = uint(len(r.created) + 1)
+	r.created = append(r.created, *user)
+	return nil
+}
+
+func (r *fakeUsers) RemoveRoles(id uint, roleIDs []uint) error {
+	r.removed = append(r.removed, roleIDs...)
+	return nil
+}
+
+func (r *fakeUsers) IsShared(id uint) (bool, error) {
+	return r.shared[id], nil
+}
+
+func (r *fakeUsers) Restore(id uint) error {
+	r.restored = append(r.restored, id)
+	return nil
+}
+
+func TestRestoreRequiresOwnedAccount(t *testing.T) {
+	users := &fakeUsers{shared: map[uint]bool{1: true}}
+	s := &userService{userRepo: users, orgID: 3}
+
+	if err := s.Restore(1); !errors.Is(err, ErrSharedAccount) {
+		t.Fatalf("Restore(shared) error = %v; want ErrSharedAccount", err)
+	}
+	if err := s.Restore(2); err != nil {
+		t.Fatalf("Restore(owned) error = %v", err)
+	}
+	if len(users.restored) != 1 || users.restored[0] != 2 {
+		t.Errorf("restored = %v; want [2]", users.restored)
+	}
+}
+
+func TestRemoveRolesRequiresGrantableRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator []string
+		roleIDs  []uint
+		wantErr  error
+	}{
+		{"assign only cannot remove admin", []string{"user:assign"}, []uint{1}, ErrPrivilegeEscalation},
+		{"cannot remove role with unheld inherited permission", []string{"user:assign", "user:list"}, []uint{2}, ErrPrivilegeEscalation},
+		{"removes grantable role", []string{"user:assign", "user:list", "user:read"}, []uint{2, 3}, nil},
+		{"unknown role", []string{"*"}, []uint{9}, ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{}
+			s := NewUserService(users, newFakeRoles(), nil, nil, nil, nil).ForOperator(auth.NewPermissionSet(tt.operator, nil))
+
+			_, err := s.RemoveRoles(5, tt.roleIDs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveRoles() error = %v; want %v", err, tt.wantErr)
+			}
+			if removed := len(users.removed) > 0; removed != (tt.wantErr == nil) {
+				t.Errorf("roles removed = %v", removed)
+			}
+		})
+	}
+}
+
+// fakeVerification 记录需要发送验证邮件的用户
+type fakeVerification struct {
+	VerificationService
+	sent []string
+}
+
+func (s *fakeVerification) SendVerification(user *model.User) error {
+	if !user.EmailVerified {
+		s.sent = append(s.sent, user.Username)
+	}
+	return nil
+}
+
+func TestImportEmailVerification(t *testing.T) {
+	users := &fakeUsers{}
+	verification := &fakeVerification{}
+	s := NewUserService(users, nil, nil, verification, nil, nil)
+
+	const hash = "$sha256$salt$" + "7a37b85c8918eac19a9089c0fa5a2ab4dce3f90528dcdeec108b23ddf3607b99"
+	result, err := s.Import(ImportUsersRequest{Users: []ImportUserRequest{
+		{Username: "verified", Email: "verified@example.com", PasswordHash: hash, EmailVerified: true},
+		{Username: "unverified", Email: "unverified@example.com", PasswordHash: hash},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 {
+		t.Fatalf("imported = %d; failed = %v", result.Imported, result.Failed)
+	}
+
+	verified, unverified := users.created[0], users.created[1]
+	if !verified.EmailVerified || verified.VerifiedAt == nil {
+		t.Errorf("verified user: EmailVerified = %v, VerifiedAt = %v", verified.EmailVerified, verified.VerifiedAt)
+	}
+	if unverified.EmailVerified || unverified.VerifiedAt != nil {
+		t.Errorf("unverified user: EmailVerified = %v, VerifiedAt = %v", unverified.EmailVerified, unverified.VerifiedAt)
+	}
+	if len(verification.sent) != 1 || verification.sent[0] != "unverified" {
+		t.Errorf("verification sent to %v; want [unverified]", verification.sent)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/mailer"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"authentication/pkg/auth"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailNotVerified 邮箱未验证
+	ErrEmailNotVerified = errors.New("邮箱尚未验证")
+	// ErrInvalidVerificationToken 验证令牌无效或已过期
+	ErrInvalidVerificationToken = errors.New("验证链接无效或已过期")
+)
+
+// VerifyEmailRequest 验证邮箱请求
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerificationService 邮箱验证服务接口
+type VerificationService interface {
+	Enabled() bool
+	SendVerification(user *model.User) error
+	Verify(req VerifyEmailRequest) error
+	Resend(req ResendVerificationRequest) error
+	RestrictPermissions(user *model.User, permissions []string) ([]string, error)
+}
+
+// verificationService 邮箱验证服务实现
+type verificationService struct {
+	userRepo         repository.UserRepository
+	verificationRepo repository.EmailVerificationRepository
+	mailer           mailer.Mailer
+	cfg              config.EmailVerificationConfig
+}
+
+// NewVerificationService 创建邮箱验证服务实例
+func NewVerificationService(userRepo repository.UserRepository, verificationRepo repository.EmailVerificationRepository, mailer mailer.Mailer, cfg config.EmailVerificationConfig) VerificationService {
+	return &verificationService{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+// Enabled 是否启用邮箱验证
+func (s *verificationService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// SendVerification 生成验证令牌并发送验证邮件，之前未使用的令牌会失效
+func (s *verificationService) SendVerification(user *model.User) error {
+	if !s.cfg.Enabled || user.EmailVerified {
+		return nil
+	}
+
+	// 生成令牌
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("生成验证令牌失败: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	// 使旧令牌失效并保存新令牌
+	if err := s.verificationRepo.InvalidateByUser(user.ID); err != nil {
+		return fmt.Errorf("清理验证令牌失败: %w", err)
+	}
+	verification := model.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hashVerificationToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(s.cfg.TokenExpire) * time.Hour),
+	}
+	if err := s.verificationRepo.Create(&verification); err != nil {
+		return fmt.Errorf("保存验证令牌失败: %w", err)
+	}
+
+	// 发送邮件
+	link := token
+	if s.cfg.VerifyURL != "" {
+		link = fmt.Sprintf(s.cfg.VerifyURL, token)
+	}
+	body := fmt.Sprintf("%s，您好：\n\n请点击以下链接验证您的邮箱地址，链接%d小时内有效：\n\n%s\n\n如果这不是您本人的操作，请忽略此邮件。\n",
+		user.Username, s.cfg.TokenExpire, link)
+	return s.mailer.Send(user.Email, "请验证您的邮箱地址", body)
+}
+
+// Verify 校验令牌并标记邮箱已验证
+func (s *verificationService) Verify(req VerifyEmailRequest) error {
+	verification, err := s.verificationRepo.GetByTokenHash(hashVerificationToken(req.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("获取验证令牌失败: %w", err)
+	}
+
+	if verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := s.verificationRepo.Consume(verification); err != nil {
+		if errors.Is(err, repository.ErrVerificationUsed) || errors.Is(err, repository.ErrVerificationEmailChanged) {
+			return ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("更新验证令牌失败: %w", err)
+	}
+
+	return nil
+}
+
+// Resend 重新发送验证邮件。邮箱不存在、已验证或发送过于频繁时静默返回，
+// 邮件在后台发送，任何输入的响应和耗时都相同，避免泄露账户信息。
+func (s *verificationService) Resend(req ResendVerificationRequest) error {
+	if !s.cfg.Enabled {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	// 发送频率限制
+	latest, err := s.verificationRepo.GetLatestByUser(user.ID)
+	if err == nil && time.Since(latest.CreatedAt) < time.Duration(s.cfg.ResendInterval)*time.Second {
+		return nil
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("获取验证令牌失败: %w", err)
+	}
+
+	go func() {
+		if err := s.SendVerification(user); err != nil {
+			log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// RestrictPermissions 根据配置处理未验证邮箱用户的权限
+// block 策略返回 ErrEmailNotVerified，restrict 策略只保留配置中允许的权限。
+func (s *verificationService) RestrictPermissions(user *model.User, permissions []string) ([]string, error) {
+	if !s.cfg.Enabled || user.EmailVerified {
+		return permissions, nil
+	}
+
+	switch strings.ToLower(s.cfg.UnverifiedLogin) {
+	case "block":
+		return nil, ErrEmailNotVerified
+	case "restrict":
+		// 保留用户权限与允许列表的交集，两侧都可能包含通配符
+		restricted := []string{}
+		for _, code := range permissions {
+			if auth.HasPermission(s.cfg.RestrictedPermissions, code) {
+				restricted = append(restricted, code)
+			}
+		}
+		for _, code := range s.cfg.RestrictedPermissions {
+			if auth.HasPermission(permissions, code) {
+				restricted = append(restricted, code)
+			}
+		}
+		return restricted, nil
+	default:
+		return permissions, nil
+	}
+}
+
+// hashVerificationToken 计算验证令牌的哈希
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
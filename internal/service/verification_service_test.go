@@ -0,0 +1,51 @@
+package service
+
+import (
+	"authentication/internal/config"
+	"authentication/internal/model"
+	"authentication/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeVerifications 返回固定验证记录的验证令牌存储库，Consume 返回预设错误
+type fakeVerifications struct {
+	repository.EmailVerificationRepository
+	verification model.EmailVerification
+	consumeErr   error
+}
+
+func (r *fakeVerifications) GetByTokenHash(tokenHash string) (*model.EmailVerification, error) {
+	verification := r.verification
+	return &verification, nil
+}
+
+func (r *fakeVerifications) Consume(verification *model.EmailVerification) error {
+	return r.consumeErr
+}
+
+func TestVerifyRejectsConsumeFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		consumeErr error
+		wantErr    error
+	}{
+		{"verified", nil, nil},
+		{"token already used", repository.ErrVerificationUsed, ErrInvalidVerificationToken},
+		{"email changed", repository.ErrVerificationEmailChanged, ErrInvalidVerificationToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifications := &fakeVerifications{
+				verification: model.EmailVerification{UserID: 7, Email: "old@example.com", ExpiresAt: time.Now().Add(time.Hour)},
+				consumeErr:   tt.consumeErr,
+			}
+			s := NewVerificationService(nil, verifications, nil, config.EmailVerificationConfig{Enabled: true})
+
+			if err := s.Verify(VerifyEmailRequest{Token: "token"}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bloomMagic 布隆过滤器文件头
+var bloomMagic = [4]byte{'P', 'W', 'B', 'F'}
+
+// 布隆过滤器文件格式
+const (
+	bloomVersion    = 1       // 文件格式版本
+	bloomHeaderSize = 17      // 文件头长度：魔数、版本、k、m
+	maxBloomHashes  = 64      // 哈希函数个数上限，误判率 1e-18 时约需60个
+	maxBloomBits    = 1 << 37 // 位数上限（16GiB），足够容纳完整的HIBP语料
+)
+
+// BreachChecker 泄露密码检查接口
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// breachDigest 计算密码的SHA-1摘要，与HIBP语料的格式一致
+func breachDigest(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// HashFileChecker 基于已排序的HIBP格式文件（每行 "SHA1:COUNT"）的检查器，
+// 通过对文件做二分查找实现，不需要将语料加载到内存中。
+type HashFileChecker struct {
+	file     *os.File
+	size     int64
+	minCount int
+}
+
+// OpenHashFileChecker 打开已排序的HIBP格式文件
+// minCount 为判定为泄露所需的最小出现次数，小于等于1时只要出现即视为泄露。
+func OpenHashFileChecker(path string, minCount int) (*HashFileChecker, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开泄露密码文件失败: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取泄露密码文件信息失败: %w", err)
+	}
+
+	return &HashFileChecker{file: file, size: info.Size(), minCount: minCount}, nil
+}
+
+// IsBreached 检查密码是否出现在泄露语料中
+func (c *HashFileChecker) IsBreached(password string) (bool, error) {
+	sum := breachDigest(password)
+	count, err := c.lookup(strings.ToUpper(hex.EncodeToString(sum[:])))
+	if err != nil {
+		return false, err
+	}
+	return count > 0 && count >= c.minCount, nil
+}
+
+// Close 关闭文件
+func (c *HashFileChecker) Close() error {
+	return c.file.Close()
+}
+
+// lookup 二分查找指定摘要，返回出现次数，未找到时返回0
+func (c *HashFileChecker) lookup(target string) (int, error) {
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := c.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start < 0 {
+			hi = mid
+			continue
+		}
+
+		hash, count := parseHashLine(line)
+		switch cmp := strings.Compare(hash, target); {
+		case cmp == 0:
+			return count, nil
+		case cmp < 0:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineAt 返回从offset处（含）开始的第一整行及其起始位置，没有更多行时起始位置为-1
+func (c *HashFileChecker) lineAt(offset int64) (int64, string, error) {
+	start := offset
+	reader := bufio.NewReader(io.NewSectionReader(c.file, offset, c.size-offset))
+	if offset > 0 {
+		// 读取前一个字节判断offset是否恰好是行首
+		prev := make([]byte, 1)
+		if _, err := c.file.ReadAt(prev, offset-1); err != nil {
+			return 0, "", fmt.Errorf("读取泄露密码文件失败: %w", err)
+		}
+		if prev[0] != '\n' {
+			skipped, err := reader.ReadString('\n')
+			if err == io.EOF {
+				return -1, "", nil
+			}
+			if err != nil {
+				return 0, "", fmt.Errorf("读取泄露密码文件失败: %w", err)
+			}
+			start += int64(len(skipped))
+		}
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", fmt.Errorf("读取泄露密码文件失败: %w", err)
+	}
+	if line == "" {
+		return -1, "", nil
+	}
+	return start, strings.TrimSuffix(line, "\n"), nil
+}
+
+// parseHashLine 解析 "SHA1:COUNT" 格式的行，缺少次数时视为1
+func parseHashLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+	hash, countStr, found := strings.Cut(line, ":")
+	hash = strings.ToUpper(hash)
+	if !found {
+		return hash, 1
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return hash, 1
+	}
+	return hash, count
+}
+
+// BloomFilter 由泄露语料构建的紧凑布隆过滤器
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint32 // 哈希函数个数
+}
+
+// NewBloomFilter 按预期元素数量和误判率创建布隆过滤器
+func NewBloomFilter(expected uint64, falsePositiveRate float64) *BloomFilter {
+	if expected == 0 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	m := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(m) / float64(expected) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add 添加一个SHA-1摘要
+func (f *BloomFilter) Add(sum [sha1.Size]byte) {
+	h1, h2 := bloomHashes(sum)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Test 检查SHA-1摘要是否可能存在
+func (f *BloomFilter) Test(sum [sha1.Size]byte) bool {
+	h1, h2 := bloomHashes(sum)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsBreached 检查密码是否可能出现在泄露语料中（存在极低的误判率）
+func (f *BloomFilter) IsBreached(password string) (bool, error) {
+	return f.Test(breachDigest(password)), nil
+}
+
+// WriteTo 将过滤器序列化写出
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	var header bytes.Buffer
+	header.Write(bloomMagic[:])
+	header.WriteByte(bloomVersion)
+	binary.Write(&header, binary.LittleEndian, f.k)
+	binary.Write(&header, binary.LittleEndian, f.m)
+
+	n, err := w.Write(header.Bytes())
+	if err != nil {
+		return int64(n), err
+	}
+	if err := binary.Write(w, binary.LittleEndian, f.bits); err != nil {
+		return int64(n), err
+	}
+	return int64(n) + int64(len(f.bits)*8), nil
+}
+
+// ReadBloomFilter 读取序列化的布隆过滤器
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	return readBloomFilter(r, maxBloomBits)
+}
+
+// readBloomFilter 读取序列化的布隆过滤器，位数超过 maxBits 时拒绝，避免损坏的文件头导致巨大的内存分配
+func readBloomFilter(r io.Reader, maxBits uint64) (*BloomFilter, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, fmt.Errorf("读取过滤器文件头失败: %w", err)
+	}
+	if magic != bloomMagic {
+		return nil, errors.New("无效的过滤器文件")
+	}
+
+	var version uint8
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("读取过滤器版本失败: %w", err)
+	}
+	if version != bloomVersion {
+		return nil, fmt.Errorf("不支持的过滤器版本: %d", version)
+	}
+
+	f := &BloomFilter{}
+	if err := binary.Read(r, binary.LittleEndian, &f.k); err != nil {
+		return nil, fmt.Errorf("读取过滤器参数失败: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &f.m); err != nil {
+		return nil, fmt.Errorf("读取过滤器参数失败: %w", err)
+	}
+	if f.k == 0 || f.k > maxBloomHashes || f.m == 0 || f.m > maxBits {
+		return nil, fmt.Errorf("无效的过滤器参数: k=%d, m=%d", f.k, f.m)
+	}
+
+	f.bits = make([]uint64, (f.m+63)/64)
+	if err := binary.Read(r, binary.LittleEndian, f.bits); err != nil {
+		return nil, fmt.Errorf("读取过滤器数据失败: %w", err)
+	}
+	return f, nil
+}
+
+// LoadBloomFilter 从文件加载布隆过滤器
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开过滤器文件失败: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取过滤器文件信息失败: %w", err)
+	}
+	// 位数不能超过文件中实际的数据量
+	maxBits := uint64(maxBloomBits)
+	if data := info.Size() - bloomHeaderSize; data < 0 {
+		maxBits = 0
+	} else if uint64(data)*8 < maxBits {
+		maxBits = uint64(data) * 8
+	}
+
+	return readBloomFilter(bufio.NewReader(file), maxBits)
+}
+
+// BuildBloomFilter 从HIBP格式的语料构建布隆过滤器，出现次数小于minCount的条目会被忽略
+func BuildBloomFilter(r io.Reader, expected uint64, falsePositiveRate float64, minCount int) (*BloomFilter, uint64, error) {
+	filter := NewBloomFilter(expected, falsePositiveRate)
+
+	var added uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, count := parseHashLine(scanner.Text())
+		if hash == "" || count < minCount {
+			continue
+		}
+
+		raw, err := hex.DecodeString(hash)
+		if err != nil || len(raw) != sha1.Size {
+			return nil, added, fmt.Errorf("无效的SHA-1摘要: %q", hash)
+		}
+
+		var sum [sha1.Size]byte
+		copy(sum[:], raw)
+		filter.Add(sum)
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, added, fmt.Errorf("读取语料失败: %w", err)
+	}
+
+	return filter, added, nil
+}
+
+// bloomHashes 从SHA-1摘要中取出两个64位值用于双重哈希
+func bloomHashes(sum [sha1.Size]byte) (uint64, uint64) {
+	h1 := binary.LittleEndian.Uint64(sum[0:8])
+	h2 := binary.LittleEndian.Uint64(sum[8:16]) | 1
+	return h1, h2
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// breachCorpus 测试语料：密码及其在泄露语料中的出现次数
+var breachCorpus = map[string]int{
+	"password": 100,
+	"123456":   50,
+	"qwerty":   3,
+	"letmein":  1,
+	"dragon":   2,
+}
+
+// writeHashFile 按HIBP格式写出已排序的语料，minimal 为 true 的条目不带次数
+func writeHashFile(t *testing.T, corpus map[string]int, minimal map[string]bool) string {
+	t.Helper()
+	var lines []string
+	for password, count := range corpus {
+		sum := breachDigest(password)
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		if minimal[password] {
+			lines = append(lines, hash)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s:%d", hash, count))
+		}
+	}
+	// 填充大量其他摘要，使二分查找经过多次折半
+	for i := 0; i < 500; i++ {
+		sum := breachDigest(fmt.Sprintf("filler-%d", i))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":7")
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHashFileChecker(t *testing.T) {
+	path := writeHashFile(t, breachCorpus, map[string]bool{"letmein": true})
+
+	tests := []struct {
+		minCount int
+		password string
+		want     bool
+	}{
+		{0, "password", true},
+		{0, "letmein", true},
+		{0, "filler-0", true},
+		{0, "filler-499", true},
+		{0, "correct horse battery staple", false},
+		{3, "qwerty", true},
+		{3, "dragon", false},
+		{3, "letmein", false},
+		{100, "password", true},
+		{101, "password", false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.password, tt.minCount), func(t *testing.T) {
+			checker, err := OpenHashFileChecker(path, tt.minCount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer checker.Close()
+
+			got, err := checker.IsBreached(tt.password)
+			if err != nil || got != tt.want {
+				t.Fatalf("IsBreached(%q) = %v, %v; want %v", tt.password, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestHashFileCheckerFindsEveryLine 语料中的每一行都能被找到，包括首行和末行
+func TestHashFileCheckerFindsEveryLine(t *testing.T) {
+	path := writeHashFile(t, breachCorpus, nil)
+	checker, err := OpenHashFileChecker(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checker.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		hash, want := parseHashLine(line)
+		if got, err := checker.lookup(hash); err != nil || got != want {
+			t.Fatalf("lookup(%s) = %d, %v; want %d", hash, got, err, want)
+		}
+	}
+	for _, target := range []string{strings.Repeat("0", 40), strings.Repeat("F", 40)} {
+		if got, err := checker.lookup(target); err != nil || got != 0 {
+			t.Errorf("lookup(%s) = %d, %v; want 0", target, got, err)
+		}
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	var corpus strings.Builder
+	for password, count := range breachCorpus {
+		sum := breachDigest(password)
+		fmt.Fprintf(&corpus, "%X:%d\n", sum, count)
+	}
+	filter, added, err := BuildBloomFilter(strings.NewReader(corpus.String()), 100, 0.001, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 4 {
+		t.Fatalf("added %d entries; want 4", added)
+	}
+
+	var buf bytes.Buffer
+	if _, err := filter.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "breach.bloom")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBloomFilter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for password, count := range breachCorpus {
+		want := count >= 2
+		for _, f := range []*BloomFilter{filter, loaded} {
+			// 布隆过滤器不会漏报，语料很小时也不会误报
+			if got, _ := f.IsBreached(password); got != want {
+				t.Errorf("IsBreached(%q) = %v; want %v", password, got, want)
+			}
+		}
+	}
+	if got, _ := loaded.IsBreached("correct horse battery staple"); got {
+		t.Error("IsBreached() = true for a password not in the corpus")
+	}
+}
+
+func TestReadBloomFilterRejectsInvalidHeaders(t *testing.T) {
+	header := func(k uint32, m uint64) []byte {
+		var buf bytes.Buffer
+		buf.Write(bloomMagic[:])
+		buf.WriteByte(bloomVersion)
+		binary.Write(&buf, binary.LittleEndian, k)
+		binary.Write(&buf, binary.LittleEndian, m)
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad magic", []byte("XXXX")},
+		{"bad version", append(bloomMagic[:], 9)},
+		{"zero hashes", header(0, 64)},
+		{"too many hashes", header(maxBloomHashes+1, 64)},
+		{"zero bits", header(3, 0)},
+		{"too many bits", header(3, maxBloomBits+1)},
+		{"overflowing bits", header(3, ^uint64(0))},
+		{"more bits than the file", header(3, 1<<20)},
+		{"truncated data", append(header(3, 128), make([]byte, 8)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breach.bloom")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadBloomFilter(path); err == nil {
+				t.Fatal("LoadBloomFilter() error = nil")
+			}
+			if _, err := ReadBloomFilter(bytes.NewReader(tt.data)); err == nil {
+				t.Fatal("ReadBloomFilter() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownHashFormat 无法识别的密码哈希格式
+var ErrUnknownHashFormat = errors.New("无法识别的密码哈希格式")
+
+// PasswordHasher 密码哈希算法接口
+type PasswordHasher interface {
+	// ID 算法标识，与PHC字符串中的算法名一致
+	ID() string
+	// Identify 判断编码后的哈希是否属于该算法
+	Identify(encoded string) bool
+	// Hash 计算密码哈希，返回PHC格式字符串
+	Hash(password []byte) (string, error)
+	// Verify 校验密码是否与哈希匹配
+	Verify(password []byte, encoded string) (bool, error)
+	// Validate 完整解析哈希并检查参数是否在允许范围内
+	Validate(encoded string) error
+	// NeedsRehash 哈希参数是否与当前配置不一致
+	NeedsRehash(encoded string) bool
+}
+
+// HasherRegistry 密码哈希算法注册表
+// 新密码总是使用当前算法计算，校验时根据哈希格式选择对应算法，
+// 算法或参数过时的哈希会在校验成功后提示需要重新计算。
+type HasherRegistry struct {
+	current PasswordHasher
+	hashers []PasswordHasher
+	pepper  []byte
+
+	// 轮换pepper期间，使用当前pepper校验失败后再用 previous 校验，previous 为 nil 表示此前未启用pepper
+	rotating bool
+	previous []byte
+}
+
+// NewHasherRegistry 创建哈希算法注册表
+// pepper 为服务端密钥，不为空时会先用HMAC-SHA256对密码做一次处理。
+func NewHasherRegistry(current PasswordHasher, pepper string, others ...PasswordHasher) *HasherRegistry {
+	r := &HasherRegistry{current: current}
+	if pepper != "" {
+		r.pepper = []byte(pepper)
+	}
+	r.Register(current)
+	for _, h := range others {
+		r.Register(h)
+	}
+	return r
+}
+
+// SetPreviousPepper 开始轮换pepper：当前pepper校验失败时再用轮换前的pepper校验，成功后提示重新计算哈希。
+// previous 为空表示此前未启用pepper。未调用时不回退，失败的登录只计算一次哈希。
+func (r *HasherRegistry) SetPreviousPepper(previous string) {
+	r.rotating = true
+	r.previous = nil
+	if previous != "" {
+		r.previous = []byte(previous)
+	}
+}
+
+// Register 注册一个可用于校验的哈希算法，已注册同名算法时忽略
+func (r *HasherRegistry) Register(h PasswordHasher) {
+	for _, existing := range r.hashers {
+		if existing.ID() == h.ID() {
+			return
+		}
+	}
+	r.hashers = append(r.hashers, h)
+}
+
+// Current 返回当前用于计算新哈希的算法
+func (r *HasherRegistry) Current() PasswordHasher {
+	return r.current
+}
+
+// Identify 根据哈希格式查找对应算法
+func (r *HasherRegistry) Identify(encoded string) (PasswordHasher, error) {
+	for _, h := range r.hashers {
+		if h.Identify(encoded) {
+			return h, nil
+		}
+	}
+	return nil, ErrUnknownHashFormat
+}
+
+// Validate 查找哈希对应的算法并完整校验其格式和参数，用于导入外部哈希
+func (r *HasherRegistry) Validate(encoded string) error {
+	h, err := r.Identify(encoded)
+	if err != nil {
+		return err
+	}
+	return h.Validate(encoded)
+}
+
+// Hash 使用当前算法计算密码哈希
+func (r *HasherRegistry) Hash(password string) (string, error) {
+	return r.current.Hash(r.peppered(password))
+}
+
+// Verify 校验密码，返回是否匹配以及是否需要用当前算法重新计算哈希；旧系统算法的哈希使用不加pepper的原密码校验
+func (r *HasherRegistry) Verify(password, encoded string) (bool, bool) {
+	h, err := r.Identify(encoded)
+	if err != nil {
+		return false, false
+	}
+
+	needsRehash := h.ID() != r.current.ID() || r.current.NeedsRehash(encoded)
+
+	// 旧系统的哈希在导入前没有加入pepper，使用原密码校验，校验成功后总会重新计算
+	if isLegacy(h) {
+		ok, err := h.Verify([]byte(password), encoded)
+		return err == nil && ok, true
+	}
+
+	ok, err := h.Verify(r.peppered(password), encoded)
+	if err == nil && ok {
+		return true, needsRehash
+	}
+
+	// 轮换pepper之前生成的哈希使用旧的pepper，校验成功后需要重新计算
+	if r.rotating && err == nil {
+		if ok, err := h.Verify(pepper(r.previous, password), encoded); err == nil && ok {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// isLegacy 判断是否为只用于校验导入哈希的旧系统算法
+func isLegacy(h PasswordHasher) bool {
+	switch h.(type) {
+	case *SaltedSHA256Hasher, *PBKDF2Hasher, *ScryptHasher, *MD5CryptHasher:
+		return true
+	}
+	return false
+}
+
+// peppered 返回加入当前pepper后的密码
+func (r *HasherRegistry) peppered(password string) []byte {
+	return pepper(r.pepper, password)
+}
+
+// pepper 返回使用 key 做HMAC处理后的密码，key 为 nil 时返回原密码
+func pepper(key []byte, password string) []byte {
+	if key == nil {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+var (
+	defaultRegistryMu sync.RWMutex
+	defaultRegistry   = NewHasherRegistry(NewBcryptHasher(bcrypt.DefaultCost), "", append([]PasswordHasher{NewArgon2idHasher(DefaultArgon2idParams)}, LegacyHashers()...)...)
+)
+
+// SetDefaultHasherRegistry 设置全局默认的哈希算法注册表，应在启动时调用
+func SetDefaultHasherRegistry(r *HasherRegistry) {
+	defaultRegistryMu.Lock()
+	defer defaultRegistryMu.Unlock()
+	defaultRegistry = r
+}
+
+// DefaultHasherRegistry 返回全局默认的哈希算法注册表
+func DefaultHasherRegistry() *HasherRegistry {
+	defaultRegistryMu.RLock()
+	defer defaultRegistryMu.RUnlock()
+	return defaultRegistry
+}
+
+// maxBcryptCost 允许校验的最大bcrypt成本因子，更高的成本会使单次登录占用数秒以上的CPU
+const maxBcryptCost = 16
+
+// BcryptHasher bcrypt算法
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 创建bcrypt算法实例
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// ID 算法标识
+func (h *BcryptHasher) ID() string {
+	return "bcrypt"
+}
+
+// Identify 判断是否为bcrypt哈希（$2a$、$2b$、$2y$）
+func (h *BcryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Hash 计算bcrypt哈希
+func (h *BcryptHasher) Hash(password []byte) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword(password, h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验bcrypt哈希
+func (h *BcryptHasher) Verify(password []byte, encoded string) (bool, error) {
+	if err := h.Validate(encoded); err != nil {
+		return false, err
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), password)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Validate 检查bcrypt哈希的格式和成本因子
+func (h *BcryptHasher) Validate(encoded string) error {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return fmt.Errorf("无效的bcrypt哈希: %w", err)
+	}
+	if len(encoded) != 60 {
+		return errors.New("无效的bcrypt哈希: 长度应为60")
+	}
+	if cost > maxBcryptCost {
+		return fmt.Errorf("%w: bcrypt成本因子应不大于%d", ErrInvalidHashParams, maxBcryptCost)
+	}
+	return nil
+}
+
+// NeedsRehash 成本因子与配置不一致时需要重新计算
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+// Argon2idParams argon2id算法参数
+type Argon2idParams struct {
+	Memory      uint32 // 内存大小（KiB）
+	Iterations  uint32 // 迭代次数
+	Parallelism uint8  // 并行度
+	SaltLength  uint32 // 盐长度（字节）
+	KeyLength   uint32 // 输出长度（字节）
+}
+
+// argon2id参数的上下限，超出范围的哈希拒绝校验，避免伪造或导入的哈希导致崩溃或耗尽内存
+const (
+	maxArgon2idMemory     = 1024 * 1024 // 1 GiB
+	maxArgon2idIterations = 16
+	minArgon2idSaltLength = 8
+	maxArgon2idSaltLength = 64
+	minArgon2idKeyLength  = 16
+	maxArgon2idKeyLength  = 128
+)
+
+// ErrInvalidHashParams 哈希参数超出允许范围
+var ErrInvalidHashParams = errors.New("密码哈希参数超出允许范围")
+
+// DefaultArgon2idParams OWASP推荐的argon2id默认参数
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Validate 检查参数是否在允许范围内，内存至少为每个并行通道8 KiB
+func (p Argon2idParams) Validate() error {
+	switch {
+	case p.Parallelism == 0:
+		return fmt.Errorf("%w: argon2id并行度不能为0", ErrInvalidHashParams)
+	case p.Iterations == 0 || p.Iterations > maxArgon2idIterations:
+		return fmt.Errorf("%w: argon2id迭代次数应为1-%d", ErrInvalidHashParams, maxArgon2idIterations)
+	case p.Memory < 8*uint32(p.Parallelism) || p.Memory > maxArgon2idMemory:
+		return fmt.Errorf("%w: argon2id内存应为%d-%d KiB", ErrInvalidHashParams, 8*uint32(p.Parallelism), maxArgon2idMemory)
+	case p.SaltLength < minArgon2idSaltLength || p.SaltLength > maxArgon2idSaltLength:
+		return fmt.Errorf("%w: argon2id盐长度应为%d-%d字节", ErrInvalidHashParams, minArgon2idSaltLength, maxArgon2idSaltLength)
+	case p.KeyLength < minArgon2idKeyLength || p.KeyLength > maxArgon2idKeyLength:
+		return fmt.Errorf("%w: argon2id输出长度应为%d-%d字节", ErrInvalidHashParams, minArgon2idKeyLength, maxArgon2idKeyLength)
+	}
+	return nil
+}
+
+// Argon2idHasher argon2id算法，输出格式为
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher 创建argon2id算法实例
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2idParams.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2idParams.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2idParams.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = DefaultArgon2idParams.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = DefaultArgon2idParams.KeyLength
+	}
+	return &Argon2idHasher{params: params}
+}
+
+// Params 返回填充默认值后的参数
+func (h *Argon2idHasher) Params() Argon2idParams {
+	return h.params
+}
+
+// ID 算法标识
+func (h *Argon2idHasher) ID() string {
+	return "argon2id"
+}
+
+// Identify 判断是否为argon2id哈希
+func (h *Argon2idHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Hash 计算argon2id哈希
+func (h *Argon2idHasher) Hash(password []byte) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成盐失败: %w", err)
+	}
+
+	key := argon2.IDKey(password, salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验argon2id哈希
+func (h *Argon2idHasher) Verify(password []byte, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// Validate 检查argon2id哈希的格式和参数
+func (h *Argon2idHasher) Validate(encoded string) error {
+	_, _, _, err := decodeArgon2id(encoded)
+	return err
+}
+
+// NeedsRehash 参数与配置不一致时需要重新计算
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) != h.params.SaltLength ||
+		uint32(len(key)) != h.params.KeyLength
+}
+
+// decodeArgon2id 解析argon2id的PHC字符串，参数超出允许范围时返回 ErrInvalidHashParams
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("无效的argon2id版本: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("不支持的argon2id版本: %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("无效的argon2id参数: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("无效的argon2id盐: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("无效的argon2id哈希: %w", err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	if err := params.Validate(); err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArgon2idVerifyRejectsOutOfRangeParams(t *testing.T) {
+	h := NewArgon2idHasher(Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1})
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"zero iterations", "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{"zero parallelism", "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{"parallelism overflow", "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key},
+		{"memory below lanes", "$argon2id$v=19$m=7,t=1,p=1$" + salt + "$" + key},
+		{"huge memory", "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key},
+		{"too many iterations", "$argon2id$v=19$m=64,t=1000000,p=1$" + salt + "$" + key},
+		{"short salt", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$" + key},
+		{"short key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$a2V5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := h.Verify([]byte("password"), tt.encoded)
+			if ok || err == nil {
+				t.Fatalf("Verify() = %v, %v; want error", ok, err)
+			}
+		})
+	}
+}
+
+func TestArgon2idParamsValidate(t *testing.T) {
+	if err := DefaultArgon2idParams.Validate(); err != nil {
+		t.Fatalf("default params: %v", err)
+	}
+	params := DefaultArgon2idParams
+	params.Memory = maxArgon2idMemory + 1
+	if err := params.Validate(); !errors.Is(err, ErrInvalidHashParams) {
+		t.Fatalf("Validate() = %v; want ErrInvalidHashParams", err)
+	}
+}
+
+func TestHasherRegistryPepperFallback(t *testing.T) {
+	hasher := NewArgon2idHasher(Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1})
+	unpeppered, err := NewHasherRegistry(hasher, "").Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewHasherRegistry(hasher, "pepper")
+	if ok, _ := registry.Verify("secret", unpeppered); ok {
+		t.Fatal("unpeppered hash verified without pepper rotation")
+	}
+
+	registry.SetPreviousPepper("")
+	ok, rehash := registry.Verify("secret", unpeppered)
+	if !ok || !rehash {
+		t.Fatalf("Verify() during rotation = %v, %v; want true, true", ok, rehash)
+	}
+	if ok, _ := registry.Verify("wrong", unpeppered); ok {
+		t.Fatal("wrong password verified")
+	}
+}
@@ -0,0 +1,431 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 以下算法仅用于校验从旧系统导入的密码哈希，校验成功后会被重新计算为当前算法。
+
+// errVerifyOnly 旧算法不支持生成新哈希
+var errVerifyOnly = errors.New("该算法仅支持校验已有哈希")
+
+// 旧算法参数的上下限，超出范围的哈希在导入和校验时拒绝
+const (
+	maxPBKDF2Rounds    = 2000000
+	maxScryptMemory    = 1 << 30 // scrypt 约占用 128*r*N 字节
+	maxScryptR         = 32
+	maxScryptP         = 16
+	minLegacyKeyLength = 16
+	maxLegacyKeyLength = 128
+)
+
+// LegacyHashers 返回所有内置的旧系统哈希算法
+func LegacyHashers() []PasswordHasher {
+	return []PasswordHasher{
+		&SaltedSHA256Hasher{},
+		&PBKDF2Hasher{},
+		&ScryptHasher{},
+		&MD5CryptHasher{},
+	}
+}
+
+// SaltedSHA256Hasher 加盐SHA-256，格式为 $sha256$<salt>$<hex(SHA256(salt+password))>
+type SaltedSHA256Hasher struct{}
+
+// ID 算法标识
+func (h *SaltedSHA256Hasher) ID() string {
+	return "sha256"
+}
+
+// Identify 判断是否为加盐SHA-256哈希
+func (h *SaltedSHA256Hasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$sha256$")
+}
+
+// Hash 不支持生成新哈希
+func (h *SaltedSHA256Hasher) Hash(password []byte) (string, error) {
+	return "", errVerifyOnly
+}
+
+// Verify 校验加盐SHA-256哈希
+func (h *SaltedSHA256Hasher) Verify(password []byte, encoded string) (bool, error) {
+	salt, expected, err := decodeSaltedSHA256(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(append(salt, password...))
+	return subtle.ConstantTimeCompare(sum[:], expected) == 1, nil
+}
+
+// Validate 检查加盐SHA-256哈希的格式
+func (h *SaltedSHA256Hasher) Validate(encoded string) error {
+	_, _, err := decodeSaltedSHA256(encoded)
+	return err
+}
+
+// decodeSaltedSHA256 解析加盐SHA-256哈希，返回盐和摘要
+func decodeSaltedSHA256(encoded string) ([]byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[1] != "sha256" {
+		return nil, nil, ErrUnknownHashFormat
+	}
+
+	expected, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的SHA-256哈希: %w", err)
+	}
+	if len(expected) != sha256.Size {
+		return nil, nil, fmt.Errorf("无效的SHA-256哈希: 摘要长度应为%d字节", sha256.Size)
+	}
+	return []byte(parts[2]), expected, nil
+}
+
+// NeedsRehash 旧算法总是需要重新计算
+func (h *SaltedSHA256Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// PBKDF2Hasher PBKDF2，支持passlib格式 $pbkdf2-sha256$<rounds>$<salt>$<hash>
+// （salt与hash为adapted base64）以及Django格式 pbkdf2_sha256$<rounds>$<salt>$<base64 hash>，
+// 摘要算法支持 sha1、sha256、sha512。
+type PBKDF2Hasher struct{}
+
+// ID 算法标识
+func (h *PBKDF2Hasher) ID() string {
+	return "pbkdf2"
+}
+
+// Identify 判断是否为PBKDF2哈希
+func (h *PBKDF2Hasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$pbkdf2") || strings.HasPrefix(encoded, "pbkdf2_")
+}
+
+// Hash 不支持生成新哈希
+func (h *PBKDF2Hasher) Hash(password []byte) (string, error) {
+	return "", errVerifyOnly
+}
+
+// Verify 校验PBKDF2哈希
+func (h *PBKDF2Hasher) Verify(password []byte, encoded string) (bool, error) {
+	hashed, err := decodePBKDF2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := pbkdf2.Key(password, hashed.salt, hashed.rounds, len(hashed.expected), hashed.newHash)
+	return subtle.ConstantTimeCompare(actual, hashed.expected) == 1, nil
+}
+
+// Validate 检查PBKDF2哈希的格式、摘要算法和迭代次数
+func (h *PBKDF2Hasher) Validate(encoded string) error {
+	_, err := decodePBKDF2(encoded)
+	return err
+}
+
+// pbkdf2Hash 解析后的PBKDF2哈希
+type pbkdf2Hash struct {
+	newHash  func() hash.Hash
+	rounds   int
+	salt     []byte
+	expected []byte
+}
+
+// decodePBKDF2 解析passlib或Django格式的PBKDF2哈希
+func decodePBKDF2(encoded string) (*pbkdf2Hash, error) {
+	var digest, roundsStr string
+	hashed := &pbkdf2Hash{}
+	var err error
+
+	if strings.HasPrefix(encoded, "$") {
+		// passlib格式
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 5 {
+			return nil, ErrUnknownHashFormat
+		}
+		digest = strings.TrimPrefix(parts[1], "pbkdf2")
+		digest = strings.TrimPrefix(digest, "-")
+		roundsStr = parts[2]
+		if hashed.salt, err = decodeAdaptedBase64(parts[3]); err != nil {
+			return nil, fmt.Errorf("无效的PBKDF2盐: %w", err)
+		}
+		if hashed.expected, err = decodeAdaptedBase64(parts[4]); err != nil {
+			return nil, fmt.Errorf("无效的PBKDF2哈希: %w", err)
+		}
+	} else {
+		// Django格式
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 4 {
+			return nil, ErrUnknownHashFormat
+		}
+		digest = strings.TrimPrefix(parts[0], "pbkdf2_")
+		roundsStr = parts[1]
+		hashed.salt = []byte(parts[2])
+		if hashed.expected, err = base64.StdEncoding.DecodeString(parts[3]); err != nil {
+			return nil, fmt.Errorf("无效的PBKDF2哈希: %w", err)
+		}
+	}
+
+	switch digest {
+	case "", "sha1":
+		hashed.newHash = sha1.New
+	case "sha256":
+		hashed.newHash = sha256.New
+	case "sha512":
+		hashed.newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("不支持的PBKDF2摘要算法: %s", digest)
+	}
+
+	hashed.rounds, err = strconv.Atoi(roundsStr)
+	if err != nil || hashed.rounds <= 0 {
+		return nil, fmt.Errorf("无效的PBKDF2迭代次数: %s", roundsStr)
+	}
+	if hashed.rounds > maxPBKDF2Rounds {
+		return nil, fmt.Errorf("%w: PBKDF2迭代次数应不大于%d", ErrInvalidHashParams, maxPBKDF2Rounds)
+	}
+	if len(hashed.salt) == 0 {
+		return nil, errors.New("无效的PBKDF2盐: 不能为空")
+	}
+	if err := checkLegacyKeyLength("PBKDF2", hashed.expected); err != nil {
+		return nil, err
+	}
+	return hashed, nil
+}
+
+// NeedsRehash 旧算法总是需要重新计算
+func (h *PBKDF2Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// ScryptHasher scrypt，格式为 $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<hash>（base64无填充）
+type ScryptHasher struct{}
+
+// ID 算法标识
+func (h *ScryptHasher) ID() string {
+	return "scrypt"
+}
+
+// Identify 判断是否为scrypt哈希
+func (h *ScryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+// Hash 不支持生成新哈希
+func (h *ScryptHasher) Hash(password []byte) (string, error) {
+	return "", errVerifyOnly
+}
+
+// Verify 校验scrypt哈希
+func (h *ScryptHasher) Verify(password []byte, encoded string) (bool, error) {
+	hashed, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual, err := scrypt.Key(password, hashed.salt, 1<<hashed.ln, hashed.r, hashed.p, len(hashed.expected))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(actual, hashed.expected) == 1, nil
+}
+
+// Validate 检查scrypt哈希的格式和参数
+func (h *ScryptHasher) Validate(encoded string) error {
+	_, err := decodeScrypt(encoded)
+	return err
+}
+
+// scryptHash 解析后的scrypt哈希
+type scryptHash struct {
+	ln, r, p int
+	salt     []byte
+	expected []byte
+}
+
+// decodeScrypt 解析scrypt哈希，N、r、p 超出范围时返回 ErrInvalidHashParams
+func decodeScrypt(encoded string) (*scryptHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return nil, ErrUnknownHashFormat
+	}
+
+	hashed := &scryptHash{}
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &hashed.ln, &hashed.r, &hashed.p); err != nil {
+		return nil, fmt.Errorf("无效的scrypt参数: %w", err)
+	}
+	if hashed.ln <= 0 || hashed.ln > 30 || hashed.r <= 0 || hashed.r > maxScryptR || hashed.p <= 0 || hashed.p > maxScryptP ||
+		128*hashed.r<<hashed.ln > maxScryptMemory {
+		return nil, fmt.Errorf("%w: scrypt参数 ln=%d,r=%d,p=%d", ErrInvalidHashParams, hashed.ln, hashed.r, hashed.p)
+	}
+
+	var err error
+	if hashed.salt, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(parts[3], "=")); err != nil {
+		return nil, fmt.Errorf("无效的scrypt盐: %w", err)
+	}
+	if hashed.expected, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(parts[4], "=")); err != nil {
+		return nil, fmt.Errorf("无效的scrypt哈希: %w", err)
+	}
+	if len(hashed.salt) == 0 {
+		return nil, errors.New("无效的scrypt盐: 不能为空")
+	}
+	if err := checkLegacyKeyLength("scrypt", hashed.expected); err != nil {
+		return nil, err
+	}
+	return hashed, nil
+}
+
+// NeedsRehash 旧算法总是需要重新计算
+func (h *ScryptHasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// MD5CryptHasher MD5-crypt，格式为 $1$<salt>$<hash>
+type MD5CryptHasher struct{}
+
+// md5CryptAlphabet crypt使用的base64字母表
+const md5CryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ID 算法标识
+func (h *MD5CryptHasher) ID() string {
+	return "md5-crypt"
+}
+
+// Identify 判断是否为MD5-crypt哈希
+func (h *MD5CryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$1$")
+}
+
+// Hash 不支持生成新哈希
+func (h *MD5CryptHasher) Hash(password []byte) (string, error) {
+	return "", errVerifyOnly
+}
+
+// Verify 校验MD5-crypt哈希
+func (h *MD5CryptHasher) Verify(password []byte, encoded string) (bool, error) {
+	salt, err := decodeMD5Crypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := md5Crypt(password, salt)
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(encoded)) == 1, nil
+}
+
+// Validate 检查MD5-crypt哈希的格式
+func (h *MD5CryptHasher) Validate(encoded string) error {
+	_, err := decodeMD5Crypt(encoded)
+	return err
+}
+
+// decodeMD5Crypt 解析MD5-crypt哈希，返回盐；盐最多8个字符，哈希为22个crypt字母表字符
+func decodeMD5Crypt(encoded string) ([]byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[1] != "1" {
+		return nil, ErrUnknownHashFormat
+	}
+	if len(parts[2]) > 8 || len(parts[3]) != 22 || strings.Trim(parts[3], md5CryptAlphabet) != "" {
+		return nil, errors.New("无效的MD5-crypt哈希")
+	}
+	return []byte(parts[2]), nil
+}
+
+// NeedsRehash 旧算法总是需要重新计算
+func (h *MD5CryptHasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// md5Crypt 实现FreeBSD的MD5-crypt算法
+func md5Crypt(password, salt []byte) string {
+	const magic = "$1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	alt := md5.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	altSum := alt.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(password)
+	ctx.Write([]byte(magic))
+	ctx.Write(salt)
+	for i := len(password); i > 0; i -= 16 {
+		ctx.Write(altSum[:min(i, 16)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(password[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write(password)
+		} else {
+			round.Write(final)
+		}
+		if i%3 != 0 {
+			round.Write(salt)
+		}
+		if i%7 != 0 {
+			round.Write(password)
+		}
+		if i&1 != 0 {
+			round.Write(final)
+		} else {
+			round.Write(password)
+		}
+		final = round.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString(magic)
+	out.Write(salt)
+	out.WriteByte('$')
+	encode := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			out.WriteByte(md5CryptAlphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint32(final[group[0]])<<16|uint32(final[group[1]])<<8|uint32(final[group[2]]), 4)
+	}
+	encode(uint32(final[11]), 2)
+
+	return out.String()
+}
+
+// checkLegacyKeyLength 检查旧算法的输出长度
+func checkLegacyKeyLength(algorithm string, key []byte) error {
+	if len(key) < minLegacyKeyLength || len(key) > maxLegacyKeyLength {
+		return fmt.Errorf("%w: %s输出长度应为%d-%d字节", ErrInvalidHashParams, algorithm, minLegacyKeyLength, maxLegacyKeyLength)
+	}
+	return nil
+}
+
+// decodeAdaptedBase64 解码passlib使用的adapted base64（以 . 代替 +，无填充）
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(s, ".", "+"))
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestRegistryValidateLegacyHashes(t *testing.T) {
+	registry := NewHasherRegistry(NewBcryptHasher(4), "", append(LegacyHashers(), NewArgon2idHasher(DefaultArgon2idParams))...)
+	password := []byte("password")
+
+	sum := sha256.Sum256(append([]byte("salt"), password...))
+	sha := "$sha256$salt$" + hex.EncodeToString(sum[:])
+	pbkdf := "pbkdf2_sha256$1000$salt$" + base64.StdEncoding.EncodeToString(pbkdf2.Key(password, []byte("salt"), 1000, 32, sha256.New))
+	key, err := scrypt.Key(password, []byte("saltsalt"), 1<<4, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scryptHash := "$scrypt$ln=4,r=8,p=1$" + base64.RawStdEncoding.EncodeToString([]byte("saltsalt")) + "$" + base64.RawStdEncoding.EncodeToString(key)
+
+	for _, encoded := range []string{sha, pbkdf, scryptHash, md5Crypt(password, []byte("saltsalt"))} {
+		if err := registry.Validate(encoded); err != nil {
+			t.Errorf("Validate(%q) = %v", encoded, err)
+		}
+		if ok, _ := registry.Verify(string(password), encoded); !ok {
+			t.Errorf("Verify(%q) = false", encoded)
+		}
+	}
+
+	rejected := []struct {
+		name    string
+		encoded string
+		params  bool
+	}{
+		{"scrypt huge N", "$scrypt$ln=30,r=8,p=1$c2FsdHNhbHQ$" + base64.RawStdEncoding.EncodeToString(key), true},
+		{"scrypt huge r", "$scrypt$ln=4,r=100000,p=1$c2FsdHNhbHQ$" + base64.RawStdEncoding.EncodeToString(key), true},
+		{"scrypt zero p", "$scrypt$ln=4,r=8,p=0$c2FsdHNhbHQ$" + base64.RawStdEncoding.EncodeToString(key), true},
+		{"pbkdf2 too many rounds", "pbkdf2_sha256$100000000$salt$" + base64.StdEncoding.EncodeToString(key), true},
+		{"pbkdf2 short key", "pbkdf2_sha256$1000$salt$AAAA", true},
+		{"argon2id zero iterations", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$" + base64.RawStdEncoding.EncodeToString(key), true},
+		{"bcrypt huge cost", "$2a$31$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyzABCDE", true},
+		{"sha256 short digest", "$sha256$salt$abcd", false},
+		{"md5-crypt truncated", "$1$salt$abc", false},
+		{"unknown", "plain-text", false},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Validate(tt.encoded)
+			if err == nil {
+				t.Fatal("Validate() = nil; want error")
+			}
+			if tt.params && !errors.Is(err, ErrInvalidHashParams) {
+				t.Fatalf("Validate() = %v; want ErrInvalidHashParams", err)
+			}
+		})
+	}
+}
+
+func TestRegistryVerifyLegacyHashWithPepper(t *testing.T) {
+	registry := NewHasherRegistry(NewBcryptHasher(4), "pepper", LegacyHashers()...)
+	password := []byte("password")
+	encoded := "pbkdf2_sha256$1000$salt$" + base64.StdEncoding.EncodeToString(pbkdf2.Key(password, []byte("salt"), 1000, 32, sha256.New))
+
+	ok, rehash := registry.Verify(string(password), encoded)
+	if !ok || !rehash {
+		t.Fatalf("Verify() = %v, %v; want true, true", ok, rehash)
+	}
+	if ok, _ := registry.Verify("wrong", encoded); ok {
+		t.Fatal("wrong password verified")
+	}
+
+	// 重新计算的哈希加入pepper
+	rehashed, err := registry.Hash(string(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := NewHasherRegistry(NewBcryptHasher(4), "").Verify(string(password), rehashed); ok {
+		t.Fatal("rehashed password verified without pepper")
+	}
+	if ok, rehash := registry.Verify(string(password), rehashed); !ok || rehash {
+		t.Fatalf("Verify(rehashed) = %v, %v; want true, false", ok, rehash)
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 权限代码由 ":" 分隔的若干段组成，如 user:list、org:billing:read。
+// 授予的权限中 "*" 匹配任意一段，位于末尾的 "*" 匹配剩余的所有段，
+// 例如 user:* 覆盖 user:list 和 user:profile:read，*:read 覆盖 user:read。
+
+const (
+	permissionSeparator = ":"
+	permissionWildcard  = "*"
+)
+
+// 所有权范围，附加在权限代码末尾，如 user:read:any、user:read:self
+const (
+	ScopeAny  = "any"  // 可访问任意资源
+	ScopeSelf = "self" // 只能访问自己的资源
+)
+
+// MatchPermission 判断授予的权限 granted 是否覆盖所需权限 required
+func MatchPermission(granted, required string) bool {
+	if granted == required {
+		return true
+	}
+
+	grantedParts := strings.Split(granted, permissionSeparator)
+	requiredParts := strings.Split(required, permissionSeparator)
+	for i, part := range grantedParts {
+		last := i == len(grantedParts)-1
+		if part == permissionWildcard && last {
+			return len(requiredParts) >= len(grantedParts)
+		}
+		if i >= len(requiredParts) {
+			return false
+		}
+		if part != permissionWildcard && part != requiredParts[i] {
+			return false
+		}
+	}
+	return len(grantedParts) == len(requiredParts)
+}
+
+// HasPermission 判断授予的权限列表中是否有覆盖所需权限的项
+func HasPermission(granted []string, required string) bool {
+	for _, code := range granted {
+		if MatchPermission(code, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionSet 有效权限集合，由允许的权限和显式拒绝的权限组成。
+// 优先级：拒绝优先于允许，不论二者来自哪个角色、是否继承、是否为通配符；
+// 拒绝权限 X 同时拒绝其所有权范围 X:any 和 X:self；没有任何允许的权限覆盖的操作默认拒绝。
+type PermissionSet struct {
+	Allowed []string `json:"allowed"`
+	Denied  []string `json:"denied,omitempty"`
+}
+
+// NewPermissionSet 创建有效权限集合，两侧分别去重，并去掉已被拒绝权限完全覆盖的允许权限
+func NewPermissionSet(allowed, denied []string) PermissionSet {
+	set := PermissionSet{Allowed: []string{}, Denied: uniqueCodes(denied)}
+	for _, code := range uniqueCodes(allowed) {
+		if !set.Denies(code) {
+			set.Allowed = append(set.Allowed, code)
+		}
+	}
+	return set
+}
+
+// Has 判断是否拥有所需权限：被任一拒绝权限覆盖时没有权限，否则需要被任一允许权限覆盖
+func (s PermissionSet) Has(required string) bool {
+	return !s.Denies(required) && HasPermission(s.Allowed, required)
+}
+
+// Denies 判断所需权限是否被显式拒绝，例如拒绝 *:delete 时 user:delete:any 同样被拒绝
+func (s PermissionSet) Denies(required string) bool {
+	return HasPermission(s.deniedScopes(), required)
+}
+
+// CanGrant 判断能否将权限 code 授予他人：自身拥有该权限，且 code 与被拒绝的权限及其所有权范围没有交集，
+// 例如拒绝 user:delete 时不能授予 user:* 或 user:delete:*，拒绝 *:delete 时不能授予 user:*。
+func (s PermissionSet) CanGrant(code string) bool {
+	if !s.Has(code) {
+		return false
+	}
+	for _, denied := range s.deniedScopes() {
+		if overlapPermissions(code, denied) {
+			return false
+		}
+	}
+	return true
+}
+
+// overlapPermissions 判断两个可能包含通配符的权限是否覆盖同一个权限代码
+func overlapPermissions(a, b string) bool {
+	aParts := strings.Split(a, permissionSeparator)
+	bParts := strings.Split(b, permissionSeparator)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		// 末尾的通配符匹配剩余的所有段
+		if aParts[i] == permissionWildcard && i == len(aParts)-1 || bParts[i] == permissionWildcard && i == len(bParts)-1 {
+			return true
+		}
+		if aParts[i] != permissionWildcard && bParts[i] != permissionWildcard && aParts[i] != bParts[i] {
+			return false
+		}
+	}
+	return len(aParts) == len(bParts)
+}
+
+// deniedScopes 返回拒绝的权限及其 :any 和 :self 所有权范围
+func (s PermissionSet) deniedScopes() []string {
+	if len(s.Denied) == 0 {
+		return nil
+	}
+	codes := make([]string, 0, len(s.Denied)*3)
+	for _, code := range s.Denied {
+		codes = append(codes, code)
+		if strings.HasSuffix(code, permissionSeparator+ScopeAny) || strings.HasSuffix(code, permissionSeparator+ScopeSelf) {
+			continue
+		}
+		codes = append(codes, code+permissionSeparator+ScopeAny, code+permissionSeparator+ScopeSelf)
+	}
+	return codes
+}
+
+// uniqueCodes 按出现顺序去重
+func uniqueCodes(codes []string) []string {
+	unique := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	return unique
+}
+
+// CompactPermissions 压缩权限列表用于写入令牌：
+// 去重，去掉已被通配符覆盖的权限，并将同一前缀下的权限合并为 prefix:{a,b} 形式。
+func CompactPermissions(codes []string) []string {
+	// 去重
+	unique := uniqueCodes(codes)
+
+	// 去掉被其他权限覆盖的权限
+	kept := make([]string, 0, len(unique))
+	for _, code := range unique {
+		covered := false
+		for _, other := range unique {
+			if other != code && strings.Contains(other, permissionWildcard) && MatchPermission(other, code) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, code)
+		}
+	}
+
+	// 按前缀分组
+	groups := make(map[string][]string)
+	var standalone []string
+	for _, code := range kept {
+		i := strings.LastIndex(code, permissionSeparator)
+		if i < 0 || strings.ContainsAny(code, "{},") {
+			standalone = append(standalone, code)
+			continue
+		}
+		groups[code[:i]] = append(groups[code[:i]], code[i+1:])
+	}
+
+	compact := standalone
+	for prefix, actions := range groups {
+		if len(actions) == 1 {
+			compact = append(compact, prefix+permissionSeparator+actions[0])
+			continue
+		}
+		sort.Strings(actions)
+		compact = append(compact, prefix+permissionSeparator+"{"+strings.Join(actions, ",")+"}")
+	}
+	sort.Strings(compact)
+	return compact
+}
+
+// ExpandPermissions 展开 CompactPermissions 合并的权限，未合并的权限原样返回
+func ExpandPermissions(codes []string) []string {
+	expanded := make([]string, 0, len(codes))
+	for _, code := range codes {
+		i := strings.LastIndex(code, permissionSeparator+"{")
+		if i < 0 || !strings.HasSuffix(code, "}") {
+			expanded = append(expanded, code)
+			continue
+		}
+		prefix := code[:i+1]
+		for _, action := range strings.Split(code[i+2:len(code)-1], ",") {
+			expanded = append(expanded, prefix+action)
+		}
+	}
+	return expanded
+}
+
+// ValidPermissionCode 检查权限代码格式：由 ":" 分隔的非空段组成，
+// 每段只能包含小写字母、数字、"_"、"-"，或为通配符 "*"
+func ValidPermissionCode(code string) bool {
+	if code == "" {
+		return false
+	}
+	for _, part := range strings.Split(code, permissionSeparator) {
+		if part == permissionWildcard {
+			continue
+		}
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PermissionRegistry 记录代码中实际使用的权限代码，用于启动时与数据库同步
+type PermissionRegistry struct {
+	mu    sync.RWMutex
+	codes map[string]struct{}
+}
+
+// NewPermissionRegistry 创建权限注册表
+func NewPermissionRegistry() *PermissionRegistry {
+	return &PermissionRegistry{codes: make(map[string]struct{})}
+}
+
+// Register 登记权限代码
+func (r *PermissionRegistry) Register(codes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, code := range codes {
+		r.codes[code] = struct{}{}
+	}
+}
+
+// Codes 返回已登记的权限代码（已排序）
+func (r *PermissionRegistry) Codes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.codes))
+	for code := range r.codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
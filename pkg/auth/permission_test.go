@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		granted, required string
+		want              bool
+	}{
+		{"user:list", "user:list", true},
+		{"user:list", "user:read", false},
+		{"user:*", "user:list", true},
+		{"user:*", "user:profile:read", true},
+		{"user:*", "user", false},
+		{"user:*", "role:list", false},
+		{"*", "user:list", true},
+		{"*", "*", true},
+		{"*:read", "user:read", true},
+		{"*:read", "user:list", false},
+		{"*:read", "user:profile:read", false},
+		{"org:*:read", "org:billing:read", true},
+		{"org:*:read", "org:billing:write", false},
+		{"user:read", "user:read:any", false},
+		{"user:read:any", "user:read", false},
+		{"user:read:*", "user:read:self", true},
+		{"user:list", "user:*", false},
+		{"user", "user:list", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPermission(tt.granted, tt.required); got != tt.want {
+			t.Errorf("MatchPermission(%q, %q) = %v; want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestCompactExpandRoundTrip(t *testing.T) {
+	probes := []string{
+		"user:list", "user:read", "user:delete", "user:read:any", "user:read:self",
+		"role:list", "role:delete", "org:billing:read", "org:billing:write", "audit:read", "*",
+	}
+	tests := [][]string{
+		{},
+		{"user:list"},
+		{"user:list", "user:read", "user:list"},
+		{"user:list", "user:read", "role:list", "audit:read"},
+		{"user:*", "user:list", "role:list"},
+		{"*:read", "user:read", "user:list"},
+		{"user:read:any", "user:read:self", "org:billing:read"},
+		{"*"},
+		{"audit"},
+	}
+	for _, codes := range tests {
+		restored := ExpandPermissions(CompactPermissions(codes))
+		for _, probe := range probes {
+			if want, got := HasPermission(codes, probe), HasPermission(restored, probe); got != want {
+				t.Errorf("%v -> %v: HasPermission(%q) = %v; want %v", codes, restored, probe, got, want)
+			}
+		}
+	}
+
+	// 没有通配符时展开后与去重后的原列表一致
+	codes := []string{"user:list", "user:read", "user:list", "role:list", "org:billing:read", "org:billing:write"}
+	restored := ExpandPermissions(CompactPermissions(codes))
+	sort.Strings(restored)
+	want := []string{"org:billing:read", "org:billing:write", "role:list", "user:list", "user:read"}
+	if strings.Join(restored, " ") != strings.Join(want, " ") {
+		t.Errorf("round trip = %v; want %v", restored, want)
+	}
+	if compact := CompactPermissions(codes); len(compact) != 3 {
+		t.Errorf("CompactPermissions(%v) = %v; want 3 entries", codes, compact)
+	}
+}
+
+func TestPermissionSetHas(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      PermissionSet
+		required string
+		want     bool
+	}{
+		{"allowed", PermissionSet{Allowed: []string{"user:list"}}, "user:list", true},
+		{"not allowed by default", PermissionSet{Allowed: []string{"user:list"}}, "user:delete", false},
+		{"empty set", PermissionSet{}, "user:list", false},
+		{"wildcard allowed", PermissionSet{Allowed: []string{"user:*"}}, "user:delete", true},
+		{"deny overrides allow", PermissionSet{Allowed: []string{"user:delete"}, Denied: []string{"user:delete"}}, "user:delete", false},
+		{"deny overrides wildcard", PermissionSet{Allowed: []string{"*"}, Denied: []string{"user:delete"}}, "user:delete", false},
+		{"wildcard deny", PermissionSet{Allowed: []string{"user:*"}, Denied: []string{"*:delete"}}, "user:delete", false},
+		{"wildcard deny leaves others", PermissionSet{Allowed: []string{"user:*"}, Denied: []string{"*:delete"}}, "user:list", true},
+		{"deny without allow", PermissionSet{Denied: []string{"user:list"}}, "user:read", false},
+		{"deny covers any scope", PermissionSet{Allowed: []string{"user:delete:any"}, Denied: []string{"user:delete"}}, "user:delete:any", false},
+		{"deny covers self scope", PermissionSet{Allowed: []string{"user:read:self"}, Denied: []string{"user:read"}}, "user:read:self", false},
+		{"wildcard deny covers scopes", PermissionSet{Allowed: []string{"*"}, Denied: []string{"*:delete"}}, "user:delete:any", false},
+		{"scoped deny leaves base", PermissionSet{Allowed: []string{"user:read:*"}, Denied: []string{"user:read:any"}}, "user:read:self", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.set.Has(tt.required); got != tt.want {
+				t.Errorf("Has(%q) = %v; want %v", tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPermissionSet(t *testing.T) {
+	set := NewPermissionSet([]string{"user:list", "user:delete", "user:list", "role:list"}, []string{"*:delete", "*:delete"})
+	if strings.Join(set.Allowed, " ") != "user:list role:list" {
+		t.Errorf("Allowed = %v; want [user:list role:list]", set.Allowed)
+	}
+	if len(set.Denied) != 1 {
+		t.Errorf("Denied = %v; want [*:delete]", set.Denied)
+	}
+
+	scoped := NewPermissionSet([]string{"user:delete:any", "user:read:self"}, []string{"user:delete"})
+	if strings.Join(scoped.Allowed, " ") != "user:read:self" {
+		t.Errorf("Allowed = %v; want [user:read:self]", scoped.Allowed)
+	}
+}
+
+// TestPermissionSetAuditor 审计员拥有全部读取权限并拒绝 *:delete，任何范围的删除都被拒绝
+func TestPermissionSetAuditor(t *testing.T) {
+	auditor := NewPermissionSet([]string{"*:read", "*:read:any", "user:delete:self", "user:*"}, []string{"*:delete"})
+
+	for _, code := range []string{"user:delete", "user:delete:any", "user:delete:self", "role:delete", "role:delete:any"} {
+		if auditor.Has(code) {
+			t.Errorf("Has(%q) = true; want false", code)
+		}
+		if !auditor.Denies(code) {
+			t.Errorf("Denies(%q) = false; want true", code)
+		}
+	}
+	for _, code := range []string{"user:read", "user:read:any", "user:list"} {
+		if !auditor.Has(code) {
+			t.Errorf("Has(%q) = false; want true", code)
+		}
+	}
+	if auditor.CanGrant("user:*") {
+		t.Error("CanGrant(user:*) = true; want false")
+	}
+}
+
+func TestPermissionSetCanGrant(t *testing.T) {
+	set := PermissionSet{Allowed: []string{"*"}, Denied: []string{"user:delete"}}
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"user:list", true},
+		{"role:*", true},
+		{"user:delete", false},
+		{"user:*", false},
+		{"*:delete", false},
+		{"user:delete:*", false},
+		{"user:delete:any", false},
+		{"*", false},
+	}
+	for _, tt := range tests {
+		if got := set.CanGrant(tt.code); got != tt.want {
+			t.Errorf("CanGrant(%q) = %v; want %v", tt.code, got, tt.want)
+		}
+	}
+
+	if (PermissionSet{Allowed: []string{"user:list"}}).CanGrant("user:*") {
+		t.Error("CanGrant(user:*) with only user:list; want false")
+	}
+}
+
+func TestOverlapPermissions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"user:delete", "user:delete", true},
+		{"user:*", "*:delete", true},
+		{"user:*", "role:delete", false},
+		{"user:delete:*", "user:delete:any", true},
+		{"*:read", "user:delete", false},
+		{"*", "user:delete:self", true},
+		{"user:*", "user", false},
+		{"user:read", "user:read:any", false},
+	}
+	for _, tt := range tests {
+		if got := overlapPermissions(tt.a, tt.b); got != tt.want {
+			t.Errorf("overlapPermissions(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := overlapPermissions(tt.b, tt.a); got != tt.want {
+			t.Errorf("overlapPermissions(%q, %q) = %v; want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 工作量证明相关错误
+var (
+	ErrInvalidPowChallenge = errors.New("无效的工作量证明挑战")
+	ErrPowChallengeExpired = errors.New("工作量证明挑战已过期")
+	ErrInvalidPowSolution  = errors.New("工作量证明结果不正确")
+)
+
+// PowChallenge hashcash风格的工作量证明挑战
+// 客户端需要找到nonce，使 SHA256(challenge + ":" + nonce) 的前 Difficulty 位为0。
+// 挑战由服务端用HMAC签名，服务端无需保存即可校验。
+type PowChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// NewPowChallenge 生成一个签名的工作量证明挑战
+func NewPowChallenge(secret []byte, difficulty int, ttl time.Duration) (*PowChallenge, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("生成挑战失败: %w", err)
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	payload := fmt.Sprintf("v1.%d.%d.%s", expiresAt.Unix(), difficulty, hex.EncodeToString(random))
+	return &PowChallenge{
+		Challenge:  payload + "." + signPow(secret, payload),
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// VerifyPowSolution 校验工作量证明结果，solution 格式为 "<challenge>:<nonce>"
+// 返回挑战本身及其过期时间，调用方可据此防止重放。
+func VerifyPowSolution(secret []byte, solution string) (string, time.Time, error) {
+	challenge, nonce, found := strings.Cut(solution, ":")
+	if !found || nonce == "" {
+		return "", time.Time{}, ErrInvalidPowSolution
+	}
+
+	// 校验签名
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 5 || parts[0] != "v1" {
+		return "", time.Time{}, ErrInvalidPowChallenge
+	}
+	payload := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(signPow(secret, payload))) {
+		return "", time.Time{}, ErrInvalidPowChallenge
+	}
+
+	// 校验过期时间
+	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidPowChallenge
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	if time.Now().After(expiresAt) {
+		return "", time.Time{}, ErrPowChallengeExpired
+	}
+
+	// 校验工作量
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", time.Time{}, ErrInvalidPowChallenge
+	}
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	if leadingZeroBits(sum[:]) < difficulty {
+		return "", time.Time{}, ErrInvalidPowSolution
+	}
+
+	return challenge, expiresAt, nil
+}
+
+// SolvePowChallenge 求解工作量证明挑战，供客户端或测试工具使用
+func SolvePowChallenge(challenge string, difficulty int) string {
+	for nonce := uint64(0); ; nonce++ {
+		candidate := strconv.FormatUint(nonce, 36)
+		sum := sha256.Sum256([]byte(challenge + ":" + candidate))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return challenge + ":" + candidate
+		}
+	}
+}
+
+// signPow 计算挑战的HMAC签名
+func signPow(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// leadingZeroBits 计算字节序列开头连续0位的个数
+func leadingZeroBits(b []byte) int {
+	count := 0
+	for _, v := range b {
+		if v != 0 {
+			return count + bits.LeadingZeros8(v)
+		}
+		count += 8
+	}
+	return count
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 强度评分的阈值（以猜测次数的log10表示），与zxcvbn的0-4分档一致
+var strengthThresholds = []float64{3, 6, 8, 10}
+
+// 键盘上相邻按键组成的行，用于识别 qwerty、asdf 之类的键盘序列
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// 常见的l33t替换
+var leetSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g',
+	'1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's',
+	'7': 't', '+': 't', '2': 'z',
+}
+
+// StrengthResult 密码强度评估结果
+type StrengthResult struct {
+	Score   int     `json:"score"`   // 0-4，越大越强
+	Guesses float64 `json:"guesses"` // 估算猜测次数的log10
+}
+
+// match 密码中识别出的一段模式
+type match struct {
+	start, end int     // 在rune切片中的区间 [start, end)
+	guesses    float64 // 该段的猜测次数的log10
+}
+
+// EstimateStrength 估算密码强度
+// dictionary 为已知弱口令词典（小写），userInputs 为用户名、邮箱等个人信息，
+// 这些内容出现在密码中时只会贡献极少的猜测次数。
+func EstimateStrength(password string, dictionary map[string]struct{}, userInputs []string) StrengthResult {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return StrengthResult{}
+	}
+
+	matches := findMatches(runes, dictionary, userInputs)
+
+	// 动态规划：找出猜测次数最少的分解方式，未被模式覆盖的字符按暴力破解计算
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + 1 // 每个暴力字符约10种可能
+		for _, m := range matches {
+			if m.end != i {
+				continue
+			}
+			if candidate := best[m.start] + m.guesses; candidate < best[i] {
+				best[i] = candidate
+			}
+		}
+	}
+
+	guesses := best[len(runes)]
+	score := 0
+	for _, threshold := range strengthThresholds {
+		if guesses >= threshold {
+			score++
+		}
+	}
+
+	return StrengthResult{Score: score, Guesses: guesses}
+}
+
+// findMatches 识别密码中的各类弱模式
+func findMatches(runes []rune, dictionary map[string]struct{}, userInputs []string) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	unleet := make([]rune, len(lower))
+	for i, r := range lower {
+		if sub, ok := leetSubstitutions[r]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = r
+		}
+	}
+
+	var matches []match
+
+	// 词典与个人信息匹配
+	for i := 0; i < len(lower); i++ {
+		for j := i + 3; j <= len(lower); j++ {
+			for _, candidate := range []string{string(lower[i:j]), string(unleet[i:j])} {
+				if _, ok := dictionary[candidate]; ok {
+					matches = append(matches, match{start: i, end: j, guesses: dictionaryGuesses(runes[i:j], len(dictionary))})
+				}
+				for _, input := range userInputs {
+					if input != "" && candidate == input {
+						matches = append(matches, match{start: i, end: j, guesses: 1})
+					}
+				}
+			}
+		}
+	}
+
+	// 重复字符，如 aaaa、1111
+	for i := 0; i < len(lower); {
+		j := i + 1
+		for j < len(lower) && lower[j] == lower[i] {
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{start: i, end: j, guesses: math.Log10(float64(12 * (j - i)))})
+		}
+		i = j
+	}
+
+	// 递增或递减序列，如 abcd、4321
+	for i := 0; i < len(lower); {
+		j := i + 1
+		delta := 0
+		for j < len(lower) {
+			d := int(lower[j]) - int(lower[j-1])
+			if (d != 1 && d != -1) || (delta != 0 && d != delta) {
+				break
+			}
+			delta = d
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{start: i, end: j, guesses: math.Log10(float64(20 * (j - i)))})
+			i = j - 1
+		} else {
+			i++
+		}
+	}
+
+	// 键盘序列，如 qwerty、asdf
+	for i := 0; i < len(lower); i++ {
+		for j := i + 3; j <= len(lower); j++ {
+			if !isKeyboardRun(string(lower[i:j])) {
+				break
+			}
+			matches = append(matches, match{start: i, end: j, guesses: math.Log10(float64(40 * (j - i)))})
+		}
+	}
+
+	// 年份，如 1990、2024
+	for i := 0; i+4 <= len(lower); i++ {
+		s := string(lower[i : i+4])
+		if (strings.HasPrefix(s, "19") || strings.HasPrefix(s, "20")) && isDigits(s) {
+			matches = append(matches, match{start: i, end: i + 4, guesses: 2})
+		}
+	}
+
+	return matches
+}
+
+// dictionaryGuesses 词典命中的猜测次数，大小写变化会额外增加少量猜测
+func dictionaryGuesses(word []rune, dictionarySize int) float64 {
+	guesses := math.Log10(float64(dictionarySize + 1))
+	upper := 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	if upper > 0 && upper < len(word) {
+		guesses += math.Log10(float64(2 * upper))
+	}
+	return guesses
+}
+
+// isKeyboardRun 判断字符串是否为键盘同一行上的连续按键
+func isKeyboardRun(s string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) {
+			return true
+		}
+		reversed := []rune(s)
+		for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+			reversed[i], reversed[j] = reversed[j], reversed[i]
+		}
+		if strings.Contains(row, string(reversed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits 判断字符串是否全部为数字
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}